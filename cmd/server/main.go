@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"net/http"
+	"os"
 
 	"github.com/pavlegich/metrics-alerting/internal/app"
 	"github.com/pavlegich/metrics-alerting/internal/infra/logger"
@@ -24,9 +25,10 @@ func main() {
 
 	idleConnsClosed := make(chan struct{})
 
-	if err := app.Run(idleConnsClosed); err != http.ErrServerClosed {
+	if err := app.Run(idleConnsClosed); err != nil && err != http.ErrServerClosed {
 		logger.Log.Error("main: run app failed",
 			zap.Error(err))
+		os.Exit(1)
 	}
 
 	<-idleConnsClosed
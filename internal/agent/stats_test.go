@@ -155,7 +155,8 @@ func TestStatStorage_SendBatch(t *testing.T) {
 	defer db.Close()
 	database := storage.NewDatabase(db)
 	cfg := &config.ServerConfig{}
-	h := handlers.NewWebhook(ctx, ms, database, nil, cfg)
+	h, err := handlers.NewWebhook(ctx, ms, database, nil, cfg)
+	require.NoError(t, err)
 	ts := httptest.NewServer(h.Route(ctx))
 	defer ts.Close()
 	addr, _ := strings.CutPrefix(ts.URL, "http://")
@@ -244,7 +245,8 @@ func TestStatStorage_SendJSON(t *testing.T) {
 	defer db.Close()
 	database := storage.NewDatabase(db)
 	cfg := &config.ServerConfig{}
-	h := handlers.NewWebhook(ctx, ms, database, nil, cfg)
+	h, err := handlers.NewWebhook(ctx, ms, database, nil, cfg)
+	require.NoError(t, err)
 	ts := httptest.NewServer(h.Route(ctx))
 	defer ts.Close()
 	addr, _ := strings.CutPrefix(ts.URL, "http://")
@@ -320,7 +322,8 @@ func TestStatStorage_SendGZIP(t *testing.T) {
 	defer db.Close()
 	database := storage.NewDatabase(db)
 	cfg := &config.ServerConfig{}
-	h := handlers.NewWebhook(ctx, ms, database, nil, cfg)
+	h, err := handlers.NewWebhook(ctx, ms, database, nil, cfg)
+	require.NoError(t, err)
 	ts := httptest.NewServer(h.Route(ctx))
 	defer ts.Close()
 	addr, _ := strings.CutPrefix(ts.URL, "http://")
@@ -0,0 +1,167 @@
+package alerting
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/pavlegich/metrics-alerting/internal/entities"
+	"github.com/pavlegich/metrics-alerting/internal/infra/logger"
+	"go.uber.org/zap"
+)
+
+// acksKind содержит вид состояния для хранения подтверждений оповещений.
+const acksKind = "acks"
+
+var (
+	// ErrAlertNotFound возвращается при отсутствии оповещения с указанным отпечатком.
+	ErrAlertNotFound = errors.New("alert not found")
+	// ErrAlertNotFiring возвращается при подтверждении несрабатывающего оповещения.
+	ErrAlertNotFiring = errors.New("alert is not firing")
+	// ErrInvalidAck возвращается при некорректных данных подтверждения.
+	ErrInvalidAck = errors.New("invalid acknowledgement")
+)
+
+// ack содержит подтверждение оповещения.
+type ack struct {
+	Fingerprint string    `json:"fingerprint"` // отпечаток оповещения
+	By          string    `json:"by"`          // автор подтверждения
+	At          time.Time `json:"at"`          // время подтверждения
+}
+
+// AddAckListener подключает обработчик подтверждений оповещений.
+func (e *Engine) AddAckListener(l Listener) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.ackListeners = append(e.ackListeners, l)
+}
+
+// Acknowledge подтверждает срабатывающее оповещение и передаёт его
+// обработчикам подтверждений, например для остановки эскалации.
+// Подтверждение сохраняется до завершения оповещения.
+func (e *Engine) Acknowledge(ctx context.Context, fingerprint string, by string) (entities.Alert, error) {
+	if by == "" {
+		return entities.Alert{}, fmt.Errorf("Acknowledge: %w: author is empty", ErrInvalidAck)
+	}
+
+	e.mu.Lock()
+	alert, ok := e.alerts[fingerprint]
+	if !ok {
+		e.mu.Unlock()
+		return entities.Alert{}, fmt.Errorf("Acknowledge: %w", ErrAlertNotFound)
+	}
+	if alert.State != entities.StateFiring {
+		e.mu.Unlock()
+		return entities.Alert{}, fmt.Errorf("Acknowledge: %w", ErrAlertNotFiring)
+	}
+
+	a := ack{Fingerprint: fingerprint, By: by, At: time.Now()}
+	old, existed := e.acks[fingerprint]
+	e.acks[fingerprint] = a
+	if err := e.saveAcks(ctx); err != nil {
+		if existed {
+			e.acks[fingerprint] = old
+		} else {
+			delete(e.acks, fingerprint)
+		}
+		e.mu.Unlock()
+		return entities.Alert{}, fmt.Errorf("Acknowledge: %w", err)
+	}
+	alert.AckedBy = a.By
+	alert.AckedAt = a.At
+
+	acked := copyAlert(*alert)
+	listeners := e.ackListeners
+	e.mu.Unlock()
+
+	for _, l := range listeners {
+		l(ctx, acked)
+	}
+	return acked, nil
+}
+
+// loadAcks загружает подтверждения оповещений из хранилища состояния.
+// Подтверждения применяются к оповещениям при их повторной оценке.
+func (e *Engine) loadAcks(ctx context.Context) error {
+	if e.state == nil {
+		return nil
+	}
+
+	data, err := e.state.LoadState(ctx, acksKind)
+	if err != nil {
+		return fmt.Errorf("loadAcks: load acks failed %w", err)
+	}
+	if len(data) == 0 {
+		return nil
+	}
+
+	acks := make([]ack, 0)
+	if err := json.Unmarshal(data, &acks); err != nil {
+		return fmt.Errorf("loadAcks: acks unmarshal %w", err)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, a := range acks {
+		e.acks[a.Fingerprint] = a
+	}
+	return nil
+}
+
+// applyAck отмечает оповещение подтверждённым, если подтверждение сохранено.
+// Вызывается под блокировкой.
+func (e *Engine) applyAck(alert *entities.Alert) {
+	if a, ok := e.acks[alert.Fingerprint]; ok {
+		alert.AckedBy = a.By
+		alert.AckedAt = a.At
+	}
+}
+
+// pruneAcks удаляет подтверждения завершённых оповещений.
+// Вызывается под блокировкой.
+func (e *Engine) pruneAcks(ctx context.Context) {
+	changed := false
+	for fp := range e.acks {
+		if _, ok := e.alerts[fp]; !ok {
+			delete(e.acks, fp)
+			changed = true
+		}
+	}
+	if !changed {
+		return
+	}
+
+	if err := e.saveAcks(ctx); err != nil {
+		logger.Log.Error("pruneAcks: save acks failed", zap.Error(err))
+	}
+}
+
+// saveAcks сохраняет подтверждения оповещений в хранилище состояния.
+// Вызывается под блокировкой.
+func (e *Engine) saveAcks(ctx context.Context) error {
+	if e.state == nil {
+		return nil
+	}
+
+	acks := make([]ack, 0, len(e.acks))
+	for _, a := range e.acks {
+		acks = append(acks, a)
+	}
+	sort.Slice(acks, func(i, j int) bool {
+		return acks[i].Fingerprint < acks[j].Fingerprint
+	})
+
+	data, err := json.Marshal(acks)
+	if err != nil {
+		return fmt.Errorf("saveAcks: acks marshal %w", err)
+	}
+	if err := e.state.SaveState(ctx, acksKind, data); err != nil {
+		return fmt.Errorf("saveAcks: save acks failed %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package alerting
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pavlegich/metrics-alerting/internal/entities"
+	"github.com/pavlegich/metrics-alerting/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEngine_Acknowledge(t *testing.T) {
+	ctx := context.Background()
+	ms := storage.NewMemStorage(ctx)
+	file := storage.NewFile(filepath.Join(t.TempDir(), "metrics.json"), 0)
+	e := NewEngine(ctx, ms, file)
+
+	acked := make([]entities.Alert, 0)
+	e.AddAckListener(func(ctx context.Context, alert entities.Alert) { acked = append(acked, alert) })
+
+	_, err := e.CreateRule(ctx, entities.Rule{ID: "cpu", Name: "HighCPU", MetricType: "gauge",
+		MetricName: "CPUutilization1", Operator: ">", Threshold: 90, For: entities.Duration(time.Minute)})
+	require.NoError(t, err)
+
+	now := time.Now()
+	require.NoError(t, ms.Put(ctx, "gauge", "CPUutilization1", "95"))
+	e.Evaluate(ctx, now)
+	alerts := e.Alerts(ctx)
+	require.Len(t, alerts, 1)
+	fp := alerts[0].Fingerprint
+
+	_, err = e.Acknowledge(ctx, "unknown", "ops")
+	assert.ErrorIs(t, err, ErrAlertNotFound)
+	_, err = e.Acknowledge(ctx, fp, "")
+	assert.ErrorIs(t, err, ErrInvalidAck)
+	_, err = e.Acknowledge(ctx, fp, "ops")
+	assert.ErrorIs(t, err, ErrAlertNotFiring)
+
+	e.Evaluate(ctx, now.Add(time.Minute))
+	alert, err := e.Acknowledge(ctx, fp, "ops")
+	require.NoError(t, err)
+	assert.Equal(t, "ops", alert.AckedBy)
+	require.Len(t, acked, 1)
+	assert.Equal(t, fp, acked[0].Fingerprint)
+
+	// подтверждение восстанавливается после перезапуска
+	restored := NewEngine(ctx, ms, file)
+	require.NoError(t, restored.Load(ctx))
+	restored.Evaluate(ctx, now.Add(2*time.Minute))
+	alerts = restored.Alerts(ctx)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, "ops", alerts[0].AckedBy)
+	assert.Equal(t, alert.AckedAt.Unix(), alerts[0].AckedAt.Unix())
+
+	// подтверждение удаляется вместе с завершённым оповещением
+	require.NoError(t, ms.Put(ctx, "gauge", "CPUutilization1", "10"))
+	e.Evaluate(ctx, now.Add(3*time.Minute))
+	require.NoError(t, ms.Put(ctx, "gauge", "CPUutilization1", "95"))
+	e.Evaluate(ctx, now.Add(4*time.Minute))
+	alerts = e.Alerts(ctx)
+	require.Len(t, alerts, 1)
+	assert.Empty(t, alerts[0].AckedBy)
+}
@@ -0,0 +1,90 @@
+package alerting
+
+import (
+	"math"
+
+	"github.com/pavlegich/metrics-alerting/internal/entities"
+)
+
+// minSamples содержит минимальное количество значений истории,
+// по которым вычисляется статистика для обнаружения аномалий.
+const minSamples = 3
+
+// rate вычисляет скорость изменения значения метрики в секунду
+// между первым и последним значениями истории.
+func rate(points []entities.Point) (float64, bool) {
+	if len(points) < 2 {
+		return 0, false
+	}
+	first, last := points[0], points[len(points)-1]
+	elapsed := last.Time.Sub(first.Time).Seconds()
+	if elapsed <= 0 {
+		return 0, false
+	}
+	return (last.Last - first.Last) / elapsed, true
+}
+
+// percentChange вычисляет изменение значения метрики в процентах
+// от первого значения истории до последнего.
+func percentChange(points []entities.Point) (float64, bool) {
+	if len(points) < 2 {
+		return 0, false
+	}
+	first, last := points[0].Last, points[len(points)-1].Last
+	if first == 0 {
+		return 0, false
+	}
+	return (last - first) / math.Abs(first) * 100, true
+}
+
+// zScore вычисляет отклонение последнего значения истории от среднего
+// предыдущих значений в единицах их стандартного отклонения.
+func zScore(points []entities.Point) (float64, bool) {
+	if len(points) < minSamples+1 {
+		return 0, false
+	}
+	prev := points[:len(points)-1]
+
+	var mean float64
+	for _, p := range prev {
+		mean += p.Last
+	}
+	mean /= float64(len(prev))
+
+	var variance float64
+	for _, p := range prev {
+		variance += (p.Last - mean) * (p.Last - mean)
+	}
+	variance /= float64(len(prev))
+
+	return score(points[len(points)-1].Last, mean, variance)
+}
+
+// ewmaScore вычисляет отклонение последнего значения истории от
+// экспоненциально взвешенного среднего предыдущих значений в единицах
+// экспоненциально взвешенного стандартного отклонения с коэффициентом alpha.
+func ewmaScore(points []entities.Point, alpha float64) (float64, bool) {
+	if len(points) < minSamples+1 {
+		return 0, false
+	}
+	prev := points[:len(points)-1]
+
+	mean := prev[0].Last
+	var variance float64
+	for _, p := range prev[1:] {
+		diff := p.Last - mean
+		incr := alpha * diff
+		mean += incr
+		variance = (1 - alpha) * (variance + diff*incr)
+	}
+
+	return score(points[len(points)-1].Last, mean, variance)
+}
+
+// score вычисляет z-оценку значения. При нулевой дисперсии оценка не определена.
+func score(value float64, mean float64, variance float64) (float64, bool) {
+	if variance <= 0 {
+		return 0, false
+	}
+	return (value - mean) / math.Sqrt(variance), true
+}
@@ -0,0 +1,171 @@
+package alerting
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/pavlegich/metrics-alerting/internal/entities"
+	"github.com/pavlegich/metrics-alerting/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// series формирует историю значений с интервалом step.
+func series(start time.Time, step time.Duration, values ...float64) []entities.Point {
+	points := make([]entities.Point, len(values))
+	for i, v := range values {
+		points[i] = entities.Point{Time: start.Add(time.Duration(i) * step), Count: 1, Last: v}
+	}
+	return points
+}
+
+func TestConditions(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	flat := []float64{10, 10, 10, 10, 10}
+	noisy := []float64{10, 12, 8, 11, 9, 10}
+
+	tests := []struct {
+		name   string
+		eval   func(points []entities.Point) (float64, bool)
+		points []entities.Point
+		want   float64
+		wantOk bool
+	}{
+		{
+			name:   "rate_counter",
+			eval:   rate,
+			points: series(start, 10*time.Second, 100, 150, 200, 300),
+			want:   200.0 / 30,
+			wantOk: true,
+		},
+		{
+			name:   "rate_decreasing_gauge",
+			eval:   rate,
+			points: series(start, time.Minute, 120, 60),
+			want:   -1,
+			wantOk: true,
+		},
+		{
+			name:   "rate_single_point",
+			eval:   rate,
+			points: series(start, time.Second, 5),
+			wantOk: false,
+		},
+		{
+			name:   "change_growth",
+			eval:   percentChange,
+			points: series(start, time.Minute, 200, 250, 300),
+			want:   50,
+			wantOk: true,
+		},
+		{
+			name:   "change_negative_base",
+			eval:   percentChange,
+			points: series(start, time.Minute, -100, -50),
+			want:   50,
+			wantOk: true,
+		},
+		{
+			name:   "change_zero_base",
+			eval:   percentChange,
+			points: series(start, time.Minute, 0, 10),
+			wantOk: false,
+		},
+		{
+			name:   "zscore_spike",
+			eval:   zScore,
+			points: series(start, time.Second, append(noisy, 20)...),
+			want:   10 / math.Sqrt(10.0/6),
+			wantOk: true,
+		},
+		{
+			name:   "zscore_normal",
+			eval:   zScore,
+			points: series(start, time.Second, append(noisy, 10)...),
+			want:   0,
+			wantOk: true,
+		},
+		{
+			name:   "zscore_constant",
+			eval:   zScore,
+			points: series(start, time.Second, append(flat, 50)...),
+			wantOk: false,
+		},
+		{
+			name:   "zscore_few_samples",
+			eval:   zScore,
+			points: series(start, time.Second, 1, 2, 3),
+			wantOk: false,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := tc.eval(tc.points)
+			assert.Equal(t, tc.wantOk, ok)
+			if tc.wantOk {
+				assert.InDelta(t, tc.want, got, 1e-9)
+			}
+		})
+	}
+}
+
+func TestEWMAScore(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	base := []float64{10, 12, 10, 12, 10, 12, 10, 12}
+
+	inBand, ok := ewmaScore(series(start, time.Second, append(base, 11)...), 0.3)
+	require.True(t, ok)
+	assert.Less(t, math.Abs(inBand), 1.0)
+
+	spike, ok := ewmaScore(series(start, time.Second, append(base, 30)...), 0.3)
+	require.True(t, ok)
+	assert.Greater(t, spike, 3.0)
+
+	drop, ok := ewmaScore(series(start, time.Second, append(base, -10)...), 0.3)
+	require.True(t, ok)
+	assert.Less(t, drop, -3.0)
+}
+
+func TestEngine_EvaluateWindow(t *testing.T) {
+	ctx := context.Background()
+	ms := storage.NewMemStorage(ctx)
+	history := storage.NewHistory(ctx, storage.Retention{Raw: time.Hour, Minute: time.Hour, Hour: time.Hour})
+	e := NewEngine(ctx, ms, nil)
+	e.SetHistory(history)
+
+	rules := []entities.Rule{
+		{ID: "polls", Name: "FastPolls", Kind: entities.RuleRate, MetricType: "counter",
+			MetricName: "PollCount", Operator: ">", Threshold: 1, Window: entities.Duration(time.Minute)},
+		{ID: "heap", Name: "HeapGrowth", Kind: entities.RuleChange, MetricType: "gauge",
+			MetricName: "HeapAlloc", Operator: ">=", Threshold: 50, Window: entities.Duration(time.Minute)},
+		{ID: "heap_anomaly", Name: "HeapAnomaly", Kind: entities.RuleZScore, MetricType: "gauge",
+			MetricName: "HeapAlloc", Operator: ">", Threshold: 3, Window: entities.Duration(time.Minute)},
+		{ID: "wrong_type", Name: "WrongType", Kind: entities.RuleRate, MetricType: "gauge",
+			MetricName: "PollCount", Operator: ">", Threshold: 0, Window: entities.Duration(time.Minute)},
+	}
+	for _, r := range rules {
+		_, err := e.CreateRule(ctx, r)
+		require.NoError(t, err)
+	}
+
+	now := time.Now()
+	record := func(id, mType string, values ...float64) {
+		var total float64
+		for i, v := range values {
+			total = v
+			history.Record(ctx, entities.Update{ID: id, MType: mType, Value: total,
+				Time: now.Add(time.Duration(i-len(values)) * 5 * time.Second)})
+		}
+	}
+	record("PollCount", "counter", 0, 10, 20, 30, 40, 50)
+	record("HeapAlloc", "gauge", 100, 104, 98, 102, 96, 160)
+
+	e.Evaluate(ctx, now)
+	names := make([]string, 0)
+	for _, a := range e.Alerts(ctx) {
+		names = append(names, a.RuleName)
+	}
+	assert.ElementsMatch(t, []string{"FastPolls", "HeapGrowth", "HeapAnomaly"}, names)
+}
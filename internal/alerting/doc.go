@@ -0,0 +1,4 @@
+// Пакет alerting содержит движок оповещений: хранение и проверку правил,
+// периодическую оценку правил по метрикам сервера и отслеживание
+// состояния оповещений.
+package alerting
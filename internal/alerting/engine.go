@@ -0,0 +1,583 @@
+package alerting
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/pavlegich/metrics-alerting/internal/entities"
+	"github.com/pavlegich/metrics-alerting/internal/infra/logger"
+	"github.com/pavlegich/metrics-alerting/internal/interfaces"
+	"go.uber.org/zap"
+)
+
+// Listener вызывается при изменении состояния оповещения.
+type Listener func(ctx context.Context, alert entities.Alert)
+
+// EvaluateListener вызывается после оценки правил с текущими оповещениями.
+type EvaluateListener func(ctx context.Context, alerts []entities.Alert)
+
+// Engine содержит правила оповещений, текущие оповещения
+// и хранилища метрик и состояния.
+type Engine struct {
+	ms          interfaces.MetricStorage
+	state       interfaces.StateStorage
+	transitions interfaces.TransitionStorage
+	history     interfaces.HistoryStorage
+
+	mu        *sync.RWMutex
+	rules     map[string]entities.Rule
+	compiled  map[string]compiledRule
+	fileRules map[string]struct{}
+	alerts    map[string]*entities.Alert
+	silences  map[string]entities.Silence
+	acks      map[string]ack
+	listeners []Listener
+	notifiers []Listener
+
+	ackListeners      []Listener
+	fileListeners     []FileListener
+	suppressListeners []Listener
+	evaluateListeners []EvaluateListener
+	inhibitRules      []inhibitRule
+	// silenceIndex содержит тишины с разобранными условиями,
+	// упорядоченные по времени начала
+	silenceIndex []compiledSilence
+
+	started time.Time
+}
+
+// NewEngine создаёт новый движок оповещений. Хранилище состояния
+// может отсутствовать, тогда правила хранятся только в памяти.
+func NewEngine(ctx context.Context, ms interfaces.MetricStorage, state interfaces.StateStorage) *Engine {
+	return &Engine{
+		ms:        ms,
+		state:     state,
+		mu:        &sync.RWMutex{},
+		rules:     make(map[string]entities.Rule),
+		compiled:  make(map[string]compiledRule),
+		fileRules: make(map[string]struct{}),
+		alerts:    make(map[string]*entities.Alert),
+		silences:  make(map[string]entities.Silence),
+		acks:      make(map[string]ack),
+		started:   time.Now(),
+	}
+}
+
+// Load загружает правила оповещений, тишины и подтверждения оповещений
+// из хранилища состояния.
+func (e *Engine) Load(ctx context.Context) error {
+	if err := e.loadRules(ctx); err != nil {
+		return fmt.Errorf("Load: %w", err)
+	}
+	if err := e.loadSilences(ctx); err != nil {
+		return fmt.Errorf("Load: %w", err)
+	}
+	if err := e.loadAcks(ctx); err != nil {
+		return fmt.Errorf("Load: %w", err)
+	}
+	return nil
+}
+
+// SetTransitions подключает хранилище истории изменений состояния оповещений.
+func (e *Engine) SetTransitions(ts interfaces.TransitionStorage) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.transitions = ts
+}
+
+// SetHistory подключает историю значений метрик, по которой оцениваются
+// правила видов rate, change, zscore и ewma. Без истории такие правила не срабатывают.
+func (e *Engine) SetHistory(hs interfaces.HistoryStorage) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.history = hs
+}
+
+// Transitions возвращает историю изменений состояния оповещений по условиям отбора.
+func (e *Engine) Transitions(ctx context.Context, filter entities.AlertFilter) ([]entities.AlertTransition, error) {
+	e.mu.RLock()
+	ts := e.transitions
+	e.mu.RUnlock()
+
+	if ts == nil {
+		return []entities.AlertTransition{}, nil
+	}
+	res, err := ts.QueryTransitions(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("Transitions: %w", err)
+	}
+	return res, nil
+}
+
+// AddListener подключает обработчик всех изменений состояния оповещений.
+func (e *Engine) AddListener(l Listener) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.listeners = append(e.listeners, l)
+}
+
+// AddNotifier подключает обработчик уведомлений об изменениях состояния
+// оповещений. Изменения оповещений, подавленных тишинами, не передаются.
+func (e *Engine) AddNotifier(n Listener) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.notifiers = append(e.notifiers, n)
+}
+
+// AddSuppressListener подключает обработчик изменений подавления срабатывающих
+// оповещений тишинами и правилами подавления, не меняющих состояние оповещения.
+func (e *Engine) AddSuppressListener(l Listener) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.suppressListeners = append(e.suppressListeners, l)
+}
+
+// AddEvaluateListener подключает обработчик, вызываемый после каждой оценки правил.
+func (e *Engine) AddEvaluateListener(l EvaluateListener) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.evaluateListeners = append(e.evaluateListeners, l)
+}
+
+// Run оценивает правила оповещений с указанным интервалом времени.
+func (e *Engine) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			e.Evaluate(ctx, now)
+		}
+	}
+}
+
+// Evaluate оценивает все правила оповещений на указанный момент времени
+// и уведомляет обработчики об изменениях состояния оповещений.
+// Оповещения, подавленные тишинами или правилами подавления, оцениваются,
+// но уведомления о них не отправляются.
+func (e *Engine) Evaluate(ctx context.Context, now time.Time) {
+	e.mu.Lock()
+	e.expireSilences(ctx, now)
+	transitions := make([]entities.Alert, 0)
+	for _, rule := range e.rules {
+		value, values, active := e.evaluateRule(ctx, rule, now)
+		transitions = append(transitions, e.updateAlert(rule, value, values, active, now)...)
+	}
+	e.pruneAcks(ctx)
+	changed := make(map[string]struct{}, len(transitions))
+	for _, t := range transitions {
+		changed[t.Fingerprint] = struct{}{}
+	}
+	// срабатывающие оповещения, подавление которых изменилось без изменения состояния
+	suppressed := make([]entities.Alert, 0)
+	sources := e.inhibitSources()
+	for _, a := range e.alerts {
+		was := a.Suppressed()
+		a.SilencedBy = e.silencedBy(a.Labels, now)
+		a.InhibitedBy = e.inhibitedBy(*a, sources)
+		if _, ok := changed[a.Fingerprint]; !ok && a.State == entities.StateFiring && was != a.Suppressed() {
+			suppressed = append(suppressed, copyAlert(*a))
+		}
+	}
+	e.markSuppressed(transitions, now)
+	evaluateListeners := e.evaluateListeners
+	var current []entities.Alert
+	if len(evaluateListeners) > 0 {
+		current = make([]entities.Alert, 0, len(e.alerts))
+		for _, a := range e.alerts {
+			current = append(current, copyAlert(*a))
+		}
+	}
+	e.mu.Unlock()
+
+	for _, alert := range transitions {
+		logger.Log.Info("alert state changed",
+			zap.String("rule", alert.RuleName),
+			zap.String("state", string(alert.State)),
+			zap.Float64("value", alert.Value),
+			zap.Bool("silenced", len(alert.SilencedBy) > 0),
+			zap.Bool("inhibited", len(alert.InhibitedBy) > 0))
+	}
+	e.notify(ctx, transitions)
+	e.notifySuppressed(ctx, suppressed)
+	for _, l := range evaluateListeners {
+		l(ctx, current)
+	}
+}
+
+// Alerts возвращает текущие оповещения в состоянии pending или firing.
+func (e *Engine) Alerts(ctx context.Context) []entities.Alert {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	alerts := make([]entities.Alert, 0, len(e.alerts))
+	for _, a := range e.alerts {
+		alerts = append(alerts, copyAlert(*a))
+	}
+	sort.Slice(alerts, func(i, j int) bool {
+		return alerts[i].Fingerprint < alerts[j].Fingerprint
+	})
+	return alerts
+}
+
+// evaluateRule проверяет условие правила на указанный момент времени
+// и возвращает значение, по которому проверялось условие, и значения метрик,
+// использованные при оценке.
+func (e *Engine) evaluateRule(ctx context.Context, rule entities.Rule, now time.Time) (float64, map[string]float64, bool) {
+	switch rule.Kind {
+	case entities.RuleAbsent:
+		value, active := e.evaluateAbsent(ctx, rule, now)
+		return value, nil, active
+	case entities.RuleRate, entities.RuleChange, entities.RuleZScore, entities.RuleEWMA:
+		value, active := e.evaluateWindow(ctx, rule, now)
+		return value, nil, active
+	case entities.RuleExpr:
+		return e.evaluateExpr(ctx, rule)
+	}
+
+	raw, err := e.ms.Get(ctx, rule.MetricType, rule.MetricName)
+	if err != nil {
+		return 0, nil, false
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		logger.Log.Error("evaluateRule: parse metric value failed",
+			zap.String("metric", rule.MetricName), zap.Error(err))
+		return 0, nil, false
+	}
+	values := map[string]float64{rule.MetricName: value}
+	return value, values, compare(value, rule.Operator, rule.Threshold)
+}
+
+// evaluateExpr вычисляет выражение составного правила по текущим значениям
+// метрик. Если какая-либо метрика выражения отсутствует, условие не выполняется.
+// Выражение разбирается при сохранении правила.
+func (e *Engine) evaluateExpr(ctx context.Context, rule entities.Rule) (float64, map[string]float64, bool) {
+	expr := e.compiled[rule.ID].expr
+	if expr == nil {
+		return 0, nil, false
+	}
+
+	values := make(map[string]float64, len(expr.Metrics()))
+	for _, name := range expr.Metrics() {
+		// тип метрики не влияет на получение её значения из хранилища
+		raw, err := e.ms.Get(ctx, "gauge", name)
+		if err != nil {
+			return 0, nil, false
+		}
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			logger.Log.Error("evaluateExpr: parse metric value failed",
+				zap.String("metric", name), zap.Error(err))
+			return 0, nil, false
+		}
+		values[name] = v
+	}
+
+	value, err := expr.Eval(values)
+	if err != nil {
+		return 0, values, false
+	}
+	if rule.Operator == "" {
+		return value, values, value != 0
+	}
+	return value, values, compare(value, rule.Operator, rule.Threshold)
+}
+
+// evaluateAbsent проверяет, что метрика правила не обновлялась дольше
+// периода absent_for, и возвращает длительность отсутствия обновлений в секундах.
+// Если имя метрики не указано, учитывается последнее обновление любой метрики.
+// Метрики, не обновлявшиеся с запуска движка, считаются обновлёнными при запуске.
+func (e *Engine) evaluateAbsent(ctx context.Context, rule entities.Rule, now time.Time) (float64, bool) {
+	last := e.started
+	if rule.MetricName != "" {
+		if t, ok := e.ms.GetUpdated(ctx, rule.MetricName); ok && t.After(last) {
+			last = t
+		}
+	} else {
+		for _, t := range e.ms.GetAllUpdated(ctx) {
+			if t.After(last) {
+				last = t
+			}
+		}
+	}
+
+	absent := now.Sub(last)
+	return absent.Seconds(), absent >= time.Duration(rule.AbsentFor)
+}
+
+// evaluateWindow вычисляет по истории значений метрики за окно правила
+// скорость изменения, изменение в процентах или z-оценку последнего значения
+// и сравнивает результат с порогом правила. Вызывается под блокировкой.
+func (e *Engine) evaluateWindow(ctx context.Context, rule entities.Rule, now time.Time) (float64, bool) {
+	if e.history == nil {
+		return 0, false
+	}
+	series, ok := e.history.Query(ctx, rule.MetricName, now.Add(-time.Duration(rule.Window)), now)
+	if !ok || series.MType != rule.MetricType {
+		return 0, false
+	}
+
+	var value float64
+	switch rule.Kind {
+	case entities.RuleRate:
+		value, ok = rate(series.Points)
+	case entities.RuleChange:
+		value, ok = percentChange(series.Points)
+	case entities.RuleZScore:
+		value, ok = zScore(series.Points)
+	case entities.RuleEWMA:
+		value, ok = ewmaScore(series.Points, rule.Alpha)
+	}
+	if !ok {
+		return 0, false
+	}
+	return value, compare(value, rule.Operator, rule.Threshold)
+}
+
+// updateAlert изменяет состояние оповещения правила по результату оценки
+// и возвращает изменения состояния. Вызывается под блокировкой.
+func (e *Engine) updateAlert(rule entities.Rule, value float64, values map[string]float64,
+	active bool, now time.Time) []entities.Alert {
+	fp := fingerprint(rule.ID, nil)
+	alert, ok := e.alerts[fp]
+
+	switch {
+	case active && !ok:
+		alert = &entities.Alert{
+			Fingerprint: fp,
+			RuleID:      rule.ID,
+			RuleName:    rule.Name,
+			State:       entities.StatePending,
+			Labels:      alertLabels(rule),
+			ActiveAt:    now,
+		}
+		e.setValue(alert, rule, value, values)
+		e.applyAck(alert)
+		e.alerts[fp] = alert
+		if rule.For > 0 {
+			return []entities.Alert{copyAlert(*alert)}
+		}
+		fallthrough
+	case active:
+		e.setValue(alert, rule, value, values)
+		if alert.State == entities.StatePending && now.Sub(alert.ActiveAt) >= time.Duration(rule.For) {
+			alert.State = entities.StateFiring
+			alert.FiredAt = now
+			return []entities.Alert{copyAlert(*alert)}
+		}
+	case ok:
+		return []entities.Alert{e.resolve(alert, now)}
+	}
+
+	return nil
+}
+
+// setValue сохраняет в оповещении результат оценки правила и формирует
+// сообщение оповещения по шаблону правила. Вызывается под блокировкой.
+func (e *Engine) setValue(alert *entities.Alert, rule entities.Rule, value float64, values map[string]float64) {
+	alert.Value = value
+	alert.Values = values
+	tmpl := e.compiled[rule.ID].message
+	if tmpl == nil {
+		return
+	}
+	msg, err := renderMessage(tmpl, *alert)
+	if err != nil {
+		logger.Log.Error("setValue: render alert message failed",
+			zap.String("rule", rule.ID), zap.Error(err))
+		return
+	}
+	alert.Message = msg
+}
+
+// renderMessage формирует сообщение оповещения по шаблону.
+func renderMessage(tmpl *template.Template, alert entities.Alert) (string, error) {
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, alert); err != nil {
+		return "", fmt.Errorf("renderMessage: execute template failed %w", err)
+	}
+	return buf.String(), nil
+}
+
+// resolve завершает оповещение и возвращает его итоговое состояние.
+// Вызывается под блокировкой.
+func (e *Engine) resolve(alert *entities.Alert, now time.Time) entities.Alert {
+	delete(e.alerts, alert.Fingerprint)
+
+	resolved := copyAlert(*alert)
+	resolved.ResolvedAt = now
+	if alert.State == entities.StateFiring {
+		resolved.State = entities.StateResolved
+	} else {
+		resolved.State = entities.StateInactive
+	}
+	return resolved
+}
+
+// resolveRule завершает все оповещения указанного правила. Вызывается под блокировкой.
+func (e *Engine) resolveRule(ruleID string, now time.Time) []entities.Alert {
+	resolved := make([]entities.Alert, 0)
+	for _, a := range e.alerts {
+		if a.RuleID == ruleID {
+			resolved = append(resolved, e.resolve(a, now))
+		}
+	}
+	e.markSuppressed(resolved, now)
+	return resolved
+}
+
+// markSuppressed отмечает изменения состояния оповещений, подавленные
+// тишинами и правилами подавления. Вызывается под блокировкой.
+func (e *Engine) markSuppressed(alerts []entities.Alert, now time.Time) {
+	if len(alerts) == 0 {
+		return
+	}
+	sources := e.inhibitSources()
+	for i := range alerts {
+		alerts[i].SilencedBy = e.silencedBy(alerts[i].Labels, now)
+		alerts[i].InhibitedBy = e.inhibitedBy(alerts[i], sources)
+	}
+}
+
+// notify сохраняет изменения состояния оповещений в историю и передаёт их
+// обработчикам, а неподавленные изменения также обработчикам уведомлений.
+func (e *Engine) notify(ctx context.Context, alerts []entities.Alert) {
+	e.mu.RLock()
+	ts := e.transitions
+	listeners := e.listeners
+	notifiers := e.notifiers
+	e.mu.RUnlock()
+
+	for _, alert := range alerts {
+		if ts != nil {
+			t := entities.AlertTransition{Time: alert.StateTime(), Alert: alert}
+			if err := ts.SaveTransition(ctx, t); err != nil {
+				logger.Log.Error("notify: save alert transition failed", zap.Error(err))
+			}
+		}
+		for _, l := range listeners {
+			l(ctx, alert)
+		}
+		if alert.Suppressed() {
+			continue
+		}
+		for _, n := range notifiers {
+			n(ctx, alert)
+		}
+	}
+}
+
+// notifySuppressed передаёт срабатывающие оповещения, подавление которых
+// изменилось, обработчикам изменений подавления, а оповещения, подавление
+// которых закончилось, также обработчикам уведомлений, так как их срабатывание
+// во время действия тишины или правила подавления не передавалось.
+func (e *Engine) notifySuppressed(ctx context.Context, alerts []entities.Alert) {
+	e.mu.RLock()
+	listeners := e.suppressListeners
+	notifiers := e.notifiers
+	e.mu.RUnlock()
+
+	for _, alert := range alerts {
+		logger.Log.Info("alert suppression changed",
+			zap.String("rule", alert.RuleName),
+			zap.Bool("silenced", len(alert.SilencedBy) > 0),
+			zap.Bool("inhibited", len(alert.InhibitedBy) > 0))
+		for _, l := range listeners {
+			l(ctx, alert)
+		}
+		if alert.Suppressed() {
+			continue
+		}
+		for _, n := range notifiers {
+			n(ctx, alert)
+		}
+	}
+}
+
+// compare сравнивает значение с порогом с помощью указанного оператора.
+func compare(value float64, operator string, threshold float64) bool {
+	switch operator {
+	case ">":
+		return value > threshold
+	case ">=":
+		return value >= threshold
+	case "<":
+		return value < threshold
+	case "<=":
+		return value <= threshold
+	case "==":
+		return value == threshold
+	case "!=":
+		return value != threshold
+	default:
+		return false
+	}
+}
+
+// alertLabels формирует метки оповещения из меток правила.
+func alertLabels(rule entities.Rule) map[string]string {
+	labels := make(map[string]string, len(rule.Labels)+2)
+	for k, v := range rule.Labels {
+		labels[k] = v
+	}
+	labels["alertname"] = rule.Name
+	if rule.MetricName != "" {
+		labels["metric"] = rule.MetricName
+	}
+	return labels
+}
+
+// fingerprint вычисляет отпечаток оповещения по идентификатору правила и меткам.
+func fingerprint(ruleID string, labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := fnv.New64a()
+	h.Write([]byte(ruleID))
+	for _, k := range keys {
+		h.Write([]byte{0})
+		h.Write([]byte(k))
+		h.Write([]byte{0})
+		h.Write([]byte(labels[k]))
+	}
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+// copyAlert возвращает копию оповещения с отдельной копией меток.
+func copyAlert(a entities.Alert) entities.Alert {
+	labels := make(map[string]string, len(a.Labels))
+	for k, v := range a.Labels {
+		labels[k] = v
+	}
+	a.Labels = labels
+	a.SilencedBy = append([]string(nil), a.SilencedBy...)
+	a.InhibitedBy = append([]string(nil), a.InhibitedBy...)
+	if a.Values != nil {
+		values := make(map[string]float64, len(a.Values))
+		for k, v := range a.Values {
+			values[k] = v
+		}
+		a.Values = values
+	}
+	return a
+}
@@ -0,0 +1,251 @@
+package alerting
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pavlegich/metrics-alerting/internal/entities"
+	"github.com/pavlegich/metrics-alerting/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEngine_Evaluate(t *testing.T) {
+	ctx := context.Background()
+	ms := storage.NewMemStorage(ctx)
+	e := NewEngine(ctx, ms, nil)
+
+	transitions := make([]entities.AlertState, 0)
+	e.AddListener(func(ctx context.Context, alert entities.Alert) {
+		transitions = append(transitions, alert.State)
+	})
+
+	_, err := e.CreateRule(ctx, entities.Rule{
+		ID:         "cpu",
+		Name:       "HighCPU",
+		MetricType: "gauge",
+		MetricName: "CPUutilization1",
+		Operator:   ">",
+		Threshold:  90,
+		For:        entities.Duration(time.Minute),
+	})
+	require.NoError(t, err)
+
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		value  string
+		offset time.Duration
+		want   []entities.AlertState
+	}{
+		{
+			name:   "below_threshold",
+			value:  "50",
+			offset: 0,
+			want:   []entities.AlertState{},
+		},
+		{
+			name:   "pending",
+			value:  "95",
+			offset: 10 * time.Second,
+			want:   []entities.AlertState{entities.StatePending},
+		},
+		{
+			name:   "still_pending",
+			value:  "96",
+			offset: 30 * time.Second,
+			want:   []entities.AlertState{entities.StatePending},
+		},
+		{
+			name:   "firing",
+			value:  "97",
+			offset: 80 * time.Second,
+			want:   []entities.AlertState{entities.StatePending, entities.StateFiring},
+		},
+		{
+			name:   "resolved",
+			value:  "20",
+			offset: 90 * time.Second,
+			want:   []entities.AlertState{entities.StatePending, entities.StateFiring, entities.StateResolved},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			require.NoError(t, ms.Put(ctx, "gauge", "CPUutilization1", tc.value))
+			e.Evaluate(ctx, start.Add(tc.offset))
+			assert.Equal(t, tc.want, transitions)
+		})
+	}
+	assert.Empty(t, e.Alerts(ctx))
+}
+
+func TestEngine_DeleteRuleResolves(t *testing.T) {
+	ctx := context.Background()
+	ms := storage.NewMemStorage(ctx)
+	e := NewEngine(ctx, ms, nil)
+
+	var last entities.Alert
+	e.AddListener(func(ctx context.Context, alert entities.Alert) {
+		last = alert
+	})
+
+	rule, err := e.CreateRule(ctx, entities.Rule{
+		Name:       "ManyPolls",
+		MetricType: "counter",
+		MetricName: "PollCount",
+		Operator:   ">=",
+		Threshold:  1,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, rule.ID)
+
+	require.NoError(t, ms.Put(ctx, "counter", "PollCount", "5"))
+	e.Evaluate(ctx, time.Now())
+
+	alerts := e.Alerts(ctx)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, entities.StateFiring, alerts[0].State)
+	assert.Equal(t, float64(5), alerts[0].Value)
+	assert.Equal(t, map[string]string{"alertname": "ManyPolls", "metric": "PollCount"}, alerts[0].Labels)
+
+	require.NoError(t, e.DeleteRule(ctx, rule.ID))
+	assert.Empty(t, e.Alerts(ctx))
+	assert.Equal(t, entities.StateResolved, last.State)
+}
+
+func TestEngine_EvaluateAbsent(t *testing.T) {
+	ctx := context.Background()
+	ms := storage.NewMemStorage(ctx)
+	e := NewEngine(ctx, ms, nil)
+
+	_, err := e.CreateRule(ctx, entities.Rule{
+		ID:         "heap",
+		Name:       "HeapAbsent",
+		Kind:       entities.RuleAbsent,
+		MetricName: "HeapAlloc",
+		AbsentFor:  entities.Duration(time.Minute),
+	})
+	require.NoError(t, err)
+	_, err = e.CreateRule(ctx, entities.Rule{
+		ID:        "agent",
+		Name:      "AgentDown",
+		Kind:      entities.RuleAbsent,
+		AbsentFor: entities.Duration(5 * time.Minute),
+	})
+	require.NoError(t, err)
+
+	start := time.Now()
+	require.NoError(t, ms.Put(ctx, "gauge", "HeapAlloc", "1"))
+	require.NoError(t, ms.Put(ctx, "gauge", "Alloc", "1"))
+
+	firing := func(now time.Time) []string {
+		e.Evaluate(ctx, now)
+		names := make([]string, 0)
+		for _, a := range e.Alerts(ctx) {
+			if a.State == entities.StateFiring {
+				names = append(names, a.RuleName)
+			}
+		}
+		return names
+	}
+
+	assert.Empty(t, firing(start.Add(30*time.Second)))
+	assert.Equal(t, []string{"HeapAbsent"}, firing(start.Add(2*time.Minute)))
+
+	// агент продолжает отправлять другие метрики
+	require.NoError(t, ms.Put(ctx, "gauge", "Alloc", "2"))
+	assert.Equal(t, []string{"HeapAbsent"}, firing(time.Now().Add(4*time.Minute)))
+
+	alerts := firing(time.Now().Add(10 * time.Minute))
+	assert.ElementsMatch(t, []string{"HeapAbsent", "AgentDown"}, alerts)
+	for _, a := range e.Alerts(ctx) {
+		if a.RuleID == "agent" {
+			assert.Equal(t, map[string]string{"alertname": "AgentDown"}, a.Labels)
+			assert.GreaterOrEqual(t, a.Value, (10 * time.Minute).Seconds())
+		}
+	}
+
+	// метрика снова обновляется
+	require.NoError(t, ms.Put(ctx, "gauge", "HeapAlloc", "2"))
+	assert.Empty(t, firing(time.Now()))
+}
+
+func TestEngine_EvaluateExpr(t *testing.T) {
+	ctx := context.Background()
+	ms := storage.NewMemStorage(ctx)
+	e := NewEngine(ctx, ms, nil)
+
+	_, err := e.CreateRule(ctx, entities.Rule{
+		ID:      "memory",
+		Name:    "LowMemoryHighCPU",
+		Kind:    entities.RuleExpr,
+		Expr:    "FreeMemory < 5% * TotalMemory and CPUutilization1 > 90",
+		For:     entities.Duration(2 * time.Minute),
+		Message: `free {{index .Values "FreeMemory"}} of {{index .Values "TotalMemory"}}, cpu {{index .Values "CPUutilization1"}}`,
+	})
+	require.NoError(t, err)
+
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	put := func(free, total, cpu string) {
+		require.NoError(t, ms.Put(ctx, "gauge", "FreeMemory", free))
+		require.NoError(t, ms.Put(ctx, "gauge", "TotalMemory", total))
+		require.NoError(t, ms.Put(ctx, "gauge", "CPUutilization1", cpu))
+	}
+
+	// метрика TotalMemory ещё не получена
+	require.NoError(t, ms.Put(ctx, "gauge", "FreeMemory", "100"))
+	e.Evaluate(ctx, start)
+	assert.Empty(t, e.Alerts(ctx))
+
+	put("400", "10000", "95")
+	e.Evaluate(ctx, start.Add(time.Minute))
+	alerts := e.Alerts(ctx)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, entities.StatePending, alerts[0].State)
+	assert.Equal(t, map[string]string{"alertname": "LowMemoryHighCPU"}, alerts[0].Labels)
+
+	put("300", "10000", "97")
+	e.Evaluate(ctx, start.Add(3*time.Minute))
+	alerts = e.Alerts(ctx)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, entities.StateFiring, alerts[0].State)
+	assert.Equal(t, map[string]float64{"FreeMemory": 300, "TotalMemory": 10000, "CPUutilization1": 97}, alerts[0].Values)
+	assert.Equal(t, "free 300 of 10000, cpu 97", alerts[0].Message)
+
+	put("300", "10000", "50")
+	e.Evaluate(ctx, start.Add(4*time.Minute))
+	assert.Empty(t, e.Alerts(ctx))
+}
+
+func TestEngine_EvaluateExprUpdated(t *testing.T) {
+	ctx := context.Background()
+	ms := storage.NewMemStorage(ctx)
+	e := NewEngine(ctx, ms, nil)
+
+	rule, err := e.CreateRule(ctx, entities.Rule{
+		ID:      "polls",
+		Name:    "ManyPolls",
+		Kind:    entities.RuleExpr,
+		Expr:    "PollCount > 10",
+		Message: `polls {{index .Values "PollCount"}}`,
+	})
+	require.NoError(t, err)
+
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	require.NoError(t, ms.Put(ctx, "counter", "PollCount", "5"))
+	e.Evaluate(ctx, now)
+	assert.Empty(t, e.Alerts(ctx))
+
+	// изменённое выражение и шаблон разбираются заново
+	rule.Expr = "PollCount > 1"
+	rule.Message = `count {{index .Values "PollCount"}}`
+	_, err = e.UpdateRule(ctx, rule.ID, rule)
+	require.NoError(t, err)
+
+	e.Evaluate(ctx, now.Add(time.Minute))
+	alerts := e.Alerts(ctx)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, entities.StateFiring, alerts[0].State)
+	assert.Equal(t, "count 5", alerts[0].Message)
+}
@@ -0,0 +1,392 @@
+package alerting
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ErrInvalidExpr возвращается при ошибке разбора выражения правила.
+var ErrInvalidExpr = errors.New("invalid expression")
+
+// Expr содержит разобранное выражение составного правила. Выражение
+// вычисляется по значениям метрик, логические значения представлены
+// числами 1 и 0.
+//
+// Поддерживаются числа, в том числе с суффиксом % (5% равно 0.05),
+// имена метрик, арифметические операторы + - * /, операторы сравнения
+// > >= < <= == !=, логические операторы and (&&), or (||), not (!)
+// в любом регистре и скобки.
+type Expr struct {
+	root    node
+	metrics []string
+}
+
+// node содержит узел дерева выражения.
+type node interface {
+	eval(values map[string]float64) (float64, error)
+}
+
+type (
+	// numberNode содержит числовую константу.
+	numberNode float64
+
+	// metricNode содержит ссылку на значение метрики.
+	metricNode string
+
+	// unaryNode содержит унарную операцию.
+	unaryNode struct {
+		op string
+		x  node
+	}
+
+	// binaryNode содержит бинарную операцию.
+	binaryNode struct {
+		op   string
+		l, r node
+	}
+)
+
+// ParseExpr разбирает выражение составного правила.
+func ParseExpr(s string) (*Expr, error) {
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, fmt.Errorf("ParseExpr: %w", err)
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("ParseExpr: %w: expression is empty", ErrInvalidExpr)
+	}
+
+	p := &parser{tokens: tokens, seen: make(map[string]struct{})}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, fmt.Errorf("ParseExpr: %w", err)
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("ParseExpr: %w: unexpected %q", ErrInvalidExpr, p.tokens[p.pos])
+	}
+	return &Expr{root: root, metrics: p.metrics}, nil
+}
+
+// Metrics возвращает имена метрик, на которые ссылается выражение,
+// в порядке первого упоминания.
+func (e *Expr) Metrics() []string {
+	return append([]string(nil), e.metrics...)
+}
+
+// Eval вычисляет выражение по значениям метрик.
+func (e *Expr) Eval(values map[string]float64) (float64, error) {
+	v, err := e.root.eval(values)
+	if err != nil {
+		return 0, fmt.Errorf("Eval: %w", err)
+	}
+	return v, nil
+}
+
+func (n numberNode) eval(values map[string]float64) (float64, error) {
+	return float64(n), nil
+}
+
+func (n metricNode) eval(values map[string]float64) (float64, error) {
+	v, ok := values[string(n)]
+	if !ok {
+		return 0, fmt.Errorf("metric %q not found", string(n))
+	}
+	return v, nil
+}
+
+func (n unaryNode) eval(values map[string]float64) (float64, error) {
+	x, err := n.x.eval(values)
+	if err != nil {
+		return 0, err
+	}
+	if n.op == "not" {
+		return boolValue(x == 0), nil
+	}
+	return -x, nil
+}
+
+func (n binaryNode) eval(values map[string]float64) (float64, error) {
+	l, err := n.l.eval(values)
+	if err != nil {
+		return 0, err
+	}
+	// логические операторы вычисляются по короткой схеме
+	switch {
+	case n.op == "and" && l == 0:
+		return 0, nil
+	case n.op == "or" && l != 0:
+		return 1, nil
+	}
+	r, err := n.r.eval(values)
+	if err != nil {
+		return 0, err
+	}
+
+	switch n.op {
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	case "/":
+		if r == 0 {
+			return 0, fmt.Errorf("division by zero")
+		}
+		return l / r, nil
+	case "and", "or":
+		return boolValue(r != 0), nil
+	default:
+		return boolValue(compare(l, n.op, r)), nil
+	}
+}
+
+// boolValue представляет логическое значение числом.
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// parser содержит состояние разбора выражения.
+type parser struct {
+	tokens  []string
+	pos     int
+	metrics []string
+	seen    map[string]struct{}
+}
+
+// peek возвращает текущую лексему без перехода к следующей.
+func (p *parser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+// accept переходит к следующей лексеме, если текущая входит в указанные.
+func (p *parser) accept(ops ...string) (string, bool) {
+	tok := p.peek()
+	for _, op := range ops {
+		if tok == op {
+			p.pos++
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) parseOr() (node, error) {
+	l, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("or", "||"); !ok {
+			return l, nil
+		}
+		r, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l = binaryNode{op: "or", l: l, r: r}
+	}
+}
+
+func (p *parser) parseAnd() (node, error) {
+	l, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("and", "&&"); !ok {
+			return l, nil
+		}
+		r, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		l = binaryNode{op: "and", l: l, r: r}
+	}
+}
+
+func (p *parser) parseNot() (node, error) {
+	if _, ok := p.accept("not", "!"); ok {
+		x, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return unaryNode{op: "not", x: x}, nil
+	}
+	return p.parseCompare()
+}
+
+func (p *parser) parseCompare() (node, error) {
+	l, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	op, ok := p.accept(">", ">=", "<", "<=", "==", "!=")
+	if !ok {
+		return l, nil
+	}
+	r, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	return binaryNode{op: op, l: l, r: r}, nil
+}
+
+func (p *parser) parseSum() (node, error) {
+	l, err := p.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("+", "-")
+		if !ok {
+			return l, nil
+		}
+		r, err := p.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		l = binaryNode{op: op, l: l, r: r}
+	}
+}
+
+func (p *parser) parseProduct() (node, error) {
+	l, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("*", "/")
+		if !ok {
+			return l, nil
+		}
+		r, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l = binaryNode{op: op, l: l, r: r}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if _, ok := p.accept("-"); ok {
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return unaryNode{op: "-", x: x}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.peek()
+	switch {
+	case tok == "":
+		return nil, fmt.Errorf("%w: unexpected end of expression", ErrInvalidExpr)
+	case tok == "(":
+		p.pos++
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := p.accept(")"); !ok {
+			return nil, fmt.Errorf("%w: missing closing parenthesis", ErrInvalidExpr)
+		}
+		return x, nil
+	case isNumberStart(rune(tok[0])):
+		p.pos++
+		percent := strings.HasSuffix(tok, "%")
+		v, err := strconv.ParseFloat(strings.TrimSuffix(tok, "%"), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid number %q", ErrInvalidExpr, tok)
+		}
+		if percent {
+			v /= 100
+		}
+		return numberNode(v), nil
+	case isIdentStart(rune(tok[0])) && !isKeyword(tok):
+		p.pos++
+		if _, ok := p.seen[tok]; !ok {
+			p.seen[tok] = struct{}{}
+			p.metrics = append(p.metrics, tok)
+		}
+		return metricNode(tok), nil
+	default:
+		return nil, fmt.Errorf("%w: unexpected %q", ErrInvalidExpr, tok)
+	}
+}
+
+// tokenize разбивает выражение на лексемы.
+func tokenize(s string) ([]string, error) {
+	tokens := make([]string, 0)
+	runes := []rune(s)
+	for i := 0; i < len(runes); {
+		c := runes[i]
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case isNumberStart(c):
+			j := i + 1
+			for j < len(runes) && (isNumberStart(runes[j]) || runes[j] == 'e' || runes[j] == 'E' ||
+				((runes[j] == '+' || runes[j] == '-') && (runes[j-1] == 'e' || runes[j-1] == 'E'))) {
+				j++
+			}
+			if j < len(runes) && runes[j] == '%' {
+				j++
+			}
+			tokens = append(tokens, string(runes[i:j]))
+			i = j
+		case isIdentStart(c):
+			j := i + 1
+			for j < len(runes) && (isIdentStart(runes[j]) || unicode.IsDigit(runes[j])) {
+				j++
+			}
+			tok := string(runes[i:j])
+			if isKeyword(strings.ToLower(tok)) {
+				tok = strings.ToLower(tok)
+			}
+			tokens = append(tokens, tok)
+			i = j
+		default:
+			if i+1 < len(runes) {
+				switch two := string(runes[i : i+2]); two {
+				case ">=", "<=", "==", "!=", "&&", "||":
+					tokens = append(tokens, two)
+					i += 2
+					continue
+				}
+			}
+			if !strings.ContainsRune("+-*/()<>!", c) {
+				return nil, fmt.Errorf("%w: unexpected character %q", ErrInvalidExpr, c)
+			}
+			tokens = append(tokens, string(c))
+			i++
+		}
+	}
+	return tokens, nil
+}
+
+// isNumberStart проверяет, что символ может входить в число.
+func isNumberStart(c rune) bool {
+	return unicode.IsDigit(c) || c == '.'
+}
+
+// isIdentStart проверяет, что символ может начинать имя метрики.
+func isIdentStart(c rune) bool {
+	return unicode.IsLetter(c) || c == '_'
+}
+
+// isKeyword проверяет, что лексема является логическим оператором.
+func isKeyword(tok string) bool {
+	return tok == "and" || tok == "or" || tok == "not"
+}
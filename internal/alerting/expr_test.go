@@ -0,0 +1,114 @@
+package alerting
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseExpr(t *testing.T) {
+	values := map[string]float64{
+		"FreeMemory":      400,
+		"TotalMemory":     10000,
+		"CPUutilization1": 95,
+		"Alloc":           30,
+		"HeapAlloc":       20,
+		"Zero":            0,
+	}
+
+	tests := []struct {
+		name        string
+		expr        string
+		want        float64
+		wantMetrics []string
+		wantErr     bool
+		wantEvalErr bool
+	}{
+		{
+			name:        "uppercase_keywords",
+			expr:        "FreeMemory < 5% * TotalMemory AND NOT CPUutilization1 < 90",
+			want:        1,
+			wantMetrics: []string{"FreeMemory", "TotalMemory", "CPUutilization1"},
+		},
+		{
+			name:        "composite",
+			expr:        "FreeMemory < 5% * TotalMemory and CPUutilization1 > 90",
+			want:        1,
+			wantMetrics: []string{"FreeMemory", "TotalMemory", "CPUutilization1"},
+		},
+		{
+			name:        "sum",
+			expr:        "Alloc + HeapAlloc",
+			want:        50,
+			wantMetrics: []string{"Alloc", "HeapAlloc"},
+		},
+		{
+			name:        "precedence",
+			expr:        "2 + 3 * 4 - -1",
+			want:        15,
+			wantMetrics: nil,
+		},
+		{
+			name:        "parentheses_ratio",
+			expr:        "(TotalMemory - FreeMemory) / TotalMemory * 100",
+			want:        96,
+			wantMetrics: []string{"TotalMemory", "FreeMemory"},
+		},
+		{
+			name:        "or_not",
+			expr:        "not (Alloc > 100) || Zero",
+			want:        1,
+			wantMetrics: []string{"Alloc", "Zero"},
+		},
+		{
+			name:        "short_circuit",
+			expr:        "Zero != 0 && Alloc / Zero > 1",
+			want:        0,
+			wantMetrics: []string{"Zero", "Alloc"},
+		},
+		{
+			name:        "exponent",
+			expr:        "TotalMemory >= 1e4",
+			want:        1,
+			wantMetrics: []string{"TotalMemory"},
+		},
+		{
+			name:        "division_by_zero",
+			expr:        "Alloc / Zero",
+			wantMetrics: []string{"Alloc", "Zero"},
+			wantEvalErr: true,
+		},
+		{
+			name:        "unknown_metric",
+			expr:        "PollCount > 1",
+			wantMetrics: []string{"PollCount"},
+			wantEvalErr: true,
+		},
+		{name: "empty", expr: " ", wantErr: true},
+		{name: "unbalanced", expr: "(Alloc + 1", wantErr: true},
+		{name: "dangling_operator", expr: "Alloc >", wantErr: true},
+		{name: "chained_compare", expr: "1 < Alloc < 3", wantErr: true},
+		{name: "bad_character", expr: "Alloc % 2", wantErr: true},
+		{name: "bad_number", expr: "1.2.3", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			expr, err := ParseExpr(tc.expr)
+			if tc.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidExpr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantMetrics, expr.Metrics())
+
+			got, err := expr.Eval(values)
+			if tc.wantEvalErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.InDelta(t, tc.want, got, 1e-9)
+		})
+	}
+}
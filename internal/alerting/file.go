@@ -0,0 +1,367 @@
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/mail"
+	"net/url"
+	"os"
+	"regexp"
+	"text/template"
+	"time"
+
+	"github.com/pavlegich/metrics-alerting/internal/entities"
+	"github.com/pavlegich/metrics-alerting/internal/infra/logger"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrInvalidConfig возвращается при некорректных настройках уведомлений.
+var ErrInvalidConfig = errors.New("invalid notification config")
+
+// RulesFile содержит декларативное описание правил оповещений, правил
+// подавления, маршрута, политик эскалации и получателей уведомлений.
+// Файл записывается в формате YAML или JSON.
+type RulesFile struct {
+	ExternalURL  string                      `yaml:"external_url"`
+	Rules        []entities.Rule             `yaml:"rules"`
+	InhibitRules []entities.InhibitRule      `yaml:"inhibit_rules"`
+	Route        entities.Route              `yaml:"route"`
+	Escalations  []entities.EscalationPolicy `yaml:"escalations"`
+	Receivers    []entities.Receiver         `yaml:"receivers"`
+}
+
+// ParseRulesFile читает и проверяет файл правил оповещений.
+// Ошибки содержат номер строки файла, в которой описан некорректный элемент.
+// Правило без идентификатора получает идентификатор, равный названию.
+func ParseRulesFile(path string) (RulesFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return RulesFile{}, fmt.Errorf("ParseRulesFile: read file failed %w", err)
+	}
+
+	// строгая проверка структуры файла, включая неизвестные поля
+	var file RulesFile
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return RulesFile{}, fmt.Errorf("ParseRulesFile: %s: %w", path, err)
+	}
+
+	// проверка элементов файла с указанием строк
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return RulesFile{}, fmt.Errorf("ParseRulesFile: %s: %w", path, err)
+	}
+
+	items := sectionItems(&root, "rules")
+	lines := make(map[string]int, len(file.Rules))
+	for i := range file.Rules {
+		rule := &file.Rules[i]
+		line := itemLine(items, i)
+
+		if rule.ID == "" {
+			rule.ID = rule.Name
+		}
+		if err := ValidateRule(*rule); err != nil {
+			return RulesFile{}, fmt.Errorf("ParseRulesFile: %s:%d: rule %q: %w", path, line, rule.ID, err)
+		}
+		if prev, ok := lines[rule.ID]; ok {
+			return RulesFile{}, fmt.Errorf("ParseRulesFile: %s:%d: rule %q: %w, first defined at line %d",
+				path, line, rule.ID, ErrRuleExists, prev)
+		}
+		lines[rule.ID] = line
+	}
+
+	items = sectionItems(&root, "inhibit_rules")
+	for i, r := range file.InhibitRules {
+		if err := ValidateInhibitRule(r); err != nil {
+			return RulesFile{}, fmt.Errorf("ParseRulesFile: %s:%d: inhibit rule: %w", path, itemLine(items, i), err)
+		}
+	}
+
+	items = sectionItems(&root, "receivers")
+	receivers := make(map[string]int, len(file.Receivers))
+	for i, r := range file.Receivers {
+		line := itemLine(items, i)
+
+		if err := ValidateReceiver(r); err != nil {
+			return RulesFile{}, fmt.Errorf("ParseRulesFile: %s:%d: receiver %q: %w", path, line, r.Name, err)
+		}
+		if prev, ok := receivers[r.Name]; ok {
+			return RulesFile{}, fmt.Errorf("ParseRulesFile: %s:%d: receiver %q: %w: duplicate name, first defined at line %d",
+				path, line, r.Name, ErrInvalidConfig, prev)
+		}
+		receivers[r.Name] = line
+	}
+
+	if file.ExternalURL != "" {
+		if _, err := url.ParseRequestURI(file.ExternalURL); err != nil {
+			line := 0
+			if node := section(&root, "external_url"); node != nil {
+				line = node.Line
+			}
+			return RulesFile{}, fmt.Errorf("ParseRulesFile: %s:%d: external_url: %w: invalid url %q",
+				path, line, ErrInvalidConfig, file.ExternalURL)
+		}
+	}
+
+	items = sectionItems(&root, "escalations")
+	policies := make(map[string]int, len(file.Escalations))
+	for i, p := range file.Escalations {
+		line := itemLine(items, i)
+
+		if err := validateEscalation(p, receivers); err != nil {
+			return RulesFile{}, fmt.Errorf("ParseRulesFile: %s:%d: escalation %q: %w", path, line, p.Name, err)
+		}
+		if prev, ok := policies[p.Name]; ok {
+			return RulesFile{}, fmt.Errorf("ParseRulesFile: %s:%d: escalation %q: %w: duplicate name, first defined at line %d",
+				path, line, p.Name, ErrInvalidConfig, prev)
+		}
+		policies[p.Name] = line
+	}
+
+	if err := validateRoute(file.Route, receivers, len(file.Escalations) > 0); err != nil {
+		line := 0
+		if node := section(&root, "route"); node != nil {
+			line = node.Line
+		}
+		return RulesFile{}, fmt.Errorf("ParseRulesFile: %s:%d: route: %w", path, line, err)
+	}
+
+	return file, nil
+}
+
+// ValidateReceiver проверяет настройки получателя уведомлений.
+func ValidateReceiver(r entities.Receiver) error {
+	if r.Name == "" {
+		return fmt.Errorf("%w: receiver name is empty", ErrInvalidConfig)
+	}
+
+	channels := 0
+	if r.Webhook != nil {
+		channels++
+		if _, err := url.ParseRequestURI(r.Webhook.URL); err != nil {
+			return fmt.Errorf("%w: invalid webhook url %q", ErrInvalidConfig, r.Webhook.URL)
+		}
+	}
+	if r.Email != nil {
+		channels++
+		if err := validateEmail(*r.Email); err != nil {
+			return err
+		}
+	}
+	if r.Slack != nil {
+		channels++
+		if _, err := url.ParseRequestURI(r.Slack.URL); err != nil {
+			return fmt.Errorf("%w: invalid slack url %q", ErrInvalidConfig, r.Slack.URL)
+		}
+	}
+	if r.Telegram != nil {
+		channels++
+		if r.Telegram.BotToken == "" || r.Telegram.ChatID == "" {
+			return fmt.Errorf("%w: telegram bot token and chat id are required", ErrInvalidConfig)
+		}
+		if r.Telegram.APIURL != "" {
+			if _, err := url.ParseRequestURI(r.Telegram.APIURL); err != nil {
+				return fmt.Errorf("%w: invalid telegram api url %q", ErrInvalidConfig, r.Telegram.APIURL)
+			}
+		}
+	}
+	if channels != 1 {
+		return fmt.Errorf("%w: exactly one channel must be configured", ErrInvalidConfig)
+	}
+	return nil
+}
+
+// validateEmail проверяет настройки отправки уведомлений по SMTP.
+func validateEmail(cfg entities.EmailConfig) error {
+	if _, _, err := net.SplitHostPort(cfg.Smarthost); err != nil {
+		return fmt.Errorf("%w: invalid email smarthost %q", ErrInvalidConfig, cfg.Smarthost)
+	}
+	switch cfg.TLS {
+	case "", entities.EmailTLSNone, entities.EmailTLSStartTLS, entities.EmailTLS:
+	default:
+		return fmt.Errorf("%w: unsupported email tls mode %q", ErrInvalidConfig, cfg.TLS)
+	}
+	if _, err := mail.ParseAddress(cfg.From); err != nil {
+		return fmt.Errorf("%w: invalid email from address %q", ErrInvalidConfig, cfg.From)
+	}
+	if len(cfg.To) == 0 {
+		return fmt.Errorf("%w: email recipients are empty", ErrInvalidConfig)
+	}
+	for _, to := range cfg.To {
+		if _, err := mail.ParseAddress(to); err != nil {
+			return fmt.Errorf("%w: invalid email to address %q", ErrInvalidConfig, to)
+		}
+	}
+	for name, text := range map[string]string{"subject": cfg.Subject, "text": cfg.Text, "html": cfg.HTML} {
+		if _, err := template.New(name).Parse(text); err != nil {
+			return fmt.Errorf("%w: invalid email %s template: %s", ErrInvalidConfig, name, err)
+		}
+	}
+	return nil
+}
+
+// validateRoute проверяет настройки маршрута уведомлений. При наличии
+// политик эскалации получатели могут использоваться только в них,
+// тогда получатель маршрута может отсутствовать.
+func validateRoute(route entities.Route, receivers map[string]int, escalated bool) error {
+	if _, ok := receivers[route.Receiver]; route.Receiver != "" && !ok {
+		return fmt.Errorf("%w: unknown receiver %q", ErrInvalidConfig, route.Receiver)
+	}
+	if route.Receiver == "" && len(receivers) > 0 && !escalated {
+		return fmt.Errorf("%w: receiver is empty", ErrInvalidConfig)
+	}
+	for _, l := range route.GroupBy {
+		if !labelName.MatchString(l) {
+			return fmt.Errorf("%w: invalid group_by label name %q", ErrInvalidConfig, l)
+		}
+	}
+	if route.GroupWait < 0 || route.GroupInterval < 0 || route.RepeatInterval < 0 {
+		return fmt.Errorf("%w: negative interval", ErrInvalidConfig)
+	}
+	return nil
+}
+
+// validateEscalation проверяет политику эскалации: получатели шагов должны
+// быть описаны в файле, а время шагов не должно убывать.
+func validateEscalation(p entities.EscalationPolicy, receivers map[string]int) error {
+	if p.Name == "" {
+		return fmt.Errorf("%w: escalation name is empty", ErrInvalidConfig)
+	}
+	for _, m := range p.Matchers {
+		if !labelName.MatchString(m.Name) {
+			return fmt.Errorf("%w: invalid matcher label name %q", ErrInvalidConfig, m.Name)
+		}
+		if m.IsRegex {
+			if _, err := regexp.Compile(m.Value); err != nil {
+				return fmt.Errorf("%w: invalid matcher regex %q", ErrInvalidConfig, m.Value)
+			}
+		}
+	}
+	if len(p.Steps) == 0 {
+		return fmt.Errorf("%w: steps are empty", ErrInvalidConfig)
+	}
+	var prev entities.Duration
+	for i, step := range p.Steps {
+		if _, ok := receivers[step.Receiver]; !ok {
+			return fmt.Errorf("%w: step %d: unknown receiver %q", ErrInvalidConfig, i+1, step.Receiver)
+		}
+		if step.After < prev {
+			return fmt.Errorf("%w: step %d: after must not decrease", ErrInvalidConfig, i+1)
+		}
+		prev = step.After
+	}
+	return nil
+}
+
+// section возвращает узел значения раздела документа верхнего уровня.
+func section(root *yaml.Node, name string) *yaml.Node {
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 {
+		return nil
+	}
+	doc := root.Content[0]
+	if doc.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(doc.Content); i += 2 {
+		if doc.Content[i].Value == name {
+			return doc.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// sectionItems возвращает узлы элементов раздела-списка документа.
+func sectionItems(root *yaml.Node, name string) []*yaml.Node {
+	node := section(root, name)
+	if node == nil || node.Kind != yaml.SequenceNode {
+		return nil
+	}
+	return node.Content
+}
+
+// itemLine возвращает номер строки элемента списка.
+func itemLine(items []*yaml.Node, i int) int {
+	if i < len(items) {
+		return items[i].Line
+	}
+	return 0
+}
+
+// FileListener вызывается после загрузки файла правил.
+type FileListener func(ctx context.Context, file RulesFile)
+
+// AddFileListener подключает обработчик загрузки файла правил,
+// например для применения настроек уведомлений.
+func (e *Engine) AddFileListener(l FileListener) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.fileListeners = append(e.fileListeners, l)
+}
+
+// LoadRulesFile читает файл правил, атомарно заменяет правила,
+// загруженные из файла ранее, и правила подавления
+// и передаёт файл обработчикам загрузки.
+func (e *Engine) LoadRulesFile(ctx context.Context, path string) error {
+	file, err := ParseRulesFile(path)
+	if err != nil {
+		return fmt.Errorf("LoadRulesFile: %w", err)
+	}
+	inhibitRules := compileInhibitRules(file.InhibitRules)
+
+	// правила и правила подавления заменяются под одной блокировкой,
+	// чтобы вычисление не видело новые правила со старыми правилами подавления
+	e.mu.Lock()
+	resolved, err := e.replaceFileRules(file.Rules)
+	if err != nil {
+		e.mu.Unlock()
+		return fmt.Errorf("LoadRulesFile: %w", err)
+	}
+	e.inhibitRules = inhibitRules
+	listeners := e.fileListeners
+	e.mu.Unlock()
+
+	e.notify(ctx, resolved)
+	for _, l := range listeners {
+		l(ctx, file)
+	}
+	return nil
+}
+
+// WatchRulesFile перечитывает файл правил при его изменении и при получении
+// сигнала из канала hup. При ошибке в файле сохраняются действующие правила.
+func (e *Engine) WatchRulesFile(ctx context.Context, path string, interval time.Duration,
+	hup <-chan os.Signal) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last, _ := os.Stat(path)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-hup:
+			last, _ = os.Stat(path)
+		case <-ticker.C:
+			info, err := os.Stat(path)
+			if err != nil || (last != nil && info.ModTime().Equal(last.ModTime()) && info.Size() == last.Size()) {
+				continue
+			}
+			last = info
+		}
+
+		if err := e.LoadRulesFile(ctx, path); err != nil {
+			logger.Log.Error("WatchRulesFile: reload rules file failed, previous rules kept",
+				zap.String("path", path), zap.Error(err))
+			continue
+		}
+		logger.Log.Info("rules file reloaded", zap.String("path", path))
+	}
+}
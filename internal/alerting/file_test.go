@@ -0,0 +1,213 @@
+package alerting
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pavlegich/metrics-alerting/internal/entities"
+	"github.com/pavlegich/metrics-alerting/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRulesFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    []entities.Rule
+		wantErr string
+	}{
+		{
+			name: "yaml",
+			file: "rules.yaml",
+			content: `rules:
+  - name: HighCPU
+    metric_type: gauge
+    metric_name: CPUutilization1
+    operator: ">"
+    threshold: 90
+    for: 2m
+    labels:
+      severity: critical
+`,
+			want: []entities.Rule{{
+				ID:         "HighCPU",
+				Name:       "HighCPU",
+				MetricType: "gauge",
+				MetricName: "CPUutilization1",
+				Operator:   ">",
+				Threshold:  90,
+				For:        entities.Duration(2 * time.Minute),
+				Labels:     map[string]string{"severity": "critical"},
+			}},
+		},
+		{
+			name: "json",
+			file: "rules.json",
+			content: `{"rules": [
+  {"id": "polls", "name": "ManyPolls", "metric_type": "counter",
+   "metric_name": "PollCount", "operator": ">=", "threshold": 100}
+]}`,
+			want: []entities.Rule{{
+				ID:         "polls",
+				Name:       "ManyPolls",
+				MetricType: "counter",
+				MetricName: "PollCount",
+				Operator:   ">=",
+				Threshold:  100,
+			}},
+		},
+		{
+			name: "invalid_rule_line",
+			file: "rules.yaml",
+			content: `rules:
+  - name: HighCPU
+    metric_type: gauge
+    metric_name: CPUutilization1
+    operator: ">"
+  - name: Broken
+    metric_type: histogram
+    metric_name: Alloc
+    operator: ">"
+`,
+			wantErr: "rules.yaml:6: rule \"Broken\": invalid rule",
+		},
+		{
+			name: "unknown_field",
+			file: "rules.yaml",
+			content: `rules:
+  - name: HighCPU
+    treshold: 90
+`,
+			wantErr: "line 3: field treshold not found",
+		},
+		{
+			name: "invalid_duration",
+			file: "rules.yaml",
+			content: `rules:
+  - name: HighCPU
+    for: soon
+`,
+			wantErr: "invalid duration",
+		},
+		{
+			name: "duplicate_id",
+			file: "rules.yaml",
+			content: `rules:
+  - {name: A, metric_type: gauge, metric_name: Alloc, operator: ">"}
+  - {name: A, metric_type: gauge, metric_name: Alloc, operator: "<"}
+`,
+			wantErr: "rules.yaml:3: rule \"A\": rule already exists, first defined at line 2",
+		},
+		{
+			name: "unknown_receiver",
+			file: "rules.yaml",
+			content: `receivers:
+  - name: ops
+    webhook:
+      url: http://localhost:9093/hook
+route:
+  receiver: oncall
+`,
+			wantErr: "rules.yaml:6: route: invalid notification config: unknown receiver \"oncall\"",
+		},
+		{
+			name: "invalid_inhibit_rule",
+			file: "rules.yaml",
+			content: `inhibit_rules:
+  - source_matchers: [{name: alertname, value: AgentAbsent}]
+    target_matchers: [{name: severity, value: warning}]
+    equal: [host]
+  - source_matchers: [{name: alertname, value: AgentAbsent}]
+`,
+			wantErr: "rules.yaml:5: inhibit rule: invalid inhibit rule: target matchers are empty",
+		},
+		{
+			name: "escalation_step_order",
+			file: "rules.yaml",
+			content: `receivers:
+  - name: primary
+    webhook: {url: "http://localhost"}
+  - name: secondary
+    webhook: {url: "http://localhost"}
+escalations:
+  - name: oncall
+    matchers: [{name: severity, value: critical}]
+    steps:
+      - {receiver: primary, after: 10m}
+      - {receiver: secondary, after: 5m}
+`,
+			wantErr: "rules.yaml:7: escalation \"oncall\": invalid notification config: step 2: after must not decrease",
+		},
+		{
+			name: "receiver_without_channel",
+			file: "rules.yaml",
+			content: `receivers:
+  - name: ops
+route:
+  receiver: ops
+`,
+			wantErr: "rules.yaml:2: receiver \"ops\": invalid notification config",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tc.file)
+			require.NoError(t, os.WriteFile(path, []byte(tc.content), 0644))
+
+			got, err := ParseRulesFile(path)
+			if tc.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got.Rules)
+		})
+	}
+}
+
+func TestEngine_ReplaceFileRules(t *testing.T) {
+	ctx := context.Background()
+	ms := storage.NewMemStorage(ctx)
+	e := NewEngine(ctx, ms, nil)
+
+	cpu := entities.Rule{ID: "cpu", Name: "HighCPU", MetricType: "gauge",
+		MetricName: "CPUutilization1", Operator: ">", Threshold: 90}
+	heap := entities.Rule{ID: "heap", Name: "HighHeap", MetricType: "gauge",
+		MetricName: "HeapAlloc", Operator: ">", Threshold: 100}
+	require.NoError(t, e.ReplaceFileRules(ctx, []entities.Rule{cpu, heap}))
+
+	require.NoError(t, ms.Put(ctx, "gauge", "CPUutilization1", "95"))
+	require.NoError(t, ms.Put(ctx, "gauge", "HeapAlloc", "150"))
+	e.Evaluate(ctx, time.Now())
+	require.Len(t, e.Alerts(ctx), 2)
+
+	// правила из файла нельзя изменить через API
+	assert.ErrorIs(t, e.DeleteRule(ctx, "cpu"), ErrRuleReadOnly)
+
+	// изменённое правило теряет состояние, неизменённое сохраняет
+	heap.Threshold = 200
+	require.NoError(t, e.ReplaceFileRules(ctx, []entities.Rule{cpu, heap}))
+	alerts := e.Alerts(ctx)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, "cpu", alerts[0].RuleID)
+	assert.Equal(t, entities.StateFiring, alerts[0].State)
+
+	// конфликт с правилом, созданным через API, не меняет правила
+	_, err := e.CreateRule(ctx, entities.Rule{ID: "api", Name: "Api", MetricType: "gauge",
+		MetricName: "Alloc", Operator: ">"})
+	require.NoError(t, err)
+	api := cpu
+	api.ID = "api"
+	assert.ErrorIs(t, e.ReplaceFileRules(ctx, []entities.Rule{api}), ErrRuleExists)
+	assert.Len(t, e.ListRules(ctx), 3)
+
+	require.NoError(t, e.ReplaceFileRules(ctx, nil))
+	assert.Len(t, e.ListRules(ctx), 1)
+	assert.Empty(t, e.Alerts(ctx))
+}
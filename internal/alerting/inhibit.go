@@ -0,0 +1,141 @@
+package alerting
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/pavlegich/metrics-alerting/internal/entities"
+	"github.com/pavlegich/metrics-alerting/internal/infra/logger"
+	"go.uber.org/zap"
+)
+
+// ErrInvalidInhibitRule возвращается при некорректном правиле подавления.
+var ErrInvalidInhibitRule = errors.New("invalid inhibit rule")
+
+// ValidateInhibitRule проверяет корректность правила подавления.
+func ValidateInhibitRule(r entities.InhibitRule) error {
+	if len(r.SourceMatchers) == 0 {
+		return fmt.Errorf("%w: source matchers are empty", ErrInvalidInhibitRule)
+	}
+	if len(r.TargetMatchers) == 0 {
+		return fmt.Errorf("%w: target matchers are empty", ErrInvalidInhibitRule)
+	}
+	for _, m := range append(append([]entities.Matcher(nil), r.SourceMatchers...), r.TargetMatchers...) {
+		if !labelName.MatchString(m.Name) {
+			return fmt.Errorf("%w: invalid matcher label name %q", ErrInvalidInhibitRule, m.Name)
+		}
+		if m.IsRegex {
+			if _, err := regexp.Compile(m.Value); err != nil {
+				return fmt.Errorf("%w: invalid matcher regex %q", ErrInvalidInhibitRule, m.Value)
+			}
+		}
+	}
+	for _, l := range r.Equal {
+		if !labelName.MatchString(l) {
+			return fmt.Errorf("%w: invalid equal label name %q", ErrInvalidInhibitRule, l)
+		}
+	}
+	return nil
+}
+
+// inhibitRule содержит правило подавления с разобранными условиями на метки.
+type inhibitRule struct {
+	entities.InhibitRule
+	source entities.LabelMatchers
+	target entities.LabelMatchers
+}
+
+// SetInhibitRules заменяет правила подавления оповещений.
+// Правила применяются при следующей оценке правил оповещений.
+// Правила с некорректными условиями пропускаются.
+func (e *Engine) SetInhibitRules(rules []entities.InhibitRule) {
+	compiled := compileInhibitRules(rules)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.inhibitRules = compiled
+}
+
+// compileInhibitRules компилирует условия правил подавления,
+// правила с некорректными условиями пропускаются.
+func compileInhibitRules(rules []entities.InhibitRule) []inhibitRule {
+	compiled := make([]inhibitRule, 0, len(rules))
+	for _, r := range rules {
+		source, err := entities.CompileMatchers(r.SourceMatchers)
+		if err != nil {
+			logger.Log.Error("compileInhibitRules: invalid source matchers", zap.Error(err))
+			continue
+		}
+		target, err := entities.CompileMatchers(r.TargetMatchers)
+		if err != nil {
+			logger.Log.Error("compileInhibitRules: invalid target matchers", zap.Error(err))
+			continue
+		}
+		compiled = append(compiled, inhibitRule{InhibitRule: r, source: source, target: target})
+	}
+	return compiled
+}
+
+// inhibitSources возвращает для каждого правила подавления срабатывающие
+// оповещения, подходящие под условия источника. Вызывается под блокировкой.
+func (e *Engine) inhibitSources() [][]*entities.Alert {
+	sources := make([][]*entities.Alert, len(e.inhibitRules))
+	if len(e.inhibitRules) == 0 {
+		return sources
+	}
+	for _, src := range e.alerts {
+		if src.State != entities.StateFiring {
+			continue
+		}
+		for i, r := range e.inhibitRules {
+			if r.source.Match(src.Labels) {
+				sources[i] = append(sources[i], src)
+			}
+		}
+	}
+	return sources
+}
+
+// inhibitedBy возвращает отпечатки срабатывающих оповещений, подавляющих
+// указанное оповещение. Источник подавляет цель, если подходит под условия
+// источника правила, цель подходит под условия цели, а значения меток из
+// списка equal у них совпадают. Источники правил вычисляются inhibitSources.
+// Вызывается под блокировкой.
+func (e *Engine) inhibitedBy(alert entities.Alert, sources [][]*entities.Alert) []string {
+	seen := make(map[string]struct{})
+	for i, r := range e.inhibitRules {
+		if len(sources[i]) == 0 || !r.target.Match(alert.Labels) {
+			continue
+		}
+		for _, src := range sources[i] {
+			if src.Fingerprint == alert.Fingerprint || !equalLabels(r.Equal, src.Labels, alert.Labels) {
+				continue
+			}
+			seen[src.Fingerprint] = struct{}{}
+		}
+	}
+	if len(seen) == 0 {
+		return nil
+	}
+
+	fps := make([]string, 0, len(seen))
+	for fp := range seen {
+		fps = append(fps, fp)
+	}
+	sort.Strings(fps)
+	return fps
+}
+
+// equalLabels проверяет, что значения указанных меток совпадают.
+// Отсутствующие метки считаются пустыми.
+func equalLabels(names []string, a map[string]string, b map[string]string) bool {
+	for _, n := range names {
+		if a[n] != b[n] {
+			return false
+		}
+	}
+	return true
+}
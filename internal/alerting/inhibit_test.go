@@ -0,0 +1,215 @@
+package alerting
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pavlegich/metrics-alerting/internal/entities"
+	"github.com/pavlegich/metrics-alerting/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateInhibitRule(t *testing.T) {
+	valid := entities.InhibitRule{
+		SourceMatchers: []entities.Matcher{{Name: "alertname", Value: "AgentAbsent"}},
+		TargetMatchers: []entities.Matcher{{Name: "severity", Value: "warning|critical", IsRegex: true}},
+		Equal:          []string{"host"},
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(r *entities.InhibitRule)
+		wantErr bool
+	}{
+		{
+			name:    "valid",
+			modify:  func(r *entities.InhibitRule) {},
+			wantErr: false,
+		},
+		{
+			name:    "empty_source",
+			modify:  func(r *entities.InhibitRule) { r.SourceMatchers = nil },
+			wantErr: true,
+		},
+		{
+			name:    "empty_target",
+			modify:  func(r *entities.InhibitRule) { r.TargetMatchers = nil },
+			wantErr: true,
+		},
+		{
+			name: "invalid_regex",
+			modify: func(r *entities.InhibitRule) {
+				r.TargetMatchers = []entities.Matcher{{Name: "severity", Value: "(", IsRegex: true}}
+			},
+			wantErr: true,
+		},
+		{
+			name:    "invalid_equal",
+			modify:  func(r *entities.InhibitRule) { r.Equal = []string{"host-name"} },
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rule := valid
+			tc.modify(&rule)
+			err := ValidateInhibitRule(rule)
+			if tc.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidInhibitRule)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestEngine_Inhibition(t *testing.T) {
+	ctx := context.Background()
+	ms := storage.NewMemStorage(ctx)
+	e := NewEngine(ctx, ms, nil)
+
+	notified := make([]string, 0)
+	e.AddNotifier(func(ctx context.Context, alert entities.Alert) {
+		notified = append(notified, alert.RuleName+"/"+string(alert.State))
+	})
+
+	rules := []entities.Rule{
+		{ID: "absent_a", Name: "AgentAbsent", Kind: entities.RuleAbsent, MetricName: "PollCount",
+			AbsentFor: entities.Duration(5 * time.Minute), Labels: map[string]string{"host": "a"}},
+		{ID: "cpu_a", Name: "HighCPU", MetricType: "gauge", MetricName: "CPUutilization1",
+			Operator: ">", Threshold: 90, Labels: map[string]string{"host": "a", "severity": "warning"}},
+		{ID: "cpu_b", Name: "HighCPUb", MetricType: "gauge", MetricName: "CPUutilization1",
+			Operator: ">", Threshold: 90, Labels: map[string]string{"host": "b", "severity": "warning"}},
+	}
+	for _, r := range rules {
+		_, err := e.CreateRule(ctx, r)
+		require.NoError(t, err)
+	}
+	e.SetInhibitRules([]entities.InhibitRule{{
+		SourceMatchers: []entities.Matcher{{Name: "alertname", Value: "AgentAbsent"}},
+		TargetMatchers: []entities.Matcher{{Name: "severity", Value: "warning"}},
+		Equal:          []string{"host"},
+	}})
+
+	require.NoError(t, ms.Put(ctx, "counter", "PollCount", "1"))
+	require.NoError(t, ms.Put(ctx, "gauge", "CPUutilization1", "95"))
+
+	// агент отправляет данные, подавления нет
+	e.Evaluate(ctx, time.Now())
+	assert.ElementsMatch(t, []string{"HighCPU/firing", "HighCPUb/firing"}, notified)
+	for _, a := range e.Alerts(ctx) {
+		assert.Empty(t, a.InhibitedBy)
+	}
+
+	// метрики агента перестали поступать
+	notified = notified[:0]
+	e.Evaluate(ctx, time.Now().Add(10*time.Minute))
+	assert.Equal(t, []string{"AgentAbsent/firing"}, notified)
+
+	alerts := make(map[string]entities.Alert)
+	for _, a := range e.Alerts(ctx) {
+		alerts[a.RuleID] = a
+	}
+	require.Len(t, alerts, 3)
+	assert.Equal(t, []string{alerts["absent_a"].Fingerprint}, alerts["cpu_a"].InhibitedBy)
+	assert.Empty(t, alerts["cpu_b"].InhibitedBy)
+	assert.Empty(t, alerts["absent_a"].InhibitedBy)
+
+	// разрешение подавленного оповещения не отправляется
+	notified = notified[:0]
+	require.NoError(t, ms.Put(ctx, "gauge", "CPUutilization1", "10"))
+	e.Evaluate(ctx, time.Now().Add(20*time.Minute))
+	assert.Equal(t, []string{"HighCPUb/resolved"}, notified)
+}
+
+func TestEngine_SuppressionEnded(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		suppress func(t *testing.T, ctx context.Context, e *Engine, ms *storage.MemStorage)
+		release  func(t *testing.T, ctx context.Context, ms *storage.MemStorage)
+		at       time.Time
+		want     []string
+	}{
+		{
+			name: "silence_expired",
+			suppress: func(t *testing.T, ctx context.Context, e *Engine, ms *storage.MemStorage) {
+				_, err := e.CreateSilence(ctx, entities.Silence{
+					Matchers:  []entities.Matcher{{Name: "alertname", Value: "HighCPU"}},
+					StartsAt:  start,
+					EndsAt:    start.Add(10 * time.Minute),
+					CreatedBy: "ops",
+				})
+				require.NoError(t, err)
+			},
+			release: func(t *testing.T, ctx context.Context, ms *storage.MemStorage) {},
+			at:      start.Add(15 * time.Minute),
+			want:    []string{"HighCPU/firing"},
+		},
+		{
+			name: "inhibition_source_resolved",
+			suppress: func(t *testing.T, ctx context.Context, e *Engine, ms *storage.MemStorage) {
+				_, err := e.CreateRule(ctx, entities.Rule{ID: "down", Name: "HostDown", MetricType: "gauge",
+					MetricName: "Up", Operator: "<", Threshold: 1, Labels: map[string]string{"host": "a"}})
+				require.NoError(t, err)
+				require.NoError(t, ms.Put(ctx, "gauge", "Up", "0"))
+				e.SetInhibitRules([]entities.InhibitRule{{
+					SourceMatchers: []entities.Matcher{{Name: "alertname", Value: "HostDown"}},
+					TargetMatchers: []entities.Matcher{{Name: "alertname", Value: "HighCPU"}},
+					Equal:          []string{"host"},
+				}})
+			},
+			release: func(t *testing.T, ctx context.Context, ms *storage.MemStorage) {
+				require.NoError(t, ms.Put(ctx, "gauge", "Up", "1"))
+			},
+			at:   start.Add(time.Minute),
+			want: []string{"HostDown/resolved", "HighCPU/firing"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			ms := storage.NewMemStorage(ctx)
+			e := NewEngine(ctx, ms, nil)
+
+			notified := make([]string, 0)
+			e.AddNotifier(func(ctx context.Context, alert entities.Alert) {
+				notified = append(notified, alert.RuleName+"/"+string(alert.State))
+			})
+			suppressed := make([]bool, 0)
+			e.AddSuppressListener(func(ctx context.Context, alert entities.Alert) {
+				suppressed = append(suppressed, alert.Suppressed())
+			})
+			evaluated := 0
+			e.AddEvaluateListener(func(ctx context.Context, alerts []entities.Alert) {
+				evaluated++
+			})
+
+			_, err := e.CreateRule(ctx, entities.Rule{ID: "cpu", Name: "HighCPU", MetricType: "gauge",
+				MetricName: "CPUutilization1", Operator: ">", Threshold: 90, Labels: map[string]string{"host": "a"}})
+			require.NoError(t, err)
+			require.NoError(t, ms.Put(ctx, "gauge", "CPUutilization1", "95"))
+			tc.suppress(t, ctx, e, ms)
+
+			// срабатывание подавленного оповещения не отправляется
+			e.Evaluate(ctx, start)
+			e.Evaluate(ctx, start.Add(time.Second))
+			assert.NotContains(t, notified, "HighCPU/firing")
+
+			// после окончания подавления срабатывающее оповещение отправляется один раз
+			notified = notified[:0]
+			tc.release(t, ctx, ms)
+			e.Evaluate(ctx, tc.at)
+			assert.ElementsMatch(t, tc.want, notified)
+
+			notified = notified[:0]
+			e.Evaluate(ctx, tc.at.Add(time.Second))
+			assert.Empty(t, notified)
+			assert.Equal(t, []bool{false}, suppressed)
+			assert.Equal(t, 4, evaluated)
+		})
+	}
+}
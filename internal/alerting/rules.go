@@ -0,0 +1,395 @@
+package alerting
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"text/template"
+	"time"
+
+	"github.com/pavlegich/metrics-alerting/internal/entities"
+	"github.com/pavlegich/metrics-alerting/internal/infra/logger"
+	"go.uber.org/zap"
+)
+
+// rulesKind содержит вид состояния для хранения правил оповещений.
+const rulesKind = "rules"
+
+var (
+	// ErrRuleNotFound возвращается при отсутствии правила с указанным идентификатором.
+	ErrRuleNotFound = errors.New("rule not found")
+	// ErrRuleExists возвращается при создании правила с существующим идентификатором.
+	ErrRuleExists = errors.New("rule already exists")
+	// ErrInvalidRule возвращается при некорректных данных правила.
+	ErrInvalidRule = errors.New("invalid rule")
+	// ErrRuleReadOnly возвращается при изменении правила, загруженного из файла правил.
+	ErrRuleReadOnly = errors.New("rule is managed by rules file")
+)
+
+// labelName содержит шаблон допустимого имени метки.
+var labelName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// ValidateRule проверяет корректность данных правила оповещения.
+func ValidateRule(rule entities.Rule) error {
+	if rule.Name == "" {
+		return fmt.Errorf("%w: name is empty", ErrInvalidRule)
+	}
+	switch rule.Kind {
+	case "", entities.RuleThreshold:
+		if err := validateThreshold(rule); err != nil {
+			return err
+		}
+	case entities.RuleAbsent:
+		if err := validateAbsent(rule); err != nil {
+			return err
+		}
+	case entities.RuleRate, entities.RuleChange, entities.RuleZScore, entities.RuleEWMA:
+		if err := validateThreshold(rule); err != nil {
+			return err
+		}
+		if err := validateWindow(rule); err != nil {
+			return err
+		}
+	case entities.RuleExpr:
+		if err := validateExpr(rule); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("%w: unsupported rule kind %q", ErrInvalidRule, rule.Kind)
+	}
+	if rule.Message != "" {
+		if _, err := template.New("message").Parse(rule.Message); err != nil {
+			return fmt.Errorf("%w: invalid message template: %s", ErrInvalidRule, err)
+		}
+	}
+	if rule.For < 0 {
+		return fmt.Errorf("%w: negative for duration", ErrInvalidRule)
+	}
+	for k := range rule.Labels {
+		if !labelName.MatchString(k) {
+			return fmt.Errorf("%w: invalid label name %q", ErrInvalidRule, k)
+		}
+	}
+	return nil
+}
+
+// validateThreshold проверяет правило сравнения значения метрики с порогом.
+func validateThreshold(rule entities.Rule) error {
+	if rule.MetricType != "gauge" && rule.MetricType != "counter" {
+		return fmt.Errorf("%w: unsupported metric type %q", ErrInvalidRule, rule.MetricType)
+	}
+	if rule.MetricName == "" {
+		return fmt.Errorf("%w: metric name is empty", ErrInvalidRule)
+	}
+	switch rule.Operator {
+	case ">", ">=", "<", "<=", "==", "!=":
+	default:
+		return fmt.Errorf("%w: unsupported operator %q", ErrInvalidRule, rule.Operator)
+	}
+	return nil
+}
+
+// validateAbsent проверяет правило отсутствия обновлений метрики.
+// Тип и имя метрики могут быть пустыми, тогда правило относится ко всем метрикам.
+func validateAbsent(rule entities.Rule) error {
+	if rule.MetricType != "" && rule.MetricType != "gauge" && rule.MetricType != "counter" {
+		return fmt.Errorf("%w: unsupported metric type %q", ErrInvalidRule, rule.MetricType)
+	}
+	if rule.AbsentFor <= 0 {
+		return fmt.Errorf("%w: absent_for must be positive", ErrInvalidRule)
+	}
+	return nil
+}
+
+// validateWindow проверяет параметры правила, оцениваемого по истории значений метрики.
+func validateWindow(rule entities.Rule) error {
+	if rule.Window <= 0 {
+		return fmt.Errorf("%w: window must be positive", ErrInvalidRule)
+	}
+	if rule.Kind == entities.RuleEWMA && (rule.Alpha <= 0 || rule.Alpha > 1) {
+		return fmt.Errorf("%w: alpha must be in (0, 1]", ErrInvalidRule)
+	}
+	return nil
+}
+
+// validateExpr проверяет выражение составного правила. Оператор сравнения
+// с порогом может отсутствовать.
+func validateExpr(rule entities.Rule) error {
+	if _, err := ParseExpr(rule.Expr); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidRule, err)
+	}
+	switch rule.Operator {
+	case "", ">", ">=", "<", "<=", "==", "!=":
+	default:
+		return fmt.Errorf("%w: unsupported operator %q", ErrInvalidRule, rule.Operator)
+	}
+	return nil
+}
+
+// loadRules загружает правила оповещений из хранилища состояния.
+func (e *Engine) loadRules(ctx context.Context) error {
+	if e.state == nil {
+		return nil
+	}
+
+	data, err := e.state.LoadState(ctx, rulesKind)
+	if err != nil {
+		return fmt.Errorf("loadRules: load rules failed %w", err)
+	}
+	if len(data) == 0 {
+		return nil
+	}
+
+	rules := make([]entities.Rule, 0)
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return fmt.Errorf("loadRules: rules unmarshal %w", err)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, r := range rules {
+		e.setRule(r)
+	}
+	return nil
+}
+
+// ListRules возвращает все правила оповещений, упорядоченные по идентификатору.
+func (e *Engine) ListRules(ctx context.Context) []entities.Rule {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	return e.sortedRules()
+}
+
+// GetRule возвращает правило оповещения с указанным идентификатором.
+func (e *Engine) GetRule(ctx context.Context, id string) (entities.Rule, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	rule, ok := e.rules[id]
+	if !ok {
+		return entities.Rule{}, fmt.Errorf("GetRule: %w", ErrRuleNotFound)
+	}
+	return rule, nil
+}
+
+// CreateRule проверяет и сохраняет новое правило оповещения.
+// При отсутствии идентификатора он генерируется автоматически.
+func (e *Engine) CreateRule(ctx context.Context, rule entities.Rule) (entities.Rule, error) {
+	if err := ValidateRule(rule); err != nil {
+		return entities.Rule{}, fmt.Errorf("CreateRule: %w", err)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if rule.ID == "" {
+		rule.ID = newID()
+	}
+	if _, ok := e.rules[rule.ID]; ok {
+		return entities.Rule{}, fmt.Errorf("CreateRule: %w", ErrRuleExists)
+	}
+
+	e.setRule(rule)
+	if err := e.saveRules(ctx); err != nil {
+		e.removeRule(rule.ID)
+		return entities.Rule{}, fmt.Errorf("CreateRule: %w", err)
+	}
+
+	return rule, nil
+}
+
+// UpdateRule проверяет и сохраняет изменённое правило оповещения.
+// Текущие оповещения изменённого правила завершаются.
+func (e *Engine) UpdateRule(ctx context.Context, id string, rule entities.Rule) (entities.Rule, error) {
+	rule.ID = id
+	if err := ValidateRule(rule); err != nil {
+		return entities.Rule{}, fmt.Errorf("UpdateRule: %w", err)
+	}
+
+	e.mu.Lock()
+	old, ok := e.rules[id]
+	if !ok {
+		e.mu.Unlock()
+		return entities.Rule{}, fmt.Errorf("UpdateRule: %w", ErrRuleNotFound)
+	}
+	if _, ok := e.fileRules[id]; ok {
+		e.mu.Unlock()
+		return entities.Rule{}, fmt.Errorf("UpdateRule: %w", ErrRuleReadOnly)
+	}
+
+	e.setRule(rule)
+	if err := e.saveRules(ctx); err != nil {
+		e.setRule(old)
+		e.mu.Unlock()
+		return entities.Rule{}, fmt.Errorf("UpdateRule: %w", err)
+	}
+	resolved := e.resolveRule(id, time.Now())
+	e.mu.Unlock()
+
+	e.notify(ctx, resolved)
+	return rule, nil
+}
+
+// DeleteRule удаляет правило оповещения и завершает его текущие оповещения.
+func (e *Engine) DeleteRule(ctx context.Context, id string) error {
+	e.mu.Lock()
+	old, ok := e.rules[id]
+	if !ok {
+		e.mu.Unlock()
+		return fmt.Errorf("DeleteRule: %w", ErrRuleNotFound)
+	}
+	if _, ok := e.fileRules[id]; ok {
+		e.mu.Unlock()
+		return fmt.Errorf("DeleteRule: %w", ErrRuleReadOnly)
+	}
+
+	e.removeRule(id)
+	if err := e.saveRules(ctx); err != nil {
+		e.setRule(old)
+		e.mu.Unlock()
+		return fmt.Errorf("DeleteRule: %w", err)
+	}
+	resolved := e.resolveRule(id, time.Now())
+	e.mu.Unlock()
+
+	e.notify(ctx, resolved)
+	return nil
+}
+
+// ReplaceFileRules атомарно заменяет правила, загруженные из файла правил.
+// Оповещения неизменённых правил сохраняют своё состояние,
+// оповещения изменённых и удалённых правил завершаются.
+func (e *Engine) ReplaceFileRules(ctx context.Context, rules []entities.Rule) error {
+	e.mu.Lock()
+	resolved, err := e.replaceFileRules(rules)
+	e.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("ReplaceFileRules: %w", err)
+	}
+
+	e.notify(ctx, resolved)
+	return nil
+}
+
+// replaceFileRules заменяет правила, загруженные из файла правил,
+// и возвращает завершённые оповещения. Вызывается под блокировкой.
+func (e *Engine) replaceFileRules(rules []entities.Rule) ([]entities.Alert, error) {
+	next := make(map[string]entities.Rule, len(rules))
+	for _, r := range rules {
+		if _, ok := e.rules[r.ID]; ok {
+			if _, file := e.fileRules[r.ID]; !file {
+				return nil, fmt.Errorf("replaceFileRules: rule %q: %w", r.ID, ErrRuleExists)
+			}
+		}
+		next[r.ID] = r
+	}
+
+	now := time.Now()
+	resolved := make([]entities.Alert, 0)
+	for id := range e.fileRules {
+		if r, ok := next[id]; ok && reflect.DeepEqual(r, e.rules[id]) {
+			continue
+		}
+		resolved = append(resolved, e.resolveRule(id, now)...)
+		e.removeRule(id)
+	}
+
+	e.fileRules = make(map[string]struct{}, len(next))
+	for id, r := range next {
+		e.setRule(r)
+		e.fileRules[id] = struct{}{}
+	}
+	return resolved, nil
+}
+
+// saveRules сохраняет правила оповещений, созданные через API,
+// в хранилище состояния. Вызывается под блокировкой.
+func (e *Engine) saveRules(ctx context.Context) error {
+	if e.state == nil {
+		return nil
+	}
+
+	rules := make([]entities.Rule, 0, len(e.rules))
+	for _, r := range e.sortedRules() {
+		if _, ok := e.fileRules[r.ID]; !ok {
+			rules = append(rules, r)
+		}
+	}
+
+	data, err := json.Marshal(rules)
+	if err != nil {
+		return fmt.Errorf("saveRules: rules marshal %w", err)
+	}
+	if err := e.state.SaveState(ctx, rulesKind, data); err != nil {
+		return fmt.Errorf("saveRules: save rules failed %w", err)
+	}
+	return nil
+}
+
+// sortedRules возвращает правила, упорядоченные по идентификатору.
+// Вызывается под блокировкой.
+func (e *Engine) sortedRules() []entities.Rule {
+	rules := make([]entities.Rule, 0, len(e.rules))
+	for _, r := range e.rules {
+		rules = append(rules, r)
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].ID < rules[j].ID
+	})
+	return rules
+}
+
+// newID генерирует случайный идентификатор.
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// compiledRule содержит разобранные выражение и шаблон сообщения правила.
+type compiledRule struct {
+	expr    *Expr
+	message *template.Template
+}
+
+// setRule сохраняет правило и разбирает его выражение и шаблон сообщения,
+// чтобы не разбирать их при каждой оценке правил. Правила проверяются
+// до сохранения, ошибки разбора записываются в журнал, а правило с ошибкой
+// в выражении не срабатывает. Вызывается под блокировкой.
+func (e *Engine) setRule(rule entities.Rule) {
+	var compiled compiledRule
+	if rule.Kind == entities.RuleExpr {
+		expr, err := ParseExpr(rule.Expr)
+		if err != nil {
+			logger.Log.Error("setRule: parse expression failed",
+				zap.String("rule", rule.ID), zap.Error(err))
+		}
+		compiled.expr = expr
+	}
+	if rule.Message != "" {
+		tmpl, err := template.New("message").Option("missingkey=error").Parse(rule.Message)
+		if err != nil {
+			logger.Log.Error("setRule: parse message template failed",
+				zap.String("rule", rule.ID), zap.Error(err))
+		}
+		compiled.message = tmpl
+	}
+
+	e.rules[rule.ID] = rule
+	e.compiled[rule.ID] = compiled
+}
+
+// removeRule удаляет правило и его разобранные выражение и шаблон.
+// Вызывается под блокировкой.
+func (e *Engine) removeRule(id string) {
+	delete(e.rules, id)
+	delete(e.compiled, id)
+}
@@ -0,0 +1,179 @@
+package alerting
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pavlegich/metrics-alerting/internal/entities"
+	"github.com/pavlegich/metrics-alerting/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateRule(t *testing.T) {
+	valid := entities.Rule{
+		Name:       "HighHeap",
+		MetricType: "gauge",
+		MetricName: "HeapAlloc",
+		Operator:   ">",
+		Threshold:  1e9,
+		Labels:     map[string]string{"severity": "critical"},
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(r *entities.Rule)
+		wantErr bool
+	}{
+		{
+			name:    "valid",
+			modify:  func(r *entities.Rule) {},
+			wantErr: false,
+		},
+		{
+			name:    "empty_name",
+			modify:  func(r *entities.Rule) { r.Name = "" },
+			wantErr: true,
+		},
+		{
+			name:    "unknown_type",
+			modify:  func(r *entities.Rule) { r.MetricType = "histogram" },
+			wantErr: true,
+		},
+		{
+			name:    "empty_metric",
+			modify:  func(r *entities.Rule) { r.MetricName = "" },
+			wantErr: true,
+		},
+		{
+			name:    "unknown_operator",
+			modify:  func(r *entities.Rule) { r.Operator = "=>" },
+			wantErr: true,
+		},
+		{
+			name:    "negative_for",
+			modify:  func(r *entities.Rule) { r.For = entities.Duration(-time.Second) },
+			wantErr: true,
+		},
+		{
+			name:    "unknown_kind",
+			modify:  func(r *entities.Rule) { r.Kind = "delta" },
+			wantErr: true,
+		},
+		{
+			name: "absent_agent",
+			modify: func(r *entities.Rule) {
+				*r = entities.Rule{Name: "AgentDown", Kind: entities.RuleAbsent,
+					AbsentFor: entities.Duration(time.Minute)}
+			},
+			wantErr: false,
+		},
+		{
+			name: "absent_without_period",
+			modify: func(r *entities.Rule) {
+				r.Kind = entities.RuleAbsent
+			},
+			wantErr: true,
+		},
+		{
+			name: "rate",
+			modify: func(r *entities.Rule) {
+				r.Kind = entities.RuleRate
+				r.Window = entities.Duration(5 * time.Minute)
+			},
+			wantErr: false,
+		},
+		{
+			name:    "change_without_window",
+			modify:  func(r *entities.Rule) { r.Kind = entities.RuleChange },
+			wantErr: true,
+		},
+		{
+			name: "ewma_invalid_alpha",
+			modify: func(r *entities.Rule) {
+				r.Kind = entities.RuleEWMA
+				r.Window = entities.Duration(time.Hour)
+				r.Alpha = 1.5
+			},
+			wantErr: true,
+		},
+		{
+			name: "expr",
+			modify: func(r *entities.Rule) {
+				*r = entities.Rule{Name: "Memory", Kind: entities.RuleExpr,
+					Expr: "FreeMemory / TotalMemory", Operator: "<", Threshold: 0.05}
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid_expr",
+			modify: func(r *entities.Rule) {
+				*r = entities.Rule{Name: "Memory", Kind: entities.RuleExpr, Expr: "FreeMemory <"}
+			},
+			wantErr: true,
+		},
+		{
+			name:    "invalid_message",
+			modify:  func(r *entities.Rule) { r.Message = "{{.Value" },
+			wantErr: true,
+		},
+		{
+			name:    "invalid_label",
+			modify:  func(r *entities.Rule) { r.Labels = map[string]string{"1host": "a"} },
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rule := valid
+			tc.modify(&rule)
+			err := ValidateRule(rule)
+			if tc.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidRule)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestEngine_RulesCRUD(t *testing.T) {
+	ctx := context.Background()
+	ms := storage.NewMemStorage(ctx)
+	file := storage.NewFile(filepath.Join(t.TempDir(), "metrics.json"), 0)
+	e := NewEngine(ctx, ms, file)
+
+	rule := entities.Rule{
+		ID:         "heap",
+		Name:       "HighHeap",
+		MetricType: "gauge",
+		MetricName: "HeapAlloc",
+		Operator:   ">",
+		Threshold:  100,
+	}
+	_, err := e.CreateRule(ctx, rule)
+	require.NoError(t, err)
+
+	_, err = e.CreateRule(ctx, rule)
+	assert.ErrorIs(t, err, ErrRuleExists)
+
+	rule.Threshold = 200
+	_, err = e.UpdateRule(ctx, "heap", rule)
+	require.NoError(t, err)
+
+	_, err = e.UpdateRule(ctx, "unknown", rule)
+	assert.ErrorIs(t, err, ErrRuleNotFound)
+
+	// правила восстанавливаются из хранилища состояния
+	restored := NewEngine(ctx, ms, file)
+	require.NoError(t, restored.Load(ctx))
+	got, err := restored.GetRule(ctx, "heap")
+	require.NoError(t, err)
+	assert.Equal(t, rule, got)
+
+	require.NoError(t, e.DeleteRule(ctx, "heap"))
+	assert.ErrorIs(t, e.DeleteRule(ctx, "heap"), ErrRuleNotFound)
+	assert.Empty(t, e.ListRules(ctx))
+}
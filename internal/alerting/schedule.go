@@ -0,0 +1,181 @@
+package alerting
+
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxWindow содержит максимальную длительность периода обслуживания.
+const maxWindow = 7 * 24 * time.Hour
+
+// Schedule содержит расписание в формате cron из пяти полей:
+// минуты, часы, дни месяца, месяцы и дни недели. Поля поддерживают
+// значения *, числа, диапазоны a-b, списки через запятую и шаг /n.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool
+}
+
+// ParseSchedule разбирает расписание в формате cron.
+func ParseSchedule(spec string) (Schedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return Schedule{}, fmt.Errorf("ParseSchedule: expected 5 fields, got %d", len(fields))
+	}
+
+	var s Schedule
+	var err error
+	bounds := []struct {
+		field    *uint64
+		min, max int
+	}{
+		{&s.minute, 0, 59},
+		{&s.hour, 0, 23},
+		{&s.dom, 1, 31},
+		{&s.month, 1, 12},
+		{&s.dow, 0, 7},
+	}
+	for i, b := range bounds {
+		*b.field, err = parseField(fields[i], b.min, b.max)
+		if err != nil {
+			return Schedule{}, fmt.Errorf("ParseSchedule: field %d %q: %w", i+1, fields[i], err)
+		}
+	}
+
+	// воскресенье может быть указано как 0 или 7
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	s.domAny = fields[2] == "*"
+	s.dowAny = fields[4] == "*"
+
+	return s, nil
+}
+
+// Match проверяет, соответствует ли минута указанного времени расписанию.
+func (s Schedule) Match(t time.Time) bool {
+	return s.minute&(1<<t.Minute()) != 0 && s.hour&(1<<t.Hour()) != 0 && s.matchDay(t)
+}
+
+// matchDay проверяет, соответствуют ли месяц и день указанного времени расписанию.
+func (s Schedule) matchDay(t time.Time) bool {
+	if s.month&(1<<int(t.Month())) == 0 {
+		return false
+	}
+
+	dom := s.dom&(1<<t.Day()) != 0
+	dow := s.dow&(1<<int(t.Weekday())) != 0
+	switch {
+	case s.domAny && s.dowAny:
+		return true
+	case s.domAny:
+		return dow
+	case s.dowAny:
+		return dom
+	default:
+		// как в cron, при заданных днях месяца и недели достаточно одного совпадения
+		return dom || dow
+	}
+}
+
+// Within проверяет, попадает ли время в период указанной длительности,
+// начинающийся в одну из минут расписания.
+func (s Schedule) Within(t time.Time, d time.Duration) bool {
+	start, ok := s.prev(t, d)
+	return ok && t.Sub(start) < d
+}
+
+// prev возвращает последнюю минуту расписания не позже указанного времени.
+// Поиск ограничен днями, которые могут начинать период длительности d:
+// в каждом дне последняя подходящая минута выбирается по битовым маскам
+// часов и минут без перебора минут.
+func (s Schedule) prev(t time.Time, d time.Duration) (time.Time, bool) {
+	year, month, day := t.Date()
+	for i := 0; ; i++ {
+		date := time.Date(year, month, day-i, 0, 0, 0, 0, t.Location())
+		if i > 0 && t.Sub(date.Add(24*time.Hour)) >= d {
+			return time.Time{}, false
+		}
+		if !s.matchDay(date) {
+			continue
+		}
+
+		maxHour := 23
+		if i == 0 {
+			maxHour = t.Hour()
+		}
+		for hour, ok := highestBit(s.hour, maxHour); ok; hour, ok = highestBit(s.hour, hour-1) {
+			maxMinute := 59
+			if i == 0 && hour == t.Hour() {
+				maxMinute = t.Minute()
+			}
+			if minute, ok := highestBit(s.minute, maxMinute); ok {
+				return time.Date(year, month, day-i, hour, minute, 0, 0, t.Location()), true
+			}
+		}
+	}
+}
+
+// highestBit возвращает номер старшего установленного бита маски,
+// не превышающий max.
+func highestBit(mask uint64, max int) (int, bool) {
+	if max < 0 {
+		return 0, false
+	}
+	if max < 63 {
+		mask &= 1<<(max+1) - 1
+	}
+	if mask == 0 {
+		return 0, false
+	}
+	return bits.Len64(mask) - 1, true
+}
+
+// parseField разбирает поле расписания в набор разрешённых значений.
+func parseField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			v, err := strconv.Atoi(part[i+1:])
+			if err != nil || v <= 0 {
+				return 0, fmt.Errorf("parseField: invalid step %q", part[i+1:])
+			}
+			step = v
+			part = part[:i]
+		}
+
+		lo, hi := min, max
+		switch i := strings.Index(part, "-"); {
+		case part == "*":
+		case i >= 0:
+			var err error
+			if lo, err = strconv.Atoi(part[:i]); err != nil {
+				return 0, fmt.Errorf("parseField: invalid value %q", part[:i])
+			}
+			if hi, err = strconv.Atoi(part[i+1:]); err != nil {
+				return 0, fmt.Errorf("parseField: invalid value %q", part[i+1:])
+			}
+		default:
+			v, err := strconv.Atoi(part)
+			if err != nil {
+				return 0, fmt.Errorf("parseField: invalid value %q", part)
+			}
+			lo, hi = v, v
+			if step > 1 {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("parseField: value out of range %d-%d", min, max)
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
@@ -0,0 +1,85 @@
+package alerting
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchedule_Match(t *testing.T) {
+	// 2024-01-06 — суббота
+	sat := time.Date(2024, 1, 6, 2, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		spec string
+		time time.Time
+		want bool
+	}{
+		{name: "every_minute", spec: "* * * * *", time: sat, want: true},
+		{name: "exact", spec: "30 2 * * *", time: sat, want: true},
+		{name: "other_hour", spec: "30 3 * * *", time: sat, want: false},
+		{name: "step", spec: "*/15 * * * *", time: sat, want: true},
+		{name: "step_miss", spec: "*/20 * * * *", time: sat, want: false},
+		{name: "range_list", spec: "0-10,30 1-3 * * *", time: sat, want: true},
+		{name: "weekend", spec: "* * * * 6,0", time: sat, want: true},
+		{name: "weekdays", spec: "* * * * 1-5", time: sat, want: false},
+		{name: "sunday_as_7", spec: "* * * * 7", time: sat.AddDate(0, 0, 1), want: true},
+		{name: "dom_or_dow", spec: "* * 1 * 1", time: sat, want: false},
+		{name: "dom_or_dow_match", spec: "* * 6 * 1", time: sat, want: true},
+		{name: "month", spec: "* * * 2 *", time: sat, want: false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := ParseSchedule(tc.spec)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, s.Match(tc.time))
+		})
+	}
+}
+
+func TestParseSchedule_Errors(t *testing.T) {
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "5-1 * * * *", "*/0 * * * *", "a * * * *"} {
+		_, err := ParseSchedule(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestSchedule_Within(t *testing.T) {
+	// ежедневное окно обслуживания в 02:00 длительностью 1 час
+	s, err := ParseSchedule("0 2 * * *")
+	require.NoError(t, err)
+
+	day := time.Date(2024, 1, 6, 0, 0, 0, 0, time.UTC)
+	assert.False(t, s.Within(day.Add(time.Hour+59*time.Minute), time.Hour))
+	assert.True(t, s.Within(day.Add(2*time.Hour), time.Hour))
+	assert.True(t, s.Within(day.Add(2*time.Hour+59*time.Minute+59*time.Second), time.Hour))
+	assert.False(t, s.Within(day.Add(3*time.Hour), time.Hour))
+}
+
+func TestSchedule_WithinMatchesScan(t *testing.T) {
+	// поиск начала периода совпадает с перебором минут в обратном порядке
+	scan := func(s Schedule, t time.Time, d time.Duration) bool {
+		for m := t.Truncate(time.Minute); t.Sub(m) < d; m = m.Add(-time.Minute) {
+			if s.Match(m) {
+				return true
+			}
+		}
+		return false
+	}
+
+	specs := []string{"0 2 * * *", "*/15 9-17 * * 1-5", "30 23 * * 0", "0 0 1 * *", "0 12 29 2 *", "45 * 6 * 1"}
+	durations := []time.Duration{time.Minute, 90 * time.Minute, 25 * time.Hour, maxWindow}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, spec := range specs {
+		s, err := ParseSchedule(spec)
+		require.NoError(t, err)
+		for _, d := range durations {
+			for now := start; now.Before(start.AddDate(0, 0, 10)); now = now.Add(37*time.Minute + 13*time.Second) {
+				require.Equal(t, scan(s, now, d), s.Within(now, d), "%s %s %s", spec, d, now)
+			}
+		}
+	}
+}
@@ -0,0 +1,286 @@
+package alerting
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/pavlegich/metrics-alerting/internal/entities"
+	"github.com/pavlegich/metrics-alerting/internal/infra/logger"
+	"go.uber.org/zap"
+)
+
+// silencesKind содержит вид состояния для хранения тишин.
+const silencesKind = "silences"
+
+var (
+	// ErrSilenceNotFound возвращается при отсутствии тишины с указанным идентификатором.
+	ErrSilenceNotFound = errors.New("silence not found")
+	// ErrInvalidSilence возвращается при некорректных данных тишины.
+	ErrInvalidSilence = errors.New("invalid silence")
+)
+
+// ValidateSilence проверяет корректность данных тишины.
+func ValidateSilence(s entities.Silence) error {
+	if len(s.Matchers) == 0 {
+		return fmt.Errorf("%w: matchers are empty", ErrInvalidSilence)
+	}
+	for _, m := range s.Matchers {
+		if !labelName.MatchString(m.Name) {
+			return fmt.Errorf("%w: invalid matcher label name %q", ErrInvalidSilence, m.Name)
+		}
+		if m.IsRegex {
+			if _, err := regexp.Compile(m.Value); err != nil {
+				return fmt.Errorf("%w: invalid matcher regex %q", ErrInvalidSilence, m.Value)
+			}
+		}
+	}
+	if s.CreatedBy == "" {
+		return fmt.Errorf("%w: creator is empty", ErrInvalidSilence)
+	}
+
+	if s.Schedule == "" {
+		if s.EndsAt.IsZero() || !s.EndsAt.After(s.StartsAt) {
+			return fmt.Errorf("%w: end time must be after start time", ErrInvalidSilence)
+		}
+		return nil
+	}
+
+	if _, err := ParseSchedule(s.Schedule); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidSilence, err)
+	}
+	if s.Duration <= 0 || time.Duration(s.Duration) > maxWindow {
+		return fmt.Errorf("%w: window duration must be in range (0, %s]", ErrInvalidSilence, maxWindow)
+	}
+	if !s.EndsAt.IsZero() && !s.EndsAt.After(s.StartsAt) {
+		return fmt.Errorf("%w: end time must be after start time", ErrInvalidSilence)
+	}
+	return nil
+}
+
+// compiledSilence содержит тишину с разобранными условиями на метки и расписанием.
+type compiledSilence struct {
+	silence  entities.Silence
+	matchers entities.LabelMatchers
+	schedule *Schedule
+}
+
+// compileSilence разбирает условия на метки и расписание тишины.
+func compileSilence(s entities.Silence) (compiledSilence, error) {
+	matchers, err := entities.CompileMatchers(s.Matchers)
+	if err != nil {
+		return compiledSilence{}, fmt.Errorf("compileSilence: %w", err)
+	}
+	c := compiledSilence{silence: s, matchers: matchers}
+	if s.Schedule != "" {
+		sched, err := ParseSchedule(s.Schedule)
+		if err != nil {
+			return compiledSilence{}, fmt.Errorf("compileSilence: %w", err)
+		}
+		c.schedule = &sched
+	}
+	return c, nil
+}
+
+// active проверяет, действует ли тишина в указанный момент времени.
+func (c compiledSilence) active(now time.Time) bool {
+	s := c.silence
+	if now.Before(s.StartsAt) || (!s.EndsAt.IsZero() && !now.Before(s.EndsAt)) {
+		return false
+	}
+	if c.schedule == nil {
+		return true
+	}
+	return c.schedule.Within(now, time.Duration(s.Duration))
+}
+
+// SilenceActive проверяет, действует ли тишина в указанный момент времени.
+func SilenceActive(s entities.Silence, now time.Time) bool {
+	c, err := compileSilence(s)
+	if err != nil {
+		return false
+	}
+	return c.active(now)
+}
+
+// SilenceMatches проверяет, подходят ли метки оповещения под условия тишины.
+func SilenceMatches(s entities.Silence, labels map[string]string) bool {
+	return entities.MatchLabels(s.Matchers, labels)
+}
+
+// loadSilences загружает тишины из хранилища состояния.
+func (e *Engine) loadSilences(ctx context.Context) error {
+	if e.state == nil {
+		return nil
+	}
+
+	data, err := e.state.LoadState(ctx, silencesKind)
+	if err != nil {
+		return fmt.Errorf("loadSilences: load silences failed %w", err)
+	}
+	if len(data) == 0 {
+		return nil
+	}
+
+	silences := make([]entities.Silence, 0)
+	if err := json.Unmarshal(data, &silences); err != nil {
+		return fmt.Errorf("loadSilences: silences unmarshal %w", err)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, s := range silences {
+		e.silences[s.ID] = s
+	}
+	e.indexSilences()
+	return nil
+}
+
+// ListSilences возвращает все тишины, упорядоченные по времени начала.
+func (e *Engine) ListSilences(ctx context.Context) []entities.Silence {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	return e.sortedSilences()
+}
+
+// GetSilence возвращает тишину с указанным идентификатором.
+func (e *Engine) GetSilence(ctx context.Context, id string) (entities.Silence, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	s, ok := e.silences[id]
+	if !ok {
+		return entities.Silence{}, fmt.Errorf("GetSilence: %w", ErrSilenceNotFound)
+	}
+	return s, nil
+}
+
+// CreateSilence проверяет и сохраняет новую тишину.
+// Без времени начала тишина действует сразу.
+func (e *Engine) CreateSilence(ctx context.Context, s entities.Silence) (entities.Silence, error) {
+	if s.StartsAt.IsZero() {
+		s.StartsAt = time.Now()
+	}
+	if err := ValidateSilence(s); err != nil {
+		return entities.Silence{}, fmt.Errorf("CreateSilence: %w", err)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	s.ID = newID()
+	e.silences[s.ID] = s
+	if err := e.saveSilences(ctx); err != nil {
+		delete(e.silences, s.ID)
+		return entities.Silence{}, fmt.Errorf("CreateSilence: %w", err)
+	}
+	e.indexSilences()
+
+	return s, nil
+}
+
+// DeleteSilence удаляет тишину до окончания её действия.
+func (e *Engine) DeleteSilence(ctx context.Context, id string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	old, ok := e.silences[id]
+	if !ok {
+		return fmt.Errorf("DeleteSilence: %w", ErrSilenceNotFound)
+	}
+
+	delete(e.silences, id)
+	if err := e.saveSilences(ctx); err != nil {
+		e.silences[id] = old
+		return fmt.Errorf("DeleteSilence: %w", err)
+	}
+	e.indexSilences()
+	return nil
+}
+
+// expireSilences удаляет тишины с истёкшим временем действия.
+// Вызывается под блокировкой.
+func (e *Engine) expireSilences(ctx context.Context, now time.Time) {
+	expired := false
+	for id, s := range e.silences {
+		if !s.EndsAt.IsZero() && !now.Before(s.EndsAt) {
+			delete(e.silences, id)
+			expired = true
+		}
+	}
+	if !expired {
+		return
+	}
+	e.indexSilences()
+
+	if err := e.saveSilences(ctx); err != nil {
+		logger.Log.Error("expireSilences: save silences failed", zap.Error(err))
+	}
+}
+
+// silencedBy возвращает идентификаторы действующих тишин,
+// подходящих под метки оповещения. Вызывается под блокировкой.
+func (e *Engine) silencedBy(labels map[string]string, now time.Time) []string {
+	var ids []string
+	for _, c := range e.silenceIndex {
+		if c.active(now) && c.matchers.Match(labels) {
+			ids = append(ids, c.silence.ID)
+		}
+	}
+	return ids
+}
+
+// indexSilences разбирает условия и расписания тишин после их изменения,
+// чтобы не разбирать их при каждой оценке правил. Тишины с некорректными
+// условиями пропускаются. Вызывается под блокировкой.
+func (e *Engine) indexSilences() {
+	index := make([]compiledSilence, 0, len(e.silences))
+	for _, s := range e.sortedSilences() {
+		c, err := compileSilence(s)
+		if err != nil {
+			logger.Log.Error("indexSilences: invalid silence", zap.String("id", s.ID), zap.Error(err))
+			continue
+		}
+		index = append(index, c)
+	}
+	e.silenceIndex = index
+}
+
+// saveSilences сохраняет тишины в хранилище состояния.
+// Вызывается под блокировкой.
+func (e *Engine) saveSilences(ctx context.Context) error {
+	if e.state == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(e.sortedSilences())
+	if err != nil {
+		return fmt.Errorf("saveSilences: silences marshal %w", err)
+	}
+	if err := e.state.SaveState(ctx, silencesKind, data); err != nil {
+		return fmt.Errorf("saveSilences: save silences failed %w", err)
+	}
+	return nil
+}
+
+// sortedSilences возвращает тишины, упорядоченные по времени начала.
+// Вызывается под блокировкой.
+func (e *Engine) sortedSilences() []entities.Silence {
+	silences := make([]entities.Silence, 0, len(e.silences))
+	for _, s := range e.silences {
+		silences = append(silences, s)
+	}
+	sort.Slice(silences, func(i, j int) bool {
+		if silences[i].StartsAt.Equal(silences[j].StartsAt) {
+			return silences[i].ID < silences[j].ID
+		}
+		return silences[i].StartsAt.Before(silences[j].StartsAt)
+	})
+	return silences
+}
@@ -0,0 +1,122 @@
+package alerting
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pavlegich/metrics-alerting/internal/entities"
+	"github.com/pavlegich/metrics-alerting/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateSilence(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	valid := entities.Silence{
+		Matchers:  []entities.Matcher{{Name: "metric", Value: "CPU.*", IsRegex: true}},
+		StartsAt:  start,
+		EndsAt:    start.Add(time.Hour),
+		CreatedBy: "ops",
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(s *entities.Silence)
+		wantErr bool
+	}{
+		{name: "valid", modify: func(s *entities.Silence) {}, wantErr: false},
+		{name: "no_matchers", modify: func(s *entities.Silence) { s.Matchers = nil }, wantErr: true},
+		{name: "bad_regex", modify: func(s *entities.Silence) { s.Matchers[0].Value = "(" }, wantErr: true},
+		{name: "no_creator", modify: func(s *entities.Silence) { s.CreatedBy = "" }, wantErr: true},
+		{name: "no_end", modify: func(s *entities.Silence) { s.EndsAt = time.Time{} }, wantErr: true},
+		{name: "end_before_start", modify: func(s *entities.Silence) { s.EndsAt = start.Add(-time.Minute) }, wantErr: true},
+		{
+			name: "window",
+			modify: func(s *entities.Silence) {
+				s.EndsAt = time.Time{}
+				s.Schedule = "0 2 * * *"
+				s.Duration = entities.Duration(time.Hour)
+			},
+			wantErr: false,
+		},
+		{
+			name: "window_without_duration",
+			modify: func(s *entities.Silence) {
+				s.Schedule = "0 2 * * *"
+			},
+			wantErr: true,
+		},
+		{
+			name: "window_bad_schedule",
+			modify: func(s *entities.Silence) {
+				s.Schedule = "0 25 * * *"
+				s.Duration = entities.Duration(time.Hour)
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s := valid
+			s.Matchers = append([]entities.Matcher(nil), valid.Matchers...)
+			tc.modify(&s)
+			err := ValidateSilence(s)
+			if tc.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidSilence)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestEngine_Silences(t *testing.T) {
+	ctx := context.Background()
+	ms := storage.NewMemStorage(ctx)
+	file := storage.NewFile(filepath.Join(t.TempDir(), "metrics.json"), 0)
+	e := NewEngine(ctx, ms, file)
+
+	listened, notified := 0, 0
+	e.AddListener(func(ctx context.Context, alert entities.Alert) { listened++ })
+	e.AddNotifier(func(ctx context.Context, alert entities.Alert) { notified++ })
+
+	_, err := e.CreateRule(ctx, entities.Rule{ID: "cpu", Name: "HighCPU", MetricType: "gauge",
+		MetricName: "CPUutilization1", Operator: ">", Threshold: 90})
+	require.NoError(t, err)
+
+	now := time.Now()
+	silence, err := e.CreateSilence(ctx, entities.Silence{
+		Matchers:  []entities.Matcher{{Name: "metric", Value: "CPUutilization1"}},
+		StartsAt:  now.Add(-time.Minute),
+		EndsAt:    now.Add(time.Hour),
+		CreatedBy: "ops",
+		Comment:   "deploy",
+	})
+	require.NoError(t, err)
+
+	// тишина сохраняется в хранилище состояния
+	restored := NewEngine(ctx, ms, file)
+	require.NoError(t, restored.Load(ctx))
+	assert.Len(t, restored.ListSilences(ctx), 1)
+
+	// оповещение оценивается, но уведомление подавляется
+	require.NoError(t, ms.Put(ctx, "gauge", "CPUutilization1", "95"))
+	e.Evaluate(ctx, now)
+	alerts := e.Alerts(ctx)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, []string{silence.ID}, alerts[0].SilencedBy)
+	assert.Equal(t, 1, listened)
+	assert.Equal(t, 0, notified)
+
+	// по окончании действия тишина удаляется автоматически
+	require.NoError(t, ms.Put(ctx, "gauge", "CPUutilization1", "10"))
+	e.Evaluate(ctx, now.Add(2*time.Hour))
+	assert.Empty(t, e.ListSilences(ctx))
+	assert.Equal(t, 2, listened)
+	assert.Equal(t, 1, notified)
+
+	_, err = e.GetSilence(ctx, silence.ID)
+	assert.ErrorIs(t, err, ErrSilenceNotFound)
+}
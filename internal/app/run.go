@@ -16,10 +16,13 @@ import (
 	"github.com/pavlegich/metrics-alerting/internal/interfaces"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/pavlegich/metrics-alerting/internal/alerting"
+	"github.com/pavlegich/metrics-alerting/internal/broadcast"
 	"github.com/pavlegich/metrics-alerting/internal/entities"
 	"github.com/pavlegich/metrics-alerting/internal/infra/config"
 	"github.com/pavlegich/metrics-alerting/internal/infra/database"
 	"github.com/pavlegich/metrics-alerting/internal/infra/logger"
+	"github.com/pavlegich/metrics-alerting/internal/notify"
 	"github.com/pavlegich/metrics-alerting/internal/server"
 	"github.com/pavlegich/metrics-alerting/internal/server/grpcserver"
 	"github.com/pavlegich/metrics-alerting/internal/server/httpserver"
@@ -28,6 +31,19 @@ import (
 	_ "google.golang.org/grpc/encoding/gzip"
 )
 
+// rollupInterval содержит интервал сворачивания истории метрик.
+const rollupInterval = 10 * time.Second
+
+// rulesWatchInterval содержит интервал проверки изменений файла правил.
+const rulesWatchInterval = 5 * time.Second
+
+// alertLogSize содержит количество изменений состояния оповещений,
+// хранимых в памяти при отсутствии базы данных.
+const alertLogSize = 10000
+
+// dispatchInterval содержит интервал проверки готовности уведомлений к отправке.
+const dispatchInterval = time.Second
+
 // Run инициализирует основные компоненты и запускает сервер.
 func Run(idleConnsClosed chan struct{}) error {
 	ctx, cancelRun := context.WithCancel(context.Background())
@@ -47,12 +63,7 @@ func Run(idleConnsClosed chan struct{}) error {
 	}
 
 	// Интервалы
-	var storeInterval time.Duration
-	if cfg.StoreInterval == 0 {
-		storeInterval = time.Duration(1) * time.Second
-	} else {
-		storeInterval = time.Duration(cfg.StoreInterval) * time.Second
-	}
+	storeInterval := time.Duration(cfg.StoreInterval) * time.Second
 
 	// Хранилище
 	memStorage := storage.NewMemStorage(ctx)
@@ -68,10 +79,24 @@ func Run(idleConnsClosed chan struct{}) error {
 	} else {
 		db = nil
 	}
-	database := storage.NewDatabase(db)
+	var dbStorage interfaces.Storage = storage.NewDatabase(db)
+	if database.IsSQLite(cfg.Database) {
+		dbStorage = storage.NewSQLite(db)
+	}
+
+	// Встроенное key-value хранилище используется вместо базы данных,
+	// без него сервер не запускается, как и с некорректным файлом правил
+	var kv *storage.KV
+	if cfg.Database == "" && cfg.KVPath != "" {
+		kv, err = storage.NewKV(ctx, cfg.KVPath)
+		if err != nil {
+			return fmt.Errorf("Run: kv storage open failed %w", err)
+		}
+		dbStorage = kv
+	}
 
 	// Файл
-	file := storage.NewFile(cfg.StoragePath)
+	file := storage.NewFile(cfg.StoragePath, cfg.StoreBackups)
 
 	// Ключ для хеширования
 	if cfg.Key != "" {
@@ -81,8 +106,8 @@ func Run(idleConnsClosed chan struct{}) error {
 	// Получение метрик из базы данных или файла
 	if cfg.Restore {
 		switch {
-		case cfg.Database != "":
-			if err := database.Load(ctx, memStorage); err != nil {
+		case cfg.Database != "" || kv != nil:
+			if err := dbStorage.Load(ctx, memStorage); err != nil {
 				logger.Log.Error("Run: restore storage from database failed", zap.Error(err))
 			}
 		case cfg.StoragePath != "":
@@ -92,28 +117,169 @@ func Run(idleConnsClosed chan struct{}) error {
 		}
 	}
 
+	// Журнал предзаписи
+	var wal *storage.WAL
+	if cfg.WALPath != "" && server.IsSyncStore(cfg) {
+		logger.Log.Info("Run: wal is not used in synchronous store mode")
+	} else if cfg.WALPath != "" {
+		wal, err = storage.NewWAL(ctx, cfg.WALPath, time.Duration(cfg.WALSync)*time.Millisecond)
+		if err != nil {
+			logger.Log.Error("Run: wal open failed", zap.Error(err))
+		} else {
+			if cfg.Restore {
+				if err := wal.Replay(ctx, memStorage); err != nil {
+					logger.Log.Error("Run: replay wal failed", zap.Error(err))
+				}
+			}
+			memStorage.SetWAL(wal)
+		}
+	}
+
+	// История метрик
+	history := storage.NewHistory(ctx, storage.Retention{
+		Raw:    time.Duration(cfg.HistoryRaw),
+		Minute: time.Duration(cfg.HistoryMinute),
+		Hour:   time.Duration(cfg.HistoryHour),
+	})
+	var rollups interfaces.RollupStorage
+	if d, ok := dbStorage.(*storage.Database); ok && db != nil {
+		rollups = d
+		now := time.Now()
+		for res, retention := range map[entities.Resolution]time.Duration{
+			entities.ResolutionMinute: time.Duration(cfg.HistoryMinute),
+			entities.ResolutionHour:   time.Duration(cfg.HistoryHour),
+		} {
+			loaded, err := d.LoadRollups(ctx, res, now.Add(-retention))
+			if err != nil {
+				logger.Log.Error("Run: restore rollups from database failed", zap.Error(err))
+				continue
+			}
+			history.Restore(ctx, loaded)
+		}
+	}
+	memStorage.AddListener(history.Record)
+
+	// Обновления метрик рассылаются подписчикам потоков событий
+	broker := broadcast.NewBroker(ctx)
+	memStorage.AddListener(broker.PublishUpdate)
+
+	wg.Add(1)
+	go func() {
+		server.RollupRoutine(ctx, history, rollups, rollupInterval)
+		wg.Done()
+	}()
+
+	// Оповещения, правила хранятся вместе с метриками
+	var state interfaces.StateStorage
+	switch {
+	case cfg.Database != "" || kv != nil:
+		state, _ = dbStorage.(interfaces.StateStorage)
+	case cfg.StoragePath != "":
+		state = file
+	}
+	engine := alerting.NewEngine(ctx, memStorage, state)
+
+	// История оповещений хранится в базе данных или ограниченно в памяти
+	var transitions interfaces.TransitionStorage = storage.NewAlertLog(ctx, alertLogSize)
+	if ts, ok := dbStorage.(interfaces.TransitionStorage); ok && db != nil {
+		transitions = ts
+	}
+	engine.SetTransitions(transitions)
+	engine.SetHistory(history)
+	if err := engine.Load(ctx); err != nil {
+		logger.Log.Error("Run: restore alert rules failed", zap.Error(err))
+	}
+
+	engine.AddListener(broker.PublishAlert)
+
+	// Уведомления настраиваются в файле правил
+	dispatcher := notify.NewDispatcher(ctx)
+	engine.AddNotifier(dispatcher.Add)
+	engine.AddFileListener(func(ctx context.Context, file alerting.RulesFile) {
+		if err := dispatcher.Configure(ctx, file.ExternalURL, file.Route, file.Receivers); err != nil {
+			logger.Log.Error("Run: configure notifications failed", zap.Error(err))
+		}
+	})
+
+	// Эскалации восстанавливаются из хранилища состояния, останавливаются
+	// подтверждением или завершением оповещения, в том числе подавленного,
+	// и приостанавливаются на время подавления
+	escalator := notify.NewEscalator(ctx, state)
+	if err := escalator.Load(ctx); err != nil {
+		logger.Log.Error("Run: restore escalations failed", zap.Error(err))
+	}
+	engine.AddListener(escalator.Add)
+	engine.AddSuppressListener(escalator.Add)
+	engine.AddEvaluateListener(escalator.Sync)
+	engine.AddAckListener(escalator.Ack)
+	engine.AddFileListener(func(ctx context.Context, file alerting.RulesFile) {
+		if err := escalator.Configure(ctx, file.ExternalURL, file.Escalations, file.Receivers); err != nil {
+			logger.Log.Error("Run: configure escalations failed", zap.Error(err))
+		}
+	})
+
+	wg.Add(2)
+	go func() {
+		dispatcher.Run(ctx, dispatchInterval)
+		wg.Done()
+	}()
+	go func() {
+		escalator.Run(ctx, dispatchInterval)
+		wg.Done()
+	}()
+
+	// Правила из файла проверяются при запуске и перечитываются
+	// при изменении файла и по сигналу SIGHUP
+	if cfg.RulesFile != "" {
+		if err := engine.LoadRulesFile(ctx, cfg.RulesFile); err != nil {
+			return fmt.Errorf("Run: load rules file failed %w", err)
+		}
+
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		wg.Add(1)
+		go func() {
+			engine.WatchRulesFile(ctx, cfg.RulesFile, rulesWatchInterval, hup)
+			signal.Stop(hup)
+			wg.Done()
+		}()
+	}
+
+	if cfg.AlertInterval > 0 {
+		wg.Add(1)
+		go func() {
+			engine.Run(ctx, time.Duration(cfg.AlertInterval))
+			wg.Done()
+		}()
+	}
+
 	// Сервер
 	var srv interfaces.Server = nil
 	if cfg.Grpc != "" {
-		srv = grpcserver.NewServer(ctx, memStorage, database, file, cfg)
+		srv = grpcserver.NewServer(ctx, memStorage, dbStorage, file, engine, broker, cfg)
 	} else if cfg.Address != "" {
-		srv = httpserver.NewServer(ctx, memStorage, database, file, cfg)
+		failures := []interfaces.FailureReporter{dispatcher, escalator}
+		srv, err = httpserver.NewServer(ctx, memStorage, dbStorage, file, history, engine, broker, failures, cfg)
+		if err != nil {
+			return fmt.Errorf("Run: create http server failed %w", err)
+		}
 	}
 
 	if srv == nil {
 		return fmt.Errorf("Run: server is nil")
 	}
 
-	// Хранение данных в базе данных или файле
-	if cfg.Database != "" || cfg.StoragePath != "" {
+	// Хранение данных в базе данных или файле,
+	// в синхронном режиме метрики сохраняются обработчиками
+	if (cfg.Database != "" || kv != nil || cfg.StoragePath != "") && !server.IsSyncStore(cfg) {
 		saveFunc := server.SaveToFileRoutine
-		if cfg.Database != "" {
+		if cfg.Database != "" || kv != nil {
 			saveFunc = server.SaveToDBRoutine
 		}
 
 		wg.Add(1)
 		go func() {
-			saveFunc(ctx, memStorage, database, file, storeInterval)
+			saveFunc(ctx, memStorage, dbStorage, file, wal, storeInterval)
 			wg.Done()
 		}()
 	}
@@ -140,6 +306,8 @@ func Run(idleConnsClosed chan struct{}) error {
 		ctxShutDown, cancelShutDown := context.WithTimeout(ctx, 5*time.Second)
 		defer cancelShutDown()
 
+		// потоки событий завершаются до остановки сервера
+		broker.Close()
 		if err := srv.Shutdown(ctxShutDown); err != nil {
 			logger.Log.Error("server shutdown failed",
 				zap.Error(err))
@@ -155,6 +323,18 @@ func Run(idleConnsClosed chan struct{}) error {
 		logger.Log.Info("shutting down gracefully...",
 			zap.String("signal", sig.String()))
 		wg.Wait()
+		if kv != nil {
+			if err := kv.Close(); err != nil {
+				logger.Log.Error("kv storage close failed",
+					zap.Error(err))
+			}
+		}
+		if wal != nil {
+			if err := wal.Close(); err != nil {
+				logger.Log.Error("wal close failed",
+					zap.Error(err))
+			}
+		}
 		close(idleConnsClosed)
 	}()
 
@@ -0,0 +1,154 @@
+package broadcast
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pavlegich/metrics-alerting/internal/entities"
+	"github.com/pavlegich/metrics-alerting/internal/infra/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultBuffer содержит размер буфера событий подписчика по умолчанию.
+	DefaultBuffer = 64
+	// MaxBuffer содержит наибольший размер буфера событий подписчика.
+	MaxBuffer = 4096
+)
+
+// Broker рассылает события подписчикам. Рассылка не блокируется:
+// подписчик, буфер которого заполнен, отключается, а его канал закрывается,
+// чтобы медленный клиент не задерживал приём метрик.
+type Broker struct {
+	mu      *sync.Mutex
+	subs    map[uint64]*subscriber
+	next    uint64
+	closed  bool
+	dropped uint64
+}
+
+// subscriber содержит канал и условия отбора событий подписчика.
+// Условия на метки разбираются один раз при подписке.
+type subscriber struct {
+	filter   entities.EventFilter
+	matchers entities.LabelMatchers
+	invalid  bool // условия на метки некорректны, события не подходят
+	ch       chan entities.Event
+}
+
+// match проверяет, подходит ли событие под условия подписчика.
+func (s *subscriber) match(event entities.Event) bool {
+	if s.invalid || !s.filter.Match(event) {
+		return false
+	}
+	return len(s.matchers) == 0 || s.matchers.Match(event.Labels())
+}
+
+// NewBroker создаёт новый рассыльщик событий.
+func NewBroker(ctx context.Context) *Broker {
+	return &Broker{
+		mu:   &sync.Mutex{},
+		subs: make(map[uint64]*subscriber),
+	}
+}
+
+// Subscribe подписывает на события, подходящие под фильтр, и возвращает канал
+// событий и функцию отмены подписки. Канал закрывается при отмене подписки,
+// отключении медленного подписчика или остановке рассыльщика.
+// При неположительном размере буфера используется DefaultBuffer,
+// размер буфера больше MaxBuffer уменьшается до MaxBuffer.
+// Если условия на метки некорректны, подписчик не получает событий.
+func (b *Broker) Subscribe(ctx context.Context, filter entities.EventFilter,
+	buffer int) (<-chan entities.Event, func()) {
+	if buffer <= 0 {
+		buffer = DefaultBuffer
+	}
+	if buffer > MaxBuffer {
+		buffer = MaxBuffer
+	}
+	ch := make(chan entities.Event, buffer)
+
+	sub := &subscriber{filter: filter, ch: ch}
+	if len(filter.Matchers) > 0 {
+		matchers, err := entities.CompileMatchers(filter.Matchers)
+		if err != nil {
+			logger.Log.Error("Subscribe: invalid label matchers", zap.Error(err))
+			sub.invalid = true
+		}
+		sub.filter.Matchers = nil
+		sub.matchers = matchers
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	id := b.next
+	b.next++
+	b.subs[id] = sub
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		if sub, ok := b.subs[id]; ok {
+			delete(b.subs, id)
+			close(sub.ch)
+		}
+	}
+}
+
+// Publish рассылает событие подходящим подписчикам.
+func (b *Broker) Publish(ctx context.Context, event entities.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for id, sub := range b.subs {
+		if !sub.match(event) {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			delete(b.subs, id)
+			close(sub.ch)
+			b.dropped++
+			logger.Log.Info("Publish: slow subscriber dropped", zap.Uint64("subscriber", id))
+		}
+	}
+}
+
+// PublishUpdate рассылает событие обновления метрики,
+// используется как обработчик обновлений хранилища метрик.
+func (b *Broker) PublishUpdate(ctx context.Context, update entities.Update) {
+	b.Publish(ctx, entities.NewMetricEvent(update))
+}
+
+// PublishAlert рассылает событие изменения состояния оповещения,
+// используется как обработчик изменений движка оповещений.
+func (b *Broker) PublishAlert(ctx context.Context, alert entities.Alert) {
+	b.Publish(ctx, entities.NewAlertEvent(alert))
+}
+
+// Dropped возвращает количество отключённых медленных подписчиков.
+func (b *Broker) Dropped() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.dropped
+}
+
+// Close отключает всех подписчиков и запрещает новые подписки.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for id, sub := range b.subs {
+		delete(b.subs, id)
+		close(sub.ch)
+	}
+}
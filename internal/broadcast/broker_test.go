@@ -0,0 +1,114 @@
+package broadcast
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pavlegich/metrics-alerting/internal/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testUpdate(name, mType string, value float64) entities.Update {
+	return entities.Update{ID: name, MType: mType, Value: value, Time: time.Now()}
+}
+
+func TestBroker_Filter(t *testing.T) {
+	ctx := context.Background()
+	b := NewBroker(ctx)
+
+	tests := []struct {
+		name   string
+		filter entities.EventFilter
+		want   []string
+	}{
+		{name: "all", filter: entities.EventFilter{}, want: []string{"CPUutilization1", "PollCount", "HeapAlloc", "HighCPU"}},
+		{name: "metrics", filter: entities.EventFilter{Events: []string{entities.EventMetric}},
+			want: []string{"CPUutilization1", "PollCount", "HeapAlloc"}},
+		{name: "alerts", filter: entities.EventFilter{Events: []string{entities.EventAlert}}, want: []string{"HighCPU"}},
+		{name: "by_type", filter: entities.EventFilter{Types: []string{"counter"}}, want: []string{"PollCount", "HighCPU"}},
+		{name: "by_prefix", filter: entities.EventFilter{Prefix: "CPU"}, want: []string{"CPUutilization1", "HighCPU"}},
+		{
+			name: "by_label",
+			filter: entities.EventFilter{Matchers: []entities.Matcher{
+				{Name: "metric", Value: "(Heap|CPU).*", IsRegex: true},
+				{Name: "type", Value: "gauge"},
+			}},
+			want: []string{"CPUutilization1", "HeapAlloc"},
+		},
+		{
+			name:   "alert_labels",
+			filter: entities.EventFilter{Matchers: []entities.Matcher{{Name: "alertname", Value: "HighCPU"}}},
+			want:   []string{"HighCPU"},
+		},
+		{
+			name:   "invalid_regex",
+			filter: entities.EventFilter{Matchers: []entities.Matcher{{Name: "metric", Value: "(", IsRegex: true}}},
+			want:   []string{},
+		},
+	}
+	subs := make([]<-chan entities.Event, len(tests))
+	for i, tc := range tests {
+		ch, cancel := b.Subscribe(ctx, tc.filter, 0)
+		defer cancel()
+		subs[i] = ch
+	}
+
+	b.PublishUpdate(ctx, testUpdate("CPUutilization1", "gauge", 95))
+	b.PublishUpdate(ctx, testUpdate("PollCount", "counter", 3))
+	b.PublishUpdate(ctx, testUpdate("HeapAlloc", "gauge", 1024))
+	b.PublishAlert(ctx, entities.Alert{RuleName: "HighCPU", State: entities.StateFiring,
+		Labels: map[string]string{"alertname": "HighCPU", "metric": "CPUutilization1"}})
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := make([]string, 0)
+			for len(subs[i]) > 0 {
+				event := <-subs[i]
+				switch event.Type {
+				case entities.EventMetric:
+					got = append(got, event.Metric.ID)
+				case entities.EventAlert:
+					got = append(got, event.Alert.RuleName)
+				}
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestBroker_SlowSubscriber(t *testing.T) {
+	ctx := context.Background()
+	b := NewBroker(ctx)
+
+	slow, cancelSlow := b.Subscribe(ctx, entities.EventFilter{}, 1)
+	defer cancelSlow()
+	fast, cancelFast := b.Subscribe(ctx, entities.EventFilter{}, 2)
+	defer cancelFast()
+
+	// переполнение буфера отключает подписчика, не блокируя рассылку
+	b.PublishUpdate(ctx, testUpdate("PollCount", "counter", 1))
+	b.PublishUpdate(ctx, testUpdate("PollCount", "counter", 2))
+	assert.Equal(t, uint64(1), b.Dropped())
+
+	event, ok := <-slow
+	require.True(t, ok)
+	assert.Equal(t, int64(1), *event.Metric.Delta)
+	_, ok = <-slow
+	assert.False(t, ok)
+
+	assert.Len(t, fast, 2)
+
+	// после остановки каналы подписчиков закрываются
+	b.Close()
+	<-fast
+	<-fast
+	_, ok = <-fast
+	assert.False(t, ok)
+
+	closed, cancel := b.Subscribe(ctx, entities.EventFilter{}, 0)
+	defer cancel()
+	_, ok = <-closed
+	assert.False(t, ok)
+}
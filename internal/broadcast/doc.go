@@ -0,0 +1,4 @@
+// Пакет broadcast содержит рассылку событий обновления метрик и изменения
+// состояния оповещений подписчикам потоков с отбором событий
+// и отключением медленных подписчиков.
+package broadcast
@@ -0,0 +1,225 @@
+package entities
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// AlertState содержит состояние оповещения.
+type AlertState string
+
+const (
+	StatePending  AlertState = "pending"  // условие выполняется, но не дольше периода for
+	StateFiring   AlertState = "firing"   // условие выполняется дольше периода for
+	StateResolved AlertState = "resolved" // условие перестало выполняться после срабатывания
+	StateInactive AlertState = "inactive" // условие перестало выполняться до срабатывания
+)
+
+// Виды правил оповещений.
+const (
+	RuleThreshold = "threshold" // значение метрики сравнивается с порогом
+	RuleAbsent    = "absent"    // метрика не обновляется дольше указанного периода
+	RuleRate      = "rate"      // с порогом сравнивается скорость изменения метрики в секунду за окно
+	RuleChange    = "change"    // с порогом сравнивается изменение метрики в процентах за окно
+	RuleZScore    = "zscore"    // с порогом сравнивается z-оценка по среднему и отклонению за окно
+	RuleEWMA      = "ewma"      // с порогом сравнивается z-оценка по экспоненциально взвешенным среднему и отклонению
+	RuleExpr      = "expr"      // выражение по значениям нескольких метрик
+)
+
+// Duration содержит длительность, которая сериализуется в строку вида 1m30s.
+type Duration time.Duration
+
+// MarshalText преобразует длительность в строку.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// UnmarshalText получает длительность из строки.
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return fmt.Errorf("UnmarshalText: parse duration failed %w", err)
+	}
+	*d = Duration(v)
+	return nil
+}
+
+type (
+	// Rule содержит правило оповещения о значении метрики.
+	// Пустой вид правила соответствует сравнению с порогом.
+	// Правило вида absent без имени метрики срабатывает, когда перестают
+	// обновляться все метрики, то есть агент прекратил отправку данных.
+	// Правила видов rate, change, zscore и ewma оцениваются по истории
+	// значений метрики за окно window. Правило вида expr вычисляет выражение
+	// expr по значениям метрик; если задан оператор, результат сравнивается
+	// с порогом, иначе условие выполняется при ненулевом результате.
+	// Сообщение оповещения формируется по шаблону message, в котором доступны
+	// значение .Value, значения метрик .Values и метки .Labels.
+	Rule struct {
+		ID         string            `json:"id" yaml:"id"`                                     // идентификатор правила
+		Name       string            `json:"name" yaml:"name"`                                 // название правила
+		Kind       string            `json:"kind,omitempty" yaml:"kind,omitempty"`             // вид правила
+		MetricType string            `json:"metric_type" yaml:"metric_type"`                   // тип метрики
+		MetricName string            `json:"metric_name" yaml:"metric_name"`                   // имя метрики
+		Operator   string            `json:"operator" yaml:"operator"`                         // оператор сравнения с порогом
+		Threshold  float64           `json:"threshold" yaml:"threshold"`                       // пороговое значение
+		AbsentFor  Duration          `json:"absent_for,omitempty" yaml:"absent_for,omitempty"` // период отсутствия обновлений
+		Window     Duration          `json:"window,omitempty" yaml:"window,omitempty"`         // окно истории значений метрики
+		Alpha      float64           `json:"alpha,omitempty" yaml:"alpha,omitempty"`           // коэффициент сглаживания ewma
+		Expr       string            `json:"expr,omitempty" yaml:"expr,omitempty"`             // выражение составного правила
+		Message    string            `json:"message,omitempty" yaml:"message,omitempty"`       // шаблон сообщения оповещения
+		For        Duration          `json:"for" yaml:"for"`                                   // период выполнения условия до срабатывания
+		Labels     map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`         // метки оповещения
+	}
+
+	// Alert содержит оповещение, созданное правилом.
+	Alert struct {
+		Fingerprint string             `json:"fingerprint"`            // отпечаток оповещения
+		RuleID      string             `json:"rule_id"`                // идентификатор правила
+		RuleName    string             `json:"rule_name"`              // название правила
+		State       AlertState         `json:"state"`                  // состояние оповещения
+		Labels      map[string]string  `json:"labels"`                 // метки оповещения
+		Value       float64            `json:"value"`                  // значение, вызвавшее оповещение
+		Values      map[string]float64 `json:"values,omitempty"`       // значения метрик, использованные при оценке
+		Message     string             `json:"message,omitempty"`      // сообщение оповещения
+		ActiveAt    time.Time          `json:"active_at"`              // время начала выполнения условия
+		FiredAt     time.Time          `json:"fired_at"`               // время срабатывания
+		ResolvedAt  time.Time          `json:"resolved_at"`            // время завершения
+		SilencedBy  []string           `json:"silenced_by,omitempty"`  // идентификаторы подавляющих тишин
+		InhibitedBy []string           `json:"inhibited_by,omitempty"` // отпечатки подавляющих оповещений
+		AckedBy     string             `json:"acked_by,omitempty"`     // автор подтверждения оповещения
+		AckedAt     time.Time          `json:"acked_at"`               // время подтверждения оповещения
+	}
+
+	// AlertTransition содержит изменение состояния оповещения.
+	AlertTransition struct {
+		Time time.Time `json:"time"` // время изменения состояния
+		Alert
+	}
+
+	// AlertFilter содержит условия отбора изменений состояния оповещений.
+	// Пустые условия не ограничивают отбор.
+	AlertFilter struct {
+		RuleID string     // идентификатор правила
+		Metric string     // имя метрики
+		State  AlertState // состояние оповещения
+		From   time.Time  // начало периода
+		To     time.Time  // окончание периода
+		Limit  int        // максимальное количество записей
+	}
+
+	// Matcher содержит условие на значение метки оповещения.
+	// Имя метрики оповещения доступно в метке metric.
+	Matcher struct {
+		Name    string `json:"name" yaml:"name"`                             // имя метки
+		Value   string `json:"value" yaml:"value"`                           // значение или регулярное выражение
+		IsRegex bool   `json:"is_regex,omitempty" yaml:"is_regex,omitempty"` // значение является регулярным выражением
+	}
+
+	// Silence содержит тишину, подавляющую уведомления о подходящих оповещениях.
+	// Тишина с расписанием является периодическим окном обслуживания,
+	// которое действует Duration от каждого срабатывания расписания.
+	Silence struct {
+		ID        string    `json:"id"`                 // идентификатор тишины
+		Matchers  []Matcher `json:"matchers"`           // условия на метки оповещения
+		StartsAt  time.Time `json:"starts_at"`          // время начала действия
+		EndsAt    time.Time `json:"ends_at"`            // время окончания действия, для окна обслуживания может отсутствовать
+		Schedule  string    `json:"schedule,omitempty"` // расписание окна обслуживания в формате cron
+		Duration  Duration  `json:"duration,omitempty"` // длительность окна обслуживания
+		CreatedBy string    `json:"created_by"`         // автор тишины
+		Comment   string    `json:"comment"`            // комментарий
+	}
+)
+
+// InhibitRule содержит правило подавления оповещений: пока срабатывает
+// оповещение, подходящее под условия источника, подавляются уведомления
+// об оповещениях, подходящих под условия цели, у которых значения меток
+// из списка Equal совпадают со значениями меток источника.
+type InhibitRule struct {
+	SourceMatchers []Matcher `json:"source_matchers" yaml:"source_matchers"` // условия на метки источника
+	TargetMatchers []Matcher `json:"target_matchers" yaml:"target_matchers"` // условия на метки цели
+	Equal          []string  `json:"equal,omitempty" yaml:"equal,omitempty"` // метки с совпадающими значениями
+}
+
+// LabelMatchers содержит условия на метки оповещения с разобранными
+// регулярными выражениями для многократной проверки.
+type LabelMatchers []labelMatcher
+
+// labelMatcher содержит условие на метку и его регулярное выражение.
+type labelMatcher struct {
+	Matcher
+	re *regexp.Regexp
+}
+
+// CompileMatchers разбирает регулярные выражения условий на метки.
+// Регулярные выражения должны совпадать со значением метки целиком.
+func CompileMatchers(matchers []Matcher) (LabelMatchers, error) {
+	compiled := make(LabelMatchers, 0, len(matchers))
+	for _, m := range matchers {
+		lm := labelMatcher{Matcher: m}
+		if m.IsRegex {
+			re, err := regexp.Compile("^(?:" + m.Value + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("CompileMatchers: invalid regex %q %w", m.Value, err)
+			}
+			lm.re = re
+		}
+		compiled = append(compiled, lm)
+	}
+	return compiled, nil
+}
+
+// Match проверяет, подходят ли метки оповещения под все условия.
+func (lm LabelMatchers) Match(labels map[string]string) bool {
+	for _, m := range lm {
+		value := labels[m.Name]
+		if m.re != nil {
+			if !m.re.MatchString(value) {
+				return false
+			}
+			continue
+		}
+		if value != m.Value {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchLabels проверяет, подходят ли метки оповещения под все условия.
+// Регулярные выражения разбираются при каждом вызове, для многократной
+// проверки условия разбираются один раз с помощью CompileMatchers.
+func MatchLabels(matchers []Matcher, labels map[string]string) bool {
+	compiled, err := CompileMatchers(matchers)
+	if err != nil {
+		return false
+	}
+	return compiled.Match(labels)
+}
+
+// Suppressed проверяет, подавлено ли оповещение тишинами или правилами подавления.
+func (a Alert) Suppressed() bool {
+	return len(a.SilencedBy) > 0 || len(a.InhibitedBy) > 0
+}
+
+// StateTime возвращает время перехода оповещения в текущее состояние.
+func (a Alert) StateTime() time.Time {
+	switch a.State {
+	case StateFiring:
+		return a.FiredAt
+	case StateResolved, StateInactive:
+		return a.ResolvedAt
+	default:
+		return a.ActiveAt
+	}
+}
+
+// Match проверяет, удовлетворяет ли изменение состояния условиям отбора.
+func (f AlertFilter) Match(t AlertTransition) bool {
+	return (f.RuleID == "" || t.RuleID == f.RuleID) &&
+		(f.Metric == "" || t.Labels["metric"] == f.Metric) &&
+		(f.State == "" || t.State == f.State) &&
+		(f.From.IsZero() || !t.Time.Before(f.From)) &&
+		(f.To.IsZero() || !t.Time.After(f.To))
+}
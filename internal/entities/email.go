@@ -0,0 +1,19 @@
+package entities
+
+// EmailSubjectTemplate содержит шаблон темы письма с уведомлением.
+const EmailSubjectTemplate = "[{{.Status}}] {{len .Alerts}} alert(s)" +
+	"{{range $k, $v := .GroupLabels}} {{$k}}={{$v}}{{end}}"
+
+// EmailTextTemplate содержит шаблон текста письма с уведомлением.
+const EmailTextTemplate = "Status: {{.Status}}\n" +
+	"{{range .Alerts}}\n[{{.State}}] {{.RuleName}}\n" +
+	"  value: {{.Value}}\n" +
+	"{{if .Message}}  message: {{.Message}}\n{{end}}" +
+	"{{range $k, $v := .Labels}}  {{$k}}: {{$v}}\n{{end}}{{end}}"
+
+// EmailHTMLTemplate содержит шаблон HTML разметки письма с уведомлением.
+const EmailHTMLTemplate = "<html><body><h3>{{.Status}}</h3><table>" +
+	"<tr><th>Состояние</th><th>Правило</th><th>Значение</th><th>Сообщение</th><th>Метки</th></tr>" +
+	"{{range .Alerts}}<tr><td>{{.State}}</td><td>{{.RuleName}}</td><td>{{.Value}}</td><td>{{.Message}}</td>" +
+	"<td>{{range $k, $v := .Labels}}{{$k}}={{$v}} {{end}}</td></tr>{{end}}" +
+	"</table></body></html>"
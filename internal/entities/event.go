@@ -0,0 +1,92 @@
+package entities
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	EventMetric = "metric" // событие обновления метрики
+	EventAlert  = "alert"  // событие изменения состояния оповещения
+)
+
+type (
+	// Event содержит событие, рассылаемое подписчикам потока обновлений.
+	Event struct {
+		Type   string    `json:"type"`             // тип события
+		Time   time.Time `json:"time"`             // время события
+		Metric *Metrics  `json:"metric,omitempty"` // метрика после обновления
+		Alert  *Alert    `json:"alert,omitempty"`  // оповещение после изменения состояния
+	}
+
+	// EventFilter содержит условия отбора событий для подписчика.
+	// Пустые условия не ограничивают отбор.
+	EventFilter struct {
+		Events   []string  // типы событий
+		Types    []string  // типы метрик
+		Prefix   string    // префикс имени метрики
+		Matchers []Matcher // условия на метки события
+	}
+)
+
+// NewMetricEvent создаёт событие по обновлению метрики.
+func NewMetricEvent(update Update) Event {
+	metric := &Metrics{ID: update.ID, MType: update.MType}
+	updated := update.Time
+	metric.UpdatedAt = &updated
+	switch update.MType {
+	case "counter":
+		delta := int64(update.Value)
+		metric.Delta = &delta
+	default:
+		value := update.Value
+		metric.Value = &value
+	}
+
+	return Event{Type: EventMetric, Time: update.Time, Metric: metric}
+}
+
+// NewAlertEvent создаёт событие по изменению состояния оповещения.
+func NewAlertEvent(alert Alert) Event {
+	return Event{Type: EventAlert, Time: alert.StateTime(), Alert: &alert}
+}
+
+// Labels возвращает метки события: имя и тип метрики
+// или метки оповещения.
+func (e Event) Labels() map[string]string {
+	switch {
+	case e.Metric != nil:
+		return map[string]string{"metric": e.Metric.ID, "type": e.Metric.MType}
+	case e.Alert != nil:
+		return e.Alert.Labels
+	default:
+		return map[string]string{}
+	}
+}
+
+// Match проверяет, подходит ли событие под условия фильтра.
+// Условия на тип и имя метрики применяются только к событиям метрик.
+func (f EventFilter) Match(e Event) bool {
+	if len(f.Events) > 0 && !containsString(f.Events, e.Type) {
+		return false
+	}
+	if e.Metric != nil {
+		if len(f.Types) > 0 && !containsString(f.Types, e.Metric.MType) {
+			return false
+		}
+		if !strings.HasPrefix(e.Metric.ID, f.Prefix) {
+			return false
+		}
+	}
+	return MatchLabels(f.Matchers, e.Labels())
+}
+
+// containsString проверяет наличие строки в списке.
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
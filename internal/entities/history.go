@@ -0,0 +1,55 @@
+package entities
+
+import "time"
+
+// Resolution содержит разрешение хранения истории значений метрики.
+type Resolution string
+
+const (
+	ResolutionRaw    Resolution = "raw" // исходные значения метрики
+	ResolutionMinute Resolution = "1m"  // агрегаты за минуту
+	ResolutionHour   Resolution = "1h"  // агрегаты за час
+)
+
+type (
+	// Update содержит данные об обновлении метрики в хранилище сервера.
+	Update struct {
+		ID    string    // имя метрики
+		MType string    // тип метрики
+		Value float64   // значение метрики после обновления
+		Delta float64   // приращение значения в случае counter
+		Time  time.Time // время обновления
+	}
+
+	// Point содержит значение метрики или агрегат значений метрики за период.
+	Point struct {
+		Time  time.Time `json:"time"`           // время значения или начало периода
+		Count int       `json:"count"`          // количество исходных значений
+		Min   float64   `json:"min"`            // минимальное значение
+		Max   float64   `json:"max"`            // максимальное значение
+		Avg   float64   `json:"avg"`            // среднее значение
+		Last  float64   `json:"last"`           // последнее значение
+		Sum   float64   `json:"sum,omitempty"`  // сумма приращений в случае counter
+		Rate  float64   `json:"rate,omitempty"` // приращение в секунду в случае counter
+	}
+
+	// Series содержит историю значений метрики с указанным разрешением.
+	Series struct {
+		ID         string     `json:"id"`
+		MType      string     `json:"type"`
+		Resolution Resolution `json:"resolution"`
+		Points     []Point    `json:"points"`
+	}
+)
+
+// Duration возвращает длительность периода агрегации.
+func (r Resolution) Duration() time.Duration {
+	switch r {
+	case ResolutionMinute:
+		return time.Minute
+	case ResolutionHour:
+		return time.Hour
+	default:
+		return 0
+	}
+}
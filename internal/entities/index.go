@@ -1,8 +1,14 @@
 package entities
 
+import "time"
+
 // IndexTemplate содержит шаблон HTML разметки страницы.
-const IndexTemplate = "<html><body><table><tr><th>Название</th><th>Значение</th></tr>" +
-	"{{range .Rows}}<tr><td>{{.Name}}</td><td>{{.Value}}</td></tr>{{end}}</table></body></html>"
+// Устаревшие метрики выделяются отдельным классом строки, строка метрики
+// доступна по ссылке с её именем в качестве якоря.
+const IndexTemplate = "<html><head><style>tr.stale{color:#999}</style></head><body><table>" +
+	"<tr><th>Название</th><th>Значение</th><th>Обновлено</th></tr>" +
+	"{{range .Rows}}<tr id=\"{{.Name}}\"{{if .Stale}} class=\"stale\"{{end}}><td>{{.Name}}</td><td>{{.Value}}</td>" +
+	"<td>{{.Updated}}{{if .Stale}} (устарело){{end}}</td></tr>{{end}}</table></body></html>"
 
 type (
 	// Table содержит строки с данными метрик.
@@ -10,10 +16,12 @@ type (
 		Rows []Row
 	}
 
-	// Row содержит имя и значение метрики.
+	// Row содержит имя, значение и время последнего обновления метрики.
 	Row struct {
-		Name  string
-		Value string
+		Name    string
+		Value   string
+		Updated string
+		Stale   bool
 	}
 )
 
@@ -23,7 +31,11 @@ func NewTable() *Table {
 }
 
 // Put добавляет новую строку с данными метрики в таблицу.
-func (d *Table) Put(mName string, mValue string) {
-	newRow := Row{Name: mName, Value: mValue}
+// Нулевое время обновления означает, что оно неизвестно.
+func (d *Table) Put(mName string, mValue string, updated time.Time, stale bool) {
+	newRow := Row{Name: mName, Value: mValue, Stale: stale}
+	if !updated.IsZero() {
+		newRow.Updated = updated.Format(time.RFC3339)
+	}
 	d.Rows = append(d.Rows, newRow)
 }
@@ -1,9 +1,36 @@
 package entities
 
+import "time"
+
 // Metrics содержит информацию о метрике.
 type Metrics struct {
-	ID    string   `json:"id"`              // имя метрики
-	MType string   `json:"type"`            // параметр, принимающий значение gauge или counter
-	Delta *int64   `json:"delta,omitempty"` // значение метрики в случае передачи counter
-	Value *float64 `json:"value,omitempty"` // значение метрики в случае передачи gauge
+	ID        string     `json:"id"`                   // имя метрики
+	MType     string     `json:"type"`                 // параметр, принимающий значение gauge или counter
+	Delta     *int64     `json:"delta,omitempty"`      // значение метрики в случае передачи counter
+	Value     *float64   `json:"value,omitempty"`      // значение метрики в случае передачи gauge
+	UpdatedAt *time.Time `json:"updated_at,omitempty"` // время последнего обновления метрики на сервере
+	Stale     bool       `json:"stale,omitempty"`      // метрика не обновлялась дольше допустимого периода
+}
+
+// MetricFilter содержит условия отбора и постраничного получения метрик.
+type MetricFilter struct {
+	Prefix string // префикс имени метрики
+	Type   string // тип метрики
+	After  string // имя метрики, после которой начинается страница
+	Limit  int    // размер страницы, 0 — без ограничения
+}
+
+// BatchResult содержит результат применения пачки метрик.
+type BatchResult struct {
+	Applied int          `json:"applied"` // количество применённых метрик
+	Errors  []BatchError `json:"errors"`  // отклонённые метрики
+}
+
+// BatchError содержит ошибку отклонённой метрики пачки.
+type BatchError struct {
+	Index   int    `json:"index"`   // номер метрики в пачке
+	ID      string `json:"id"`      // имя метрики
+	Code    string `json:"code"`    // код ошибки
+	Message string `json:"message"` // описание ошибки
+	Err     error  `json:"-"`       // исходная ошибка хранилища
 }
@@ -0,0 +1,100 @@
+package entities
+
+import "time"
+
+// Режимы TLS при отправке уведомлений по SMTP.
+const (
+	EmailTLSNone     = "none"     // без шифрования
+	EmailTLSStartTLS = "starttls" // обязательный STARTTLS
+	EmailTLS         = "tls"      // соединение по TLS
+)
+
+type (
+	// Route содержит настройки группировки и отправки уведомлений.
+	Route struct {
+		Receiver       string   `json:"receiver" yaml:"receiver"`               // получатель уведомлений
+		GroupBy        []string `json:"group_by" yaml:"group_by"`               // метки для группировки оповещений
+		GroupWait      Duration `json:"group_wait" yaml:"group_wait"`           // ожидание перед первым уведомлением группы
+		GroupInterval  Duration `json:"group_interval" yaml:"group_interval"`   // интервал уведомлений об изменениях группы
+		RepeatInterval Duration `json:"repeat_interval" yaml:"repeat_interval"` // интервал повторных уведомлений
+	}
+
+	// Receiver содержит настройки получателя уведомлений.
+	// Должен быть указан ровно один канал отправки.
+	Receiver struct {
+		Name     string          `json:"name" yaml:"name"`                             // название получателя
+		Webhook  *WebhookConfig  `json:"webhook,omitempty" yaml:"webhook,omitempty"`   // отправка по HTTP
+		Email    *EmailConfig    `json:"email,omitempty" yaml:"email,omitempty"`       // отправка по электронной почте
+		Slack    *SlackConfig    `json:"slack,omitempty" yaml:"slack,omitempty"`       // отправка во входящий вебхук Slack или Mattermost
+		Telegram *TelegramConfig `json:"telegram,omitempty" yaml:"telegram,omitempty"` // отправка через Telegram Bot API
+	}
+
+	// WebhookConfig содержит настройки отправки уведомлений по HTTP.
+	WebhookConfig struct {
+		URL string `json:"url" yaml:"url"` // адрес получателя
+	}
+
+	// EmailConfig содержит настройки отправки уведомлений по SMTP.
+	// Пустые шаблоны заменяются шаблонами по умолчанию.
+	EmailConfig struct {
+		Smarthost string   `json:"smarthost" yaml:"smarthost"`       // адрес SMTP-сервера host:port
+		Username  string   `json:"username" yaml:"username"`         // имя пользователя для аутентификации
+		Password  string   `json:"password" yaml:"password"`         // пароль для аутентификации
+		TLS       string   `json:"tls" yaml:"tls"`                   // режим TLS, без указания STARTTLS используется при поддержке сервером
+		From      string   `json:"from" yaml:"from"`                 // адрес отправителя
+		To        []string `json:"to" yaml:"to"`                     // адреса получателей
+		Subject   string   `json:"subject,omitempty" yaml:"subject"` // шаблон темы письма
+		Text      string   `json:"text,omitempty" yaml:"text"`       // шаблон текста письма
+		HTML      string   `json:"html,omitempty" yaml:"html"`       // шаблон HTML разметки письма
+	}
+
+	// SlackConfig содержит настройки отправки уведомлений во входящий вебхук
+	// в формате Slack, который также поддерживается Mattermost.
+	SlackConfig struct {
+		URL      string `json:"url" yaml:"url"`           // адрес входящего вебхука
+		Channel  string `json:"channel" yaml:"channel"`   // канал, может отсутствовать
+		Username string `json:"username" yaml:"username"` // имя отправителя, может отсутствовать
+	}
+
+	// TelegramConfig содержит настройки отправки уведомлений через Telegram Bot API.
+	TelegramConfig struct {
+		APIURL   string `json:"api_url" yaml:"api_url"`     // адрес Bot API, по умолчанию https://api.telegram.org
+		BotToken string `json:"bot_token" yaml:"bot_token"` // токен бота
+		ChatID   string `json:"chat_id" yaml:"chat_id"`     // идентификатор чата или @имя канала
+	}
+
+	// Notification содержит уведомление о группе оповещений.
+	Notification struct {
+		Receiver    string            `json:"receiver"`     // название получателя
+		Status      AlertState        `json:"status"`       // firing при наличии сработавших оповещений, иначе resolved
+		GroupKey    string            `json:"group_key"`    // ключ группы
+		GroupLabels map[string]string `json:"group_labels"` // метки группы
+		Alerts      []Alert           `json:"alerts"`       // оповещения группы
+		ExternalURL string            `json:"external_url"` // адрес веб-интерфейса сервера
+	}
+
+	// EscalationPolicy содержит политику эскалации срабатывающих оповещений,
+	// подходящих под условия на метки. Шаги политики выполняются по очереди,
+	// пока оповещение не подтверждено или не завершено.
+	EscalationPolicy struct {
+		Name     string           `json:"name" yaml:"name"`         // название политики
+		Matchers []Matcher        `json:"matchers" yaml:"matchers"` // условия на метки оповещения
+		Steps    []EscalationStep `json:"steps" yaml:"steps"`       // шаги эскалации
+	}
+
+	// EscalationStep содержит шаг эскалации: уведомление получателя
+	// через указанное время после срабатывания оповещения.
+	EscalationStep struct {
+		Receiver string   `json:"receiver" yaml:"receiver"` // получатель уведомления
+		After    Duration `json:"after" yaml:"after"`       // время от срабатывания оповещения
+	}
+
+	// Escalation содержит состояние эскалации оповещения.
+	Escalation struct {
+		Policy    string    `json:"policy"`             // название политики эскалации
+		Alert     Alert     `json:"alert"`              // последнее состояние оповещения
+		StartedAt time.Time `json:"started_at"`         // время начала эскалации
+		NextStep  int       `json:"next_step"`          // номер следующего шага эскалации
+		Notified  []string  `json:"notified,omitempty"` // уведомлённые получатели
+	}
+)
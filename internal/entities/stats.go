@@ -0,0 +1,9 @@
+package entities
+
+// SaveStats содержит статистику сохранения метрик в базу данных.
+type SaveStats struct {
+	Saves       int64    `json:"saves"`        // количество успешных сохранений
+	RowsWritten int64    `json:"rows_written"` // общее количество записанных строк
+	LastRows    int      `json:"last_rows"`    // количество строк, записанных при последнем сохранении
+	LastLatency Duration `json:"last_latency"` // длительность последнего сохранения
+}
@@ -39,6 +39,18 @@ func (c *compressWriter) WriteHeader(statusCode int) {
 	c.w.WriteHeader(statusCode)
 }
 
+// FlushError досылает клиенту сжатые данные из буфера,
+// используется через http.ResponseController.
+func (c *compressWriter) FlushError() error {
+	if err := c.zw.Flush(); err != nil {
+		return fmt.Errorf("FlushError: gzip flush failed %w", err)
+	}
+	if err := http.NewResponseController(c.w).Flush(); err != nil {
+		return fmt.Errorf("FlushError: response flush failed %w", err)
+	}
+	return nil
+}
+
 // Close закрывает gzip.Writer и досылает все данные из буфера.
 func (c *compressWriter) Close() error {
 	return c.zw.Close()
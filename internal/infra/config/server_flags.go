@@ -7,26 +7,51 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"time"
 
 	"github.com/caarlos0/env/v6"
 )
 
 // ServerConfig содержит значения флагов и переменных окружения сервера.
 type ServerConfig struct {
-	Address       string `env:"ADDRESS" json:"address"`
-	Grpc          string `env:"GRPC" json:"grpc"`
-	StoragePath   string `env:"FILE_STORAGE_PATH" json:"store_file"`
-	Database      string `env:"DATABASE_DSN" json:"database_dsn"`
-	Key           string `env:"KEY" json:"key"`
-	CryptoKey     string `env:"CRYPTO_KEY" json:"crypto_key"`
-	Config        string `env:"CONFIG"`
-	TrustedSubnet string `env:"TRUSTED_SUBNET" json:"trusted_subnet"`
-	Profile       string `env:"PROFILE" json:"profile"`
-	Restore       bool   `env:"RESTORE" json:"restore"`
-	StoreInterval int    `env:"STORE_INTERVAL" json:"store_interval"`
+	Address       string   `env:"ADDRESS" json:"address"`
+	Grpc          string   `env:"GRPC" json:"grpc"`
+	StoragePath   string   `env:"FILE_STORAGE_PATH" json:"store_file"`
+	Database      string   `env:"DATABASE_DSN" json:"database_dsn"`
+	KVPath        string   `env:"KV_STORAGE_PATH" json:"kv_store_file"`
+	Key           string   `env:"KEY" json:"key"`
+	CryptoKey     string   `env:"CRYPTO_KEY" json:"crypto_key"`
+	Config        string   `env:"CONFIG"`
+	TrustedSubnet string   `env:"TRUSTED_SUBNET" json:"trusted_subnet"`
+	Profile       string   `env:"PROFILE" json:"profile"`
+	WALPath       string   `env:"WAL_PATH" json:"wal_path"`
+	RulesFile     string   `env:"RULES_FILE" json:"rules_file"`
+	Restore       bool     `env:"RESTORE" json:"restore"`
+	StoreInterval int      `env:"STORE_INTERVAL" json:"store_interval"`
+	StoreBackups  int      `env:"STORE_BACKUPS" json:"store_backups"`
+	WALSync       int      `env:"WAL_SYNC_INTERVAL" json:"wal_sync_interval"`
+	HistoryRaw    Duration `env:"HISTORY_RAW_RETENTION" json:"history_raw_retention"`
+	HistoryMinute Duration `env:"HISTORY_MINUTE_RETENTION" json:"history_minute_retention"`
+	HistoryHour   Duration `env:"HISTORY_HOUR_RETENTION" json:"history_hour_retention"`
+	AlertInterval Duration `env:"ALERT_EVAL_INTERVAL" json:"alert_eval_interval"`
+	StaleAfter    Duration `env:"STALE_AFTER" json:"stale_after"`
 	Network       *net.IPNet
 }
 
+// Duration содержит продолжительность, которая в файле конфигурации
+// и переменных окружения задаётся строкой в формате time.ParseDuration.
+type Duration time.Duration
+
+// UnmarshalText обрабатывает продолжительность в формате time.ParseDuration.
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return fmt.Errorf("UnmarshalText: parse duration failed %w", err)
+	}
+	*d = Duration(v)
+	return nil
+}
+
 // ServerParseFlags обрабатывает введённые значения флагов и переменных окружения
 // при запуск сервера.
 func ServerParseFlags(ctx context.Context) (*ServerConfig, error) {
@@ -35,7 +60,8 @@ func ServerParseFlags(ctx context.Context) (*ServerConfig, error) {
 	flag.StringVar(&cfg.Address, "a", "localhost:8080", "HTTP-server endpoint address host:port")
 	flag.StringVar(&cfg.Grpc, "grpc", "", "gRPC-server endpoint address host:port")
 	flag.StringVar(&cfg.StoragePath, "f", "/tmp/metrics-db.json", "Full path of values storage")
-	flag.StringVar(&cfg.Database, "d", "", "URI (DSN) to database")
+	flag.StringVar(&cfg.Database, "d", "", "URI (DSN) to database, sqlite:///path/to/file.db for embedded SQLite")
+	flag.StringVar(&cfg.KVPath, "kv", "", "Full path of embedded key-value storage")
 	flag.StringVar(&cfg.Key, "k", "", "Key for sign")
 	flag.StringVar(&cfg.CryptoKey, "crypto-key", "", "Path to private key")
 	// flag.StringVar(&cfg.Config, "config", "/Users/Pavel/Desktop/Go.Edu/metrics-alerting/internal/infra/config/server_config.json", "Path to config")
@@ -44,8 +70,17 @@ func ServerParseFlags(ctx context.Context) (*ServerConfig, error) {
 	flag.StringVar(&cfg.TrustedSubnet, "t", "", "Trusted subnet CIDR")
 	// 172.17.0.0/24
 	flag.StringVar(&cfg.Profile, "profile", "localhost:8081", "Profile endpoint address host:port")
+	flag.StringVar(&cfg.WALPath, "wal", "", "Path of the write-ahead log")
+	flag.StringVar(&cfg.RulesFile, "rules", "", "Path of the alert rules file in YAML or JSON format")
 	flag.BoolVar(&cfg.Restore, "r", false, "Restore values from the disk")
 	flag.IntVar(&cfg.StoreInterval, "i", 5, "Frequency of storing on disk")
+	flag.IntVar(&cfg.StoreBackups, "backups", 0, "Number of rotated backup generations of the storage file")
+	flag.DurationVar((*time.Duration)(&cfg.HistoryRaw), "history-raw", time.Hour, "Retention of raw metric samples")
+	flag.DurationVar((*time.Duration)(&cfg.HistoryMinute), "history-1m", 24*time.Hour, "Retention of 1-minute metric rollups")
+	flag.DurationVar((*time.Duration)(&cfg.HistoryHour), "history-1h", 30*24*time.Hour, "Retention of 1-hour metric rollups")
+	flag.DurationVar((*time.Duration)(&cfg.AlertInterval), "alert-interval", 10*time.Second, "Interval of alert rules evaluation")
+	flag.DurationVar((*time.Duration)(&cfg.StaleAfter), "stale-after", 5*time.Minute, "Period without updates after which a metric is marked stale")
+	flag.IntVar(&cfg.WALSync, "wal-sync", 0, "Group commit interval of the write-ahead log in milliseconds")
 
 	flag.Parse()
 
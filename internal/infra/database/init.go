@@ -6,17 +6,34 @@ import (
 	"database/sql"
 	"embed"
 	"fmt"
+	"strings"
 
 	"github.com/pressly/goose/v3"
+	_ "modernc.org/sqlite"
 )
 
-//go:embed migrations/*.sql
+// sqliteScheme содержит префикс DSN встроенной базы данных SQLite.
+const sqliteScheme = "sqlite://"
+
+//go:embed migrations/*.sql migrations/sqlite/*.sql
 var embedMigrations embed.FS
 
+// IsSQLite проверяет, указывает ли DSN на встроенную базу данных SQLite.
+func IsSQLite(path string) bool {
+	return strings.HasPrefix(path, sqliteScheme)
+}
+
 // Init инициализирует базу данных и создаёт таблицы из указанных миграций.
+// DSN вида sqlite:///var/lib/metrics.db открывает встроенную базу данных SQLite,
+// остальные DSN открывают базу данных PostgreSQL.
 func Init(ctx context.Context, path string) (*sql.DB, error) {
+	driver, dsn, dialect, dir := "pgx", path, "postgres", "migrations"
+	if IsSQLite(path) {
+		driver, dsn, dialect, dir = "sqlite", strings.TrimPrefix(path, sqliteScheme), "sqlite3", "migrations/sqlite"
+	}
+
 	// Открытие и проверка базы данных
-	db, err := sql.Open("pgx", path)
+	db, err := sql.Open(driver, dsn)
 	if err != nil {
 		return nil, fmt.Errorf("Init: couldn't open database %w", err)
 	}
@@ -25,12 +42,17 @@ func Init(ctx context.Context, path string) (*sql.DB, error) {
 		return nil, fmt.Errorf("Init: connection with database is died %w", err)
 	}
 
+	// SQLite не поддерживает одновременную запись из нескольких соединений
+	if IsSQLite(path) {
+		db.SetMaxOpenConns(1)
+	}
+
 	// Миграции
 	goose.SetBaseFS(embedMigrations)
-	if err := goose.SetDialect("postgres"); err != nil {
+	if err := goose.SetDialect(dialect); err != nil {
 		return nil, fmt.Errorf("Init: goose set dialect failed %w", err)
 	}
-	if err := goose.Up(db, "migrations"); err != nil {
+	if err := goose.Up(db, dir); err != nil {
 		return nil, fmt.Errorf("Init: goose up failed %w", err)
 	}
 
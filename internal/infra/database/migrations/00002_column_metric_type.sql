@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE storage ADD COLUMN IF NOT EXISTS type text NOT NULL DEFAULT 'gauge';
+
+-- +goose Down
+ALTER TABLE storage DROP COLUMN type;
\ No newline at end of file
@@ -0,0 +1,32 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS rollups_1m (
+    id text NOT NULL,
+    type text NOT NULL,
+    start timestamptz NOT NULL,
+    count integer NOT NULL,
+    min double precision NOT NULL,
+    max double precision NOT NULL,
+    avg double precision NOT NULL,
+    last double precision NOT NULL,
+    sum double precision NOT NULL,
+    rate double precision NOT NULL,
+    PRIMARY KEY (id, start)
+);
+
+CREATE TABLE IF NOT EXISTS rollups_1h (
+    id text NOT NULL,
+    type text NOT NULL,
+    start timestamptz NOT NULL,
+    count integer NOT NULL,
+    min double precision NOT NULL,
+    max double precision NOT NULL,
+    avg double precision NOT NULL,
+    last double precision NOT NULL,
+    sum double precision NOT NULL,
+    rate double precision NOT NULL,
+    PRIMARY KEY (id, start)
+);
+
+-- +goose Down
+DROP TABLE rollups_1h;
+DROP TABLE rollups_1m;
@@ -0,0 +1,8 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS state (
+    kind text PRIMARY KEY,
+    data text NOT NULL
+);
+
+-- +goose Down
+DROP TABLE state;
@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS alert_history (
+    id bigserial PRIMARY KEY,
+    time timestamptz NOT NULL,
+    fingerprint text NOT NULL,
+    rule_id text NOT NULL,
+    rule_name text NOT NULL,
+    metric text NOT NULL,
+    state text NOT NULL,
+    value double precision NOT NULL,
+    data text NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS alert_history_time_idx ON alert_history (time);
+
+-- +goose Down
+DROP TABLE alert_history;
@@ -0,0 +1,9 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS storage (
+    id TEXT PRIMARY KEY,
+    type TEXT NOT NULL DEFAULT 'gauge',
+    value TEXT NOT NULL
+);
+
+-- +goose Down
+DROP TABLE storage;
@@ -0,0 +1,8 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS state (
+    kind TEXT PRIMARY KEY,
+    data TEXT NOT NULL
+);
+
+-- +goose Down
+DROP TABLE state;
@@ -41,3 +41,9 @@ func (r *SigningResponseWriter) Write(b []byte) (int, error) {
 	r.Header().Set("HashSHA256", hex.EncodeToString(hash))
 	return size, nil
 }
+
+// Unwrap возвращает оригинальный http.ResponseWriter,
+// например для отправки данных клиенту через http.ResponseController.
+func (r *SigningResponseWriter) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
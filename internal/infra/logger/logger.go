@@ -65,6 +65,15 @@ func (r *LoggingResponseWriter) Write(b []byte) (int, error) {
 		return size, fmt.Errorf("Write: response write %w", err)
 	}
 	r.ResponseData.Size += size // захватываем размер
-	r.ResponseData.Body.Write(b)
+	// тело потока событий не захватываем, так как поток не ограничен по размеру
+	if r.Header().Get("Content-Type") != "text/event-stream" {
+		r.ResponseData.Body.Write(b)
+	}
 	return size, nil
 }
+
+// Unwrap возвращает оригинальный http.ResponseWriter,
+// например для отправки данных клиенту через http.ResponseController.
+func (r *LoggingResponseWriter) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -4,6 +4,7 @@ package interfaces
 import (
 	"context"
 	"runtime"
+	"time"
 
 	"github.com/pavlegich/metrics-alerting/internal/entities"
 	"github.com/pavlegich/metrics-alerting/internal/infra/config"
@@ -22,9 +23,20 @@ type (
 
 	// MetricStorage содержит методы для работы с метрики на сервере.
 	MetricStorage interface {
-		Put(ctx context.Context, metricType string, metricName string, metricValue string) int
+		Put(ctx context.Context, metricType string, metricName string, metricValue string) error
+		Restore(ctx context.Context, metricType string, metricName string, metricValue string) error
+		PutBatch(ctx context.Context, metrics []entities.Metrics, bestEffort bool) (entities.BatchResult, error)
+		Delete(ctx context.Context, metricName string) error
+		List(ctx context.Context, filter entities.MetricFilter) ([]entities.Metrics, string)
 		GetAll(ctx context.Context) map[string]string
-		Get(ctx context.Context, metricType string, metricName string) (string, int)
+		GetAllTypes(ctx context.Context) map[string]string
+		Get(ctx context.Context, metricType string, metricName string) (string, error)
+		GetType(ctx context.Context, metricName string) (string, bool)
+		GetUpdated(ctx context.Context, metricName string) (time.Time, bool)
+		GetAllUpdated(ctx context.Context) map[string]time.Time
+		GetDirty(ctx context.Context) (map[string]string, uint64)
+		GetDeleted(ctx context.Context) []string
+		MarkSaved(ctx context.Context, version uint64)
 	}
 
 	// Storage содержит методы для работы хранилища.
@@ -34,4 +46,74 @@ type (
 		Load(ctx context.Context, ms MetricStorage) error
 		Ping(ctx context.Context) error
 	}
+
+	// SaveStatsReporter содержит методы для получения статистики сохранения метрик.
+	SaveStatsReporter interface {
+		Stats() entities.SaveStats
+	}
+
+	// StateStorage содержит методы для хранения состояния сервера,
+	// например правил оповещений, в виде сериализованных документов.
+	StateStorage interface {
+		SaveState(ctx context.Context, kind string, data []byte) error
+		LoadState(ctx context.Context, kind string) ([]byte, error)
+	}
+
+	// RollupStorage содержит методы для хранения агрегатов истории метрик.
+	RollupStorage interface {
+		SaveRollups(ctx context.Context, rollups []entities.Series) error
+		LoadRollups(ctx context.Context, res entities.Resolution, since time.Time) ([]entities.Series, error)
+		DeleteRollups(ctx context.Context, res entities.Resolution, before time.Time) error
+	}
+
+	// HistoryStorage содержит методы для получения истории значений метрик.
+	HistoryStorage interface {
+		Query(ctx context.Context, metricName string, from time.Time, to time.Time) (entities.Series, bool)
+		QueryResolution(ctx context.Context, metricName string, from time.Time, to time.Time,
+			res entities.Resolution) (entities.Series, bool)
+	}
+
+	// TransitionStorage содержит методы для хранения истории изменений
+	// состояния оповещений.
+	TransitionStorage interface {
+		SaveTransition(ctx context.Context, t entities.AlertTransition) error
+		QueryTransitions(ctx context.Context, filter entities.AlertFilter) ([]entities.AlertTransition, error)
+	}
+
+	// Alerting содержит методы для управления правилами оповещений, тишинами
+	// и оповещениями.
+	Alerting interface {
+		ListRules(ctx context.Context) []entities.Rule
+		GetRule(ctx context.Context, id string) (entities.Rule, error)
+		CreateRule(ctx context.Context, rule entities.Rule) (entities.Rule, error)
+		UpdateRule(ctx context.Context, id string, rule entities.Rule) (entities.Rule, error)
+		DeleteRule(ctx context.Context, id string) error
+
+		ListSilences(ctx context.Context) []entities.Silence
+		GetSilence(ctx context.Context, id string) (entities.Silence, error)
+		CreateSilence(ctx context.Context, silence entities.Silence) (entities.Silence, error)
+		DeleteSilence(ctx context.Context, id string) error
+
+		Alerts(ctx context.Context) []entities.Alert
+		Transitions(ctx context.Context, filter entities.AlertFilter) ([]entities.AlertTransition, error)
+		Acknowledge(ctx context.Context, fingerprint string, by string) (entities.Alert, error)
+	}
+
+	// FailureReporter содержит методы для получения количества неудачных
+	// отправок уведомлений по получателям.
+	FailureReporter interface {
+		Failures() map[string]uint64
+	}
+
+	// DropReporter содержит методы для получения количества отключённых
+	// медленных подписчиков потока событий.
+	DropReporter interface {
+		Dropped() uint64
+	}
+
+	// Broadcaster содержит методы для подписки на поток событий обновления метрик
+	// и изменения состояния оповещений.
+	Broadcaster interface {
+		Subscribe(ctx context.Context, filter entities.EventFilter, buffer int) (<-chan entities.Event, func())
+	}
 )
@@ -0,0 +1,71 @@
+package notify
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/pavlegich/metrics-alerting/internal/entities"
+)
+
+// severityLabel содержит имя метки с критичностью оповещения.
+const severityLabel = "severity"
+
+// severityColor возвращает цвет оповещения по его состоянию и критичности.
+func severityColor(a entities.Alert) string {
+	if a.State == entities.StateResolved {
+		return "#2EB886"
+	}
+	switch a.Labels[severityLabel] {
+	case "critical":
+		return "#E01E5A"
+	case "warning":
+		return "#ECB22E"
+	default:
+		return "#439FE0"
+	}
+}
+
+// severityEmoji возвращает значок оповещения по его состоянию и критичности.
+func severityEmoji(a entities.Alert) string {
+	if a.State == entities.StateResolved {
+		return "✅"
+	}
+	switch a.Labels[severityLabel] {
+	case "critical":
+		return "🔴"
+	case "warning":
+		return "🟠"
+	default:
+		return "🔵"
+	}
+}
+
+// alertTitle возвращает заголовок оповещения.
+func alertTitle(a entities.Alert) string {
+	return fmt.Sprintf("[%s] %s", strings.ToUpper(string(a.State)), a.RuleName)
+}
+
+// alertURL возвращает ссылку на строку метрики оповещения на главной
+// странице веб-интерфейса сервера.
+func alertURL(externalURL string, a entities.Alert) string {
+	if externalURL == "" {
+		return ""
+	}
+	base := strings.TrimSuffix(externalURL, "/")
+	if metric, ok := a.Labels["metric"]; ok {
+		return base + "/#" + url.PathEscape(metric)
+	}
+	return base + "/"
+}
+
+// sortedLabels возвращает метки оповещения в виде отсортированных пар key=value.
+func sortedLabels(labels map[string]string) []string {
+	pairs := make([]string, 0, len(labels))
+	for k, v := range labels {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return pairs
+}
@@ -0,0 +1,305 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pavlegich/metrics-alerting/internal/entities"
+	"github.com/pavlegich/metrics-alerting/internal/infra/logger"
+	"go.uber.org/zap"
+)
+
+// Значения интервалов по умолчанию.
+const (
+	defaultGroupWait      = 30 * time.Second
+	defaultGroupInterval  = 5 * time.Minute
+	defaultRepeatInterval = 4 * time.Hour
+)
+
+// group содержит группу оповещений с общими значениями меток группировки.
+type group struct {
+	key        string
+	labels     map[string]string
+	alerts     map[string]entities.Alert      // последние состояния оповещений по отпечаткам
+	notified   map[string]entities.AlertState // отправленные состояния оповещений по отпечаткам
+	nextFlush  time.Time                      // время отправки накопленных изменений
+	lastNotify time.Time                      // время последнего уведомления
+}
+
+// Dispatcher группирует оповещения и отправляет уведомления о группах
+// получателю маршрута с учётом интервалов group_wait, group_interval
+// и repeat_interval. Повторные изменения состояния одного оповещения
+// объединяются по отпечатку.
+type Dispatcher struct {
+	mu          *sync.Mutex
+	externalURL string
+	route       entities.Route
+	receivers   map[string]Receiver
+	groups      map[string]*group
+	failures    map[string]uint64
+}
+
+// NewDispatcher создаёт новый диспетчер уведомлений.
+func NewDispatcher(ctx context.Context) *Dispatcher {
+	return &Dispatcher{
+		mu:        &sync.Mutex{},
+		receivers: make(map[string]Receiver),
+		groups:    make(map[string]*group),
+		failures:  make(map[string]uint64),
+	}
+}
+
+// Configure атомарно заменяет адрес веб-интерфейса сервера, маршрут и получателей
+// уведомлений. Накопленные группы оповещений сохраняются.
+func (d *Dispatcher) Configure(ctx context.Context, externalURL string, route entities.Route,
+	receivers []entities.Receiver) error {
+	built := make(map[string]Receiver, len(receivers))
+	for _, cfg := range receivers {
+		r, err := NewReceiver(cfg)
+		if err != nil {
+			return fmt.Errorf("Configure: %w", err)
+		}
+		built[cfg.Name] = r
+	}
+	if _, ok := built[route.Receiver]; route.Receiver != "" && !ok {
+		return fmt.Errorf("Configure: unknown route receiver %q", route.Receiver)
+	}
+
+	if route.GroupWait == 0 {
+		route.GroupWait = entities.Duration(defaultGroupWait)
+	}
+	if route.GroupInterval == 0 {
+		route.GroupInterval = entities.Duration(defaultGroupInterval)
+	}
+	if route.RepeatInterval == 0 {
+		route.RepeatInterval = entities.Duration(defaultRepeatInterval)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.externalURL = externalURL
+	d.route = route
+	d.receivers = built
+	return nil
+}
+
+// Add принимает изменение состояния оповещения для отправки уведомления.
+func (d *Dispatcher) Add(ctx context.Context, alert entities.Alert) {
+	d.add(alert, time.Now())
+}
+
+// Run отправляет накопленные уведомления с указанным интервалом проверки.
+func (d *Dispatcher) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			d.Flush(ctx, now)
+		}
+	}
+}
+
+// Flush отправляет уведомления о группах, для которых наступило время отправки.
+func (d *Dispatcher) Flush(ctx context.Context, now time.Time) {
+	d.mu.Lock()
+	receiver, ok := d.receivers[d.route.Receiver]
+	if !ok {
+		d.mu.Unlock()
+		return
+	}
+	due := make([]entities.Notification, 0)
+	for _, g := range d.groups {
+		if d.isDue(g, now) {
+			due = append(due, d.notification(g))
+		}
+	}
+	d.mu.Unlock()
+
+	for _, n := range due {
+		err := receiver.Notify(ctx, n)
+
+		d.mu.Lock()
+		g, ok := d.groups[n.GroupKey]
+		if !ok {
+			d.mu.Unlock()
+			continue
+		}
+		if err != nil {
+			d.failures[n.Receiver]++
+			g.nextFlush = now.Add(time.Duration(d.route.GroupInterval))
+			d.mu.Unlock()
+
+			logger.Log.Error("Flush: send notification failed",
+				zap.String("receiver", n.Receiver),
+				zap.String("group", n.GroupKey),
+				zap.Error(err))
+			continue
+		}
+		d.markNotified(g, n, now)
+		d.mu.Unlock()
+	}
+}
+
+// Failures возвращает количество ошибок отправки уведомлений по получателям.
+func (d *Dispatcher) Failures() map[string]uint64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	failures := make(map[string]uint64, len(d.failures))
+	for k, v := range d.failures {
+		failures[k] = v
+	}
+	return failures
+}
+
+// add помещает изменение состояния оповещения в его группу.
+func (d *Dispatcher) add(alert entities.Alert, now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.route.Receiver == "" {
+		return
+	}
+
+	labels := groupLabels(alert.Labels, d.route.GroupBy)
+	key := groupKey(labels)
+	g, ok := d.groups[key]
+
+	switch alert.State {
+	case entities.StateFiring:
+		if !ok {
+			g = &group{
+				key:       key,
+				labels:    labels,
+				alerts:    make(map[string]entities.Alert),
+				notified:  make(map[string]entities.AlertState),
+				nextFlush: now.Add(time.Duration(d.route.GroupWait)),
+			}
+			d.groups[key] = g
+		}
+	case entities.StateResolved:
+		if !ok {
+			return
+		}
+		// о завершении неотправленного оповещения не уведомляем
+		if _, sent := g.notified[alert.Fingerprint]; !sent {
+			delete(g.alerts, alert.Fingerprint)
+			if len(g.alerts) == 0 {
+				delete(d.groups, key)
+			}
+			return
+		}
+	default:
+		return
+	}
+
+	g.alerts[alert.Fingerprint] = alert
+	if g.notified[alert.Fingerprint] == alert.State {
+		return
+	}
+	if g.nextFlush.IsZero() {
+		next := g.lastNotify.Add(time.Duration(d.route.GroupInterval))
+		if next.Before(now) {
+			next = now
+		}
+		g.nextFlush = next
+	}
+}
+
+// isDue проверяет, наступило ли время уведомления о группе.
+// Вызывается под блокировкой.
+func (d *Dispatcher) isDue(g *group, now time.Time) bool {
+	if !g.nextFlush.IsZero() && !now.Before(g.nextFlush) {
+		return true
+	}
+	if g.lastNotify.IsZero() || now.Sub(g.lastNotify) < time.Duration(d.route.RepeatInterval) {
+		return false
+	}
+	for _, a := range g.alerts {
+		if a.State == entities.StateFiring {
+			return true
+		}
+	}
+	return false
+}
+
+// notification формирует уведомление о группе. Вызывается под блокировкой.
+func (d *Dispatcher) notification(g *group) entities.Notification {
+	n := entities.Notification{
+		Receiver:    d.route.Receiver,
+		Status:      entities.StateResolved,
+		GroupKey:    g.key,
+		GroupLabels: g.labels,
+		Alerts:      make([]entities.Alert, 0, len(g.alerts)),
+		ExternalURL: d.externalURL,
+	}
+	for _, a := range g.alerts {
+		if a.State == entities.StateFiring {
+			n.Status = entities.StateFiring
+		}
+		n.Alerts = append(n.Alerts, a)
+	}
+	sort.Slice(n.Alerts, func(i, j int) bool {
+		return n.Alerts[i].Fingerprint < n.Alerts[j].Fingerprint
+	})
+	return n
+}
+
+// markNotified отмечает отправленные состояния оповещений группы
+// и удаляет завершённые оповещения. Вызывается под блокировкой.
+func (d *Dispatcher) markNotified(g *group, n entities.Notification, now time.Time) {
+	g.lastNotify = now
+	g.nextFlush = time.Time{}
+
+	for _, sent := range n.Alerts {
+		cur, ok := g.alerts[sent.Fingerprint]
+		if !ok || cur.State != sent.State {
+			// состояние изменилось во время отправки
+			g.nextFlush = now.Add(time.Duration(d.route.GroupInterval))
+			continue
+		}
+		if sent.State == entities.StateResolved {
+			delete(g.alerts, sent.Fingerprint)
+			delete(g.notified, sent.Fingerprint)
+			continue
+		}
+		g.notified[sent.Fingerprint] = sent.State
+	}
+
+	if len(g.alerts) == 0 {
+		delete(d.groups, g.key)
+	}
+}
+
+// groupLabels возвращает значения меток группировки оповещения.
+func groupLabels(labels map[string]string, groupBy []string) map[string]string {
+	res := make(map[string]string, len(groupBy))
+	for _, k := range groupBy {
+		res[k] = labels[k]
+	}
+	return res
+}
+
+// groupKey формирует ключ группы по значениям меток группировки.
+func groupKey(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", k, labels[k]))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
@@ -0,0 +1,118 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pavlegich/metrics-alerting/internal/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeReceiver сохраняет полученные уведомления.
+type fakeReceiver struct {
+	sent []entities.Notification
+	err  error
+}
+
+func (f *fakeReceiver) Notify(ctx context.Context, n entities.Notification) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.sent = append(f.sent, n)
+	return nil
+}
+
+func newTestDispatcher(t *testing.T, r Receiver) *Dispatcher {
+	d := NewDispatcher(context.Background())
+	require.NoError(t, d.Configure(context.Background(), "", entities.Route{
+		Receiver:       "test",
+		GroupBy:        []string{"alertname"},
+		GroupWait:      entities.Duration(30 * time.Second),
+		GroupInterval:  entities.Duration(5 * time.Minute),
+		RepeatInterval: entities.Duration(time.Hour),
+	}, []entities.Receiver{{Name: "test", Webhook: &entities.WebhookConfig{URL: "http://localhost"}}}))
+	d.receivers["test"] = r
+	return d
+}
+
+func testAlert(fp, host string, state entities.AlertState) entities.Alert {
+	return entities.Alert{
+		Fingerprint: fp,
+		RuleName:    "HighCPU",
+		State:       state,
+		Labels:      map[string]string{"alertname": "HighCPU", "host": host},
+	}
+}
+
+func TestDispatcher_Grouping(t *testing.T) {
+	ctx := context.Background()
+	r := &fakeReceiver{}
+	d := newTestDispatcher(t, r)
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	// пятьдесят агентов превысили один порог
+	for i := 0; i < 50; i++ {
+		d.add(testAlert(string(rune('a'+i)), "host", entities.StateFiring), start.Add(time.Duration(i)*100*time.Millisecond))
+	}
+	// повторное срабатывание одного оповещения не создаёт новое уведомление
+	d.add(testAlert("a", "host", entities.StateFiring), start.Add(10*time.Second))
+
+	d.Flush(ctx, start.Add(29*time.Second))
+	assert.Empty(t, r.sent)
+
+	d.Flush(ctx, start.Add(30*time.Second))
+	require.Len(t, r.sent, 1)
+	assert.Len(t, r.sent[0].Alerts, 50)
+	assert.Equal(t, entities.StateFiring, r.sent[0].Status)
+	assert.Equal(t, map[string]string{"alertname": "HighCPU"}, r.sent[0].GroupLabels)
+
+	// изменения группы отправляются не чаще group_interval
+	d.add(testAlert("a", "host", entities.StateResolved), start.Add(time.Minute))
+	d.Flush(ctx, start.Add(2*time.Minute))
+	assert.Len(t, r.sent, 1)
+	d.Flush(ctx, start.Add(30*time.Second+5*time.Minute))
+	require.Len(t, r.sent, 2)
+	assert.Len(t, r.sent[1].Alerts, 50)
+
+	// неизменная группа повторяется через repeat_interval
+	d.Flush(ctx, start.Add(30*time.Second+30*time.Minute))
+	assert.Len(t, r.sent, 2)
+	d.Flush(ctx, start.Add(30*time.Second+65*time.Minute))
+	require.Len(t, r.sent, 3)
+	assert.Len(t, r.sent[2].Alerts, 49)
+}
+
+func TestDispatcher_ResolvedBeforeNotify(t *testing.T) {
+	ctx := context.Background()
+	r := &fakeReceiver{}
+	d := newTestDispatcher(t, r)
+	start := time.Now()
+
+	d.add(testAlert("a", "host", entities.StateFiring), start)
+	d.add(testAlert("a", "host", entities.StateResolved), start.Add(time.Second))
+	d.Flush(ctx, start.Add(time.Minute))
+
+	assert.Empty(t, r.sent)
+	assert.Empty(t, d.groups)
+}
+
+func TestDispatcher_Failures(t *testing.T) {
+	ctx := context.Background()
+	r := &fakeReceiver{err: errors.New("connection refused")}
+	d := newTestDispatcher(t, r)
+	start := time.Now()
+
+	d.add(testAlert("a", "host", entities.StateFiring), start)
+	d.Flush(ctx, start.Add(time.Minute))
+	assert.Equal(t, map[string]uint64{"test": 1}, d.Failures())
+
+	// повторная попытка через group_interval
+	r.err = nil
+	d.Flush(ctx, start.Add(2*time.Minute))
+	assert.Empty(t, r.sent)
+	d.Flush(ctx, start.Add(6*time.Minute))
+	assert.Len(t, r.sent, 1)
+}
@@ -0,0 +1,4 @@
+// Пакет notify содержит отправку уведомлений об оповещениях:
+// группировку и дедупликацию оповещений, интервалы повторной отправки
+// и каналы доставки уведомлений получателям.
+package notify
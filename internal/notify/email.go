@@ -0,0 +1,201 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pavlegich/metrics-alerting/internal/entities"
+)
+
+// Email содержит канал отправки уведомлений по электронной почте.
+type Email struct {
+	cfg     entities.EmailConfig
+	subject *template.Template
+	text    *template.Template
+	html    *template.Template
+}
+
+// NewEmail создаёт новый канал отправки уведомлений по SMTP
+// и разбирает шаблоны писем.
+func NewEmail(cfg entities.EmailConfig) (*Email, error) {
+	e := &Email{cfg: cfg}
+
+	var err error
+	if e.subject, err = parseTemplate("subject", cfg.Subject, entities.EmailSubjectTemplate); err != nil {
+		return nil, fmt.Errorf("NewEmail: %w", err)
+	}
+	if e.text, err = parseTemplate("text", cfg.Text, entities.EmailTextTemplate); err != nil {
+		return nil, fmt.Errorf("NewEmail: %w", err)
+	}
+	if e.html, err = parseTemplate("html", cfg.HTML, entities.EmailHTMLTemplate); err != nil {
+		return nil, fmt.Errorf("NewEmail: %w", err)
+	}
+
+	return e, nil
+}
+
+// Notify формирует письмо по шаблонам и отправляет его получателям.
+func (e *Email) Notify(ctx context.Context, n entities.Notification) error {
+	msg, err := e.message(n, time.Now())
+	if err != nil {
+		return fmt.Errorf("Notify: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	c, err := e.dial(ctx)
+	if err != nil {
+		return fmt.Errorf("Notify: %w", err)
+	}
+	defer c.Close()
+
+	if e.cfg.Username != "" {
+		host, _, _ := net.SplitHostPort(e.cfg.Smarthost)
+		if err := c.Auth(smtp.PlainAuth("", e.cfg.Username, e.cfg.Password, host)); err != nil {
+			return fmt.Errorf("Notify: smtp auth failed %w", err)
+		}
+	}
+	if err := c.Mail(e.cfg.From); err != nil {
+		return fmt.Errorf("Notify: smtp mail failed %w", err)
+	}
+	for _, to := range e.cfg.To {
+		if err := c.Rcpt(to); err != nil {
+			return fmt.Errorf("Notify: smtp rcpt %s failed %w", to, err)
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("Notify: smtp data failed %w", err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return fmt.Errorf("Notify: write message failed %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("Notify: smtp data failed %w", err)
+	}
+
+	return c.Quit()
+}
+
+// dial подключается к SMTP-серверу в соответствии с режимом TLS.
+func (e *Email) dial(ctx context.Context) (*smtp.Client, error) {
+	host, _, err := net.SplitHostPort(e.cfg.Smarthost)
+	if err != nil {
+		return nil, fmt.Errorf("dial: invalid smarthost %w", err)
+	}
+	tlsConfig := &tls.Config{ServerName: host}
+
+	var conn net.Conn
+	if e.cfg.TLS == entities.EmailTLS {
+		d := &tls.Dialer{Config: tlsConfig}
+		conn, err = d.DialContext(ctx, "tcp", e.cfg.Smarthost)
+	} else {
+		d := &net.Dialer{}
+		conn, err = d.DialContext(ctx, "tcp", e.cfg.Smarthost)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("dial: connect failed %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("dial: smtp handshake failed %w", err)
+	}
+
+	switch e.cfg.TLS {
+	case entities.EmailTLSStartTLS, "":
+		ok, _ := c.Extension("STARTTLS")
+		if !ok && e.cfg.TLS == "" {
+			break
+		}
+		if err := c.StartTLS(tlsConfig); err != nil {
+			c.Close()
+			return nil, fmt.Errorf("dial: starttls failed %w", err)
+		}
+	}
+
+	return c, nil
+}
+
+// message формирует письмо с текстовой и HTML частями.
+func (e *Email) message(n entities.Notification, now time.Time) ([]byte, error) {
+	subject, err := render(e.subject, n)
+	if err != nil {
+		return nil, fmt.Errorf("message: %w", err)
+	}
+	text, err := render(e.text, n)
+	if err != nil {
+		return nil, fmt.Errorf("message: %w", err)
+	}
+	html, err := render(e.html, n)
+	if err != nil {
+		return nil, fmt.Errorf("message: %w", err)
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, part := range []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=UTF-8", text},
+		{"text/html; charset=UTF-8", html},
+	} {
+		pw, err := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {part.contentType}})
+		if err != nil {
+			return nil, fmt.Errorf("message: create part failed %w", err)
+		}
+		pw.Write([]byte(part.content))
+	}
+	if err := mw.Close(); err != nil {
+		return nil, fmt.Errorf("message: close multipart failed %w", err)
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", e.cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(e.cfg.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", strings.TrimSpace(subject)))
+	fmt.Fprintf(&msg, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", mw.Boundary())
+	msg.Write(body.Bytes())
+
+	return msg.Bytes(), nil
+}
+
+// parseTemplate разбирает шаблон или шаблон по умолчанию при его отсутствии.
+func parseTemplate(name string, text string, def string) (*template.Template, error) {
+	if text == "" {
+		text = def
+	}
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parseTemplate: parse %s template failed %w", name, err)
+	}
+	return tmpl, nil
+}
+
+// render формирует текст по шаблону и данным уведомления.
+func render(tmpl *template.Template, n entities.Notification) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, n); err != nil {
+		return "", fmt.Errorf("render: execute %s template failed %w", tmpl.Name(), err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,154 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pavlegich/metrics-alerting/internal/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSMTP содержит SMTP-сервер для тестов, сохраняющий полученные письма.
+type fakeSMTP struct {
+	ln   net.Listener
+	mu   sync.Mutex
+	auth string
+	from string
+	to   []string
+	data string
+	fail bool
+}
+
+func newFakeSMTP(t *testing.T) *fakeSMTP {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &fakeSMTP{ln: ln}
+	go s.serve()
+	t.Cleanup(func() { ln.Close() })
+	return s
+}
+
+func (s *fakeSMTP) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeSMTP) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 localhost fake smtp")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+
+		s.mu.Lock()
+		switch cmd {
+		case "EHLO", "HELO":
+			reply("250-localhost")
+			reply("250 AUTH PLAIN")
+		case "AUTH":
+			creds, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(line, "AUTH PLAIN "))
+			s.auth = string(creds)
+			reply("235 authenticated")
+		case "MAIL":
+			s.from = line
+			if s.fail {
+				reply("550 rejected")
+			} else {
+				reply("250 ok")
+			}
+		case "RCPT":
+			s.to = append(s.to, line)
+			reply("250 ok")
+		case "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil || l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			s.data = data.String()
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			s.mu.Unlock()
+			return
+		default:
+			reply("250 ok")
+		}
+		s.mu.Unlock()
+	}
+}
+
+func TestEmail_Notify(t *testing.T) {
+	srv := newFakeSMTP(t)
+
+	e, err := NewEmail(entities.EmailConfig{
+		Smarthost: srv.ln.Addr().String(),
+		Username:  "alerts",
+		Password:  "secret",
+		TLS:       entities.EmailTLSNone,
+		From:      "alerts@example.com",
+		To:        []string{"oncall@example.com", "ops@example.com"},
+		Subject:   "{{.Status}}: {{range .Alerts}}{{.RuleName}} {{end}}",
+	})
+	require.NoError(t, err)
+
+	n := entities.Notification{
+		Receiver: "email",
+		Status:   entities.StateFiring,
+		Alerts: []entities.Alert{{
+			RuleName: "HighCPU",
+			State:    entities.StateFiring,
+			Value:    97.5,
+			Labels:   map[string]string{"host": "agent-1"},
+		}},
+	}
+	require.NoError(t, e.Notify(context.Background(), n))
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	assert.Equal(t, "\x00alerts\x00secret", srv.auth)
+	assert.Contains(t, srv.from, "<alerts@example.com>")
+	assert.Len(t, srv.to, 2)
+	assert.Contains(t, srv.data, "Subject: firing: HighCPU\r\n")
+	assert.Contains(t, srv.data, "Content-Type: text/plain; charset=UTF-8")
+	assert.Contains(t, srv.data, "value: 97.5")
+	assert.Contains(t, srv.data, "<td>HighCPU</td><td>97.5</td>")
+}
+
+func TestEmail_NotifyFailed(t *testing.T) {
+	srv := newFakeSMTP(t)
+	srv.fail = true
+
+	e, err := NewEmail(entities.EmailConfig{
+		Smarthost: srv.ln.Addr().String(),
+		From:      "alerts@example.com",
+		To:        []string{"oncall@example.com"},
+	})
+	require.NoError(t, err)
+	assert.Error(t, e.Notify(context.Background(), entities.Notification{}))
+
+	_, err = NewEmail(entities.EmailConfig{Text: "{{.Unclosed"})
+	assert.Error(t, err)
+}
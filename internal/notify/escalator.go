@@ -0,0 +1,375 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pavlegich/metrics-alerting/internal/entities"
+	"github.com/pavlegich/metrics-alerting/internal/infra/logger"
+	"github.com/pavlegich/metrics-alerting/internal/interfaces"
+	"go.uber.org/zap"
+)
+
+// escalationsKind содержит вид состояния для хранения эскалаций.
+const escalationsKind = "escalations"
+
+// Escalator уведомляет получателей шагов политик эскалации о срабатывающих
+// оповещениях, пока оповещение не подтверждено или не завершено.
+// Время шагов отсчитывается от срабатывания оповещения, состояние эскалаций
+// сохраняется в хранилище состояния и восстанавливается после перезапуска.
+// Эскалация приостанавливается, пока оповещение подавлено тишиной
+// или правилом подавления.
+type Escalator struct {
+	mu          *sync.Mutex
+	state       interfaces.StateStorage
+	externalURL string
+	policies    []entities.EscalationPolicy
+	matchers    []entities.LabelMatchers // разобранные условия политик эскалации
+	receivers   map[string]Receiver
+	escalations map[string]*entities.Escalation
+	failures    map[string]uint64
+	restored    map[string]struct{} // эскалации, восстановленные и ещё не сверенные с оповещениями
+}
+
+// job содержит уведомление, которое требуется отправить получателям.
+type job struct {
+	receivers []string
+	n         entities.Notification
+}
+
+// NewEscalator создаёт новый обработчик эскалаций. Хранилище состояния
+// может отсутствовать, тогда эскалации хранятся только в памяти.
+func NewEscalator(ctx context.Context, state interfaces.StateStorage) *Escalator {
+	return &Escalator{
+		mu:          &sync.Mutex{},
+		state:       state,
+		receivers:   make(map[string]Receiver),
+		escalations: make(map[string]*entities.Escalation),
+		failures:    make(map[string]uint64),
+	}
+}
+
+// Load загружает эскалации из хранилища состояния.
+func (e *Escalator) Load(ctx context.Context) error {
+	if e.state == nil {
+		return nil
+	}
+
+	data, err := e.state.LoadState(ctx, escalationsKind)
+	if err != nil {
+		return fmt.Errorf("Load: load escalations failed %w", err)
+	}
+	if len(data) == 0 {
+		return nil
+	}
+
+	escalations := make(map[string]*entities.Escalation)
+	if err := json.Unmarshal(data, &escalations); err != nil {
+		return fmt.Errorf("Load: escalations unmarshal %w", err)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.escalations = escalations
+	e.restored = make(map[string]struct{}, len(escalations))
+	for fp := range escalations {
+		e.restored[fp] = struct{}{}
+	}
+	return nil
+}
+
+// Configure атомарно заменяет адрес веб-интерфейса сервера, политики эскалации
+// и получателей уведомлений. Начатые эскалации сохраняются.
+func (e *Escalator) Configure(ctx context.Context, externalURL string, policies []entities.EscalationPolicy,
+	receivers []entities.Receiver) error {
+	built := make(map[string]Receiver, len(receivers))
+	for _, cfg := range receivers {
+		r, err := NewReceiver(cfg)
+		if err != nil {
+			return fmt.Errorf("Configure: %w", err)
+		}
+		built[cfg.Name] = r
+	}
+	matchers := make([]entities.LabelMatchers, 0, len(policies))
+	for _, p := range policies {
+		for _, step := range p.Steps {
+			if _, ok := built[step.Receiver]; !ok {
+				return fmt.Errorf("Configure: policy %q: unknown receiver %q", p.Name, step.Receiver)
+			}
+		}
+		m, err := entities.CompileMatchers(p.Matchers)
+		if err != nil {
+			return fmt.Errorf("Configure: policy %q: %w", p.Name, err)
+		}
+		matchers = append(matchers, m)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.externalURL = externalURL
+	e.policies = append([]entities.EscalationPolicy(nil), policies...)
+	e.matchers = matchers
+	e.receivers = built
+	return nil
+}
+
+// Add принимает изменение состояния или подавления оповещения, включая
+// подавленные оповещения. Срабатывающее оповещение, подходящее под политику
+// эскалации, начинает эскалацию, а завершение оповещения её останавливает.
+func (e *Escalator) Add(ctx context.Context, alert entities.Alert) {
+	e.add(ctx, alert, time.Now())
+}
+
+// Sync сверяет восстановленные после перезапуска эскалации с текущими
+// оповещениями после каждой оценки правил. Эскалация срабатывающего оповещения
+// считается сверенной. Эскалация ожидающего оповещения сохраняется до
+// следующей оценки: после перезапуска оповещение правила с периодом for
+// снова проходит ожидание. Эскалации завершённых оповещений и оповещений
+// удалённых правил удаляются, иначе они продолжались бы бесконечно.
+func (e *Escalator) Sync(ctx context.Context, alerts []entities.Alert) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if len(e.restored) == 0 {
+		return
+	}
+
+	states := make(map[string]entities.AlertState, len(alerts))
+	for _, a := range alerts {
+		states[a.Fingerprint] = a.State
+	}
+	changed := false
+	for fp := range e.restored {
+		switch states[fp] {
+		case entities.StateFiring:
+			delete(e.restored, fp)
+		case entities.StatePending:
+		default:
+			delete(e.restored, fp)
+			if _, ok := e.escalations[fp]; ok {
+				delete(e.escalations, fp)
+				changed = true
+			}
+		}
+	}
+	if changed {
+		e.save(ctx)
+	}
+}
+
+// Ack останавливает эскалацию подтверждённого оповещения.
+func (e *Escalator) Ack(ctx context.Context, alert entities.Alert) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	esc, ok := e.escalations[alert.Fingerprint]
+	if !ok {
+		return
+	}
+	esc.Alert.AckedBy = alert.AckedBy
+	esc.Alert.AckedAt = alert.AckedAt
+	e.save(ctx)
+}
+
+// Escalations возвращает текущие эскалации по отпечаткам оповещений.
+func (e *Escalator) Escalations() map[string]entities.Escalation {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	res := make(map[string]entities.Escalation, len(e.escalations))
+	for fp, esc := range e.escalations {
+		c := *esc
+		c.Notified = append([]string(nil), esc.Notified...)
+		res[fp] = c
+	}
+	return res
+}
+
+// Failures возвращает количество ошибок отправки уведомлений по получателям.
+func (e *Escalator) Failures() map[string]uint64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	failures := make(map[string]uint64, len(e.failures))
+	for k, v := range e.failures {
+		failures[k] = v
+	}
+	return failures
+}
+
+// Run выполняет наступившие шаги эскалаций с указанным интервалом проверки.
+func (e *Escalator) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			e.Escalate(ctx, now)
+		}
+	}
+}
+
+// Escalate уведомляет получателей наступивших шагов неподтверждённых
+// и неподавленных эскалаций, а о завершении оповещения уведомляет всех
+// ранее уведомлённых получателей.
+// Шаг считается выполненным и при ошибке отправки, чтобы эскалация
+// не задерживалась на недоступном получателе.
+func (e *Escalator) Escalate(ctx context.Context, now time.Time) {
+	e.mu.Lock()
+	jobs := make([]job, 0)
+	changed := false
+	for fp, esc := range e.escalations {
+		policy, ok := e.policy(esc.Policy)
+		if !ok || esc.Alert.State == entities.StateResolved {
+			if esc.Alert.State == entities.StateResolved && len(esc.Notified) > 0 {
+				jobs = append(jobs, job{receivers: esc.Notified, n: e.notification(esc)})
+			}
+			delete(e.escalations, fp)
+			changed = true
+			continue
+		}
+		if esc.Alert.AckedBy != "" || esc.Alert.Suppressed() {
+			continue
+		}
+
+		due := make([]string, 0)
+		for esc.NextStep < len(policy.Steps) &&
+			!now.Before(esc.StartedAt.Add(time.Duration(policy.Steps[esc.NextStep].After))) {
+			receiver := policy.Steps[esc.NextStep].Receiver
+			esc.NextStep++
+			changed = true
+			if contains(esc.Notified, receiver) {
+				continue
+			}
+			esc.Notified = append(esc.Notified, receiver)
+			due = append(due, receiver)
+		}
+		if len(due) > 0 {
+			jobs = append(jobs, job{receivers: due, n: e.notification(esc)})
+		}
+	}
+	if changed {
+		e.save(ctx)
+	}
+	receivers := e.receivers
+	e.mu.Unlock()
+
+	for _, j := range jobs {
+		for _, name := range j.receivers {
+			r, ok := receivers[name]
+			if !ok {
+				continue
+			}
+			n := j.n
+			n.Receiver = name
+			if err := r.Notify(ctx, n); err != nil {
+				e.mu.Lock()
+				e.failures[name]++
+				e.mu.Unlock()
+
+				logger.Log.Error("Escalate: send notification failed",
+					zap.String("receiver", name),
+					zap.String("alert", n.GroupKey),
+					zap.Error(err))
+			}
+		}
+	}
+}
+
+// add обрабатывает изменение состояния оповещения на указанный момент времени.
+func (e *Escalator) add(ctx context.Context, alert entities.Alert, now time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	esc, ok := e.escalations[alert.Fingerprint]
+	switch alert.State {
+	case entities.StateFiring:
+		if ok {
+			// эскалация восстановлена после перезапуска, оповещение изменилось
+			// или изменилось его подавление
+			acked := esc.Alert
+			esc.Alert = alert
+			if alert.AckedBy == "" {
+				esc.Alert.AckedBy, esc.Alert.AckedAt = acked.AckedBy, acked.AckedAt
+			}
+			return
+		}
+		started := alert.FiredAt
+		if started.IsZero() {
+			started = now
+		}
+		for i, p := range e.policies {
+			if e.matchers[i].Match(alert.Labels) {
+				e.escalations[alert.Fingerprint] = &entities.Escalation{
+					Policy:    p.Name,
+					Alert:     alert,
+					StartedAt: started,
+				}
+				e.save(ctx)
+				return
+			}
+		}
+	case entities.StateResolved:
+		if !ok {
+			return
+		}
+		esc.Alert = alert
+		e.save(ctx)
+	}
+}
+
+// policy возвращает политику эскалации по названию. Вызывается под блокировкой.
+func (e *Escalator) policy(name string) (entities.EscalationPolicy, bool) {
+	for _, p := range e.policies {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return entities.EscalationPolicy{}, false
+}
+
+// notification формирует уведомление об оповещении эскалации.
+// Вызывается под блокировкой.
+func (e *Escalator) notification(esc *entities.Escalation) entities.Notification {
+	return entities.Notification{
+		Status:      esc.Alert.State,
+		GroupKey:    esc.Alert.Fingerprint,
+		GroupLabels: esc.Alert.Labels,
+		Alerts:      []entities.Alert{esc.Alert},
+		ExternalURL: e.externalURL,
+	}
+}
+
+// save сохраняет эскалации в хранилище состояния. Вызывается под блокировкой.
+func (e *Escalator) save(ctx context.Context) {
+	if e.state == nil {
+		return
+	}
+
+	data, err := json.Marshal(e.escalations)
+	if err != nil {
+		logger.Log.Error("save: escalations marshal failed", zap.Error(err))
+		return
+	}
+	if err := e.state.SaveState(ctx, escalationsKind, data); err != nil {
+		logger.Log.Error("save: save escalations failed", zap.Error(err))
+	}
+}
+
+// contains проверяет наличие строки в списке.
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
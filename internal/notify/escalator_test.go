@@ -0,0 +1,254 @@
+package notify
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pavlegich/metrics-alerting/internal/alerting"
+	"github.com/pavlegich/metrics-alerting/internal/entities"
+	"github.com/pavlegich/metrics-alerting/internal/interfaces"
+	"github.com/pavlegich/metrics-alerting/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestEscalator(t *testing.T, state interfaces.StateStorage, receivers map[string]*fakeReceiver) *Escalator {
+	ctx := context.Background()
+	e := NewEscalator(ctx, state)
+	require.NoError(t, e.Load(ctx))
+
+	cfgs := make([]entities.Receiver, 0, len(receivers))
+	for name := range receivers {
+		cfgs = append(cfgs, entities.Receiver{Name: name, Webhook: &entities.WebhookConfig{URL: "http://localhost"}})
+	}
+	require.NoError(t, e.Configure(ctx, "", []entities.EscalationPolicy{{
+		Name:     "oncall",
+		Matchers: []entities.Matcher{{Name: "alertname", Value: "HighCPU"}},
+		Steps: []entities.EscalationStep{
+			{Receiver: "a"},
+			{Receiver: "b", After: entities.Duration(10 * time.Minute)},
+			{Receiver: "c", After: entities.Duration(30 * time.Minute)},
+		},
+	}}, cfgs))
+	for name, r := range receivers {
+		e.receivers[name] = r
+	}
+	return e
+}
+
+func TestEscalator_Escalate(t *testing.T) {
+	ctx := context.Background()
+	state := storage.NewFile(filepath.Join(t.TempDir(), "metrics.json"), 0)
+	receivers := map[string]*fakeReceiver{"a": {}, "b": {}, "c": {}}
+	e := newTestEscalator(t, state, receivers)
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	alert := testAlert("fp", "host", entities.StateFiring)
+	alert.FiredAt = start
+	e.add(ctx, alert, start)
+	// оповещения без подходящей политики не эскалируются
+	other := testAlert("other", "host", entities.StateFiring)
+	other.Labels["alertname"] = "HighMemory"
+	e.add(ctx, other, start)
+	require.Len(t, e.Escalations(), 1)
+
+	e.Escalate(ctx, start)
+	assert.Len(t, receivers["a"].sent, 1)
+	assert.Empty(t, receivers["b"].sent)
+
+	e.Escalate(ctx, start.Add(9*time.Minute))
+	assert.Len(t, receivers["a"].sent, 1)
+	assert.Empty(t, receivers["b"].sent)
+
+	e.Escalate(ctx, start.Add(10*time.Minute))
+	assert.Len(t, receivers["b"].sent, 1)
+	assert.Equal(t, "b", receivers["b"].sent[0].Receiver)
+
+	// таймеры эскалации восстанавливаются после перезапуска
+	restored := newTestEscalator(t, state, receivers)
+	esc := restored.Escalations()["fp"]
+	assert.Equal(t, 2, esc.NextStep)
+	assert.Equal(t, []string{"a", "b"}, esc.Notified)
+
+	restored.Escalate(ctx, start.Add(30*time.Minute))
+	assert.Len(t, receivers["a"].sent, 1)
+	assert.Len(t, receivers["b"].sent, 1)
+	require.Len(t, receivers["c"].sent, 1)
+
+	// о завершении оповещения уведомляются все уведомлённые получатели
+	restored.add(ctx, testAlert("fp", "host", entities.StateResolved), start.Add(40*time.Minute))
+	restored.Escalate(ctx, start.Add(40*time.Minute))
+	for name, r := range receivers {
+		require.Len(t, r.sent, 2, name)
+		assert.Equal(t, entities.StateResolved, r.sent[1].Status)
+	}
+	assert.Empty(t, restored.Escalations())
+}
+
+func TestEscalator_Ack(t *testing.T) {
+	ctx := context.Background()
+	receivers := map[string]*fakeReceiver{"a": {}, "b": {}, "c": {}}
+	e := newTestEscalator(t, nil, receivers)
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	alert := testAlert("fp", "host", entities.StateFiring)
+	alert.FiredAt = start
+	e.add(ctx, alert, start)
+	e.Escalate(ctx, start)
+	require.Len(t, receivers["a"].sent, 1)
+
+	alert.AckedBy = "ops"
+	alert.AckedAt = start.Add(time.Minute)
+	e.Ack(ctx, alert)
+	// повторное срабатывание не сбрасывает подтверждение
+	alert.AckedBy = ""
+	e.add(ctx, alert, start.Add(2*time.Minute))
+
+	e.Escalate(ctx, start.Add(time.Hour))
+	assert.Empty(t, receivers["b"].sent)
+	assert.Empty(t, receivers["c"].sent)
+	assert.Equal(t, "ops", e.Escalations()["fp"].Alert.AckedBy)
+}
+
+func TestEscalator_Suppressed(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		resolved bool
+		want     map[string]int
+	}{
+		// эскалация приостанавливается на время тишины и продолжается после неё
+		{name: "paused", want: map[string]int{"a": 1, "b": 1, "c": 0}},
+		// завершение подавленного оповещения останавливает эскалацию
+		{name: "resolved", resolved: true, want: map[string]int{"a": 2, "b": 0, "c": 0}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			receivers := map[string]*fakeReceiver{"a": {}, "b": {}, "c": {}}
+			e := newTestEscalator(t, nil, receivers)
+
+			alert := testAlert("fp", "host", entities.StateFiring)
+			alert.FiredAt = start
+			e.add(ctx, alert, start)
+			e.Escalate(ctx, start)
+			require.Len(t, receivers["a"].sent, 1)
+
+			alert.SilencedBy = []string{"silence"}
+			e.add(ctx, alert, start.Add(time.Minute))
+			e.Escalate(ctx, start.Add(15*time.Minute))
+			assert.Empty(t, receivers["b"].sent)
+
+			if tc.resolved {
+				resolved := testAlert("fp", "host", entities.StateResolved)
+				resolved.SilencedBy = []string{"silence"}
+				e.add(ctx, resolved, start.Add(20*time.Minute))
+			} else {
+				alert.SilencedBy = nil
+				e.add(ctx, alert, start.Add(20*time.Minute))
+			}
+			e.Escalate(ctx, start.Add(20*time.Minute))
+			for name, n := range tc.want {
+				assert.Len(t, receivers[name].sent, n, name)
+			}
+			assert.Equal(t, !tc.resolved, len(e.Escalations()) == 1)
+		})
+	}
+}
+
+func TestEscalator_Sync(t *testing.T) {
+	ctx := context.Background()
+	state := storage.NewFile(filepath.Join(t.TempDir(), "metrics.json"), 0)
+	receivers := map[string]*fakeReceiver{"a": {}, "b": {}, "c": {}}
+	e := newTestEscalator(t, state, receivers)
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	for _, fp := range []string{"firing", "gone", "pending"} {
+		alert := testAlert(fp, "host", entities.StateFiring)
+		alert.FiredAt = start
+		e.add(ctx, alert, start)
+	}
+	require.Len(t, e.Escalations(), 3)
+
+	// после перезапуска удаляются эскалации оповещений, которые не срабатывают
+	// и не ожидают срабатывания
+	restored := newTestEscalator(t, state, receivers)
+	restored.Sync(ctx, []entities.Alert{
+		testAlert("firing", "host", entities.StateFiring),
+		testAlert("pending", "host", entities.StatePending),
+	})
+	escalations := restored.Escalations()
+	assert.Len(t, escalations, 2)
+	assert.Contains(t, escalations, "firing")
+	assert.Contains(t, escalations, "pending")
+
+	// эскалация ожидающего оповещения удаляется, если оно не сработало,
+	// а сверенная эскалация срабатывающего оповещения сохраняется
+	restored.Sync(ctx, nil)
+	escalations = restored.Escalations()
+	assert.Len(t, escalations, 1)
+	assert.Contains(t, escalations, "firing")
+	assert.Len(t, newTestEscalator(t, state, receivers).Escalations(), 1)
+}
+
+func TestEscalator_SyncRestart(t *testing.T) {
+	ctx := context.Background()
+	state := storage.NewFile(filepath.Join(t.TempDir(), "metrics.json"), 0)
+	ms := storage.NewMemStorage(ctx)
+	receivers := map[string]*fakeReceiver{"a": {}, "b": {}, "c": {}}
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	rule := entities.Rule{
+		ID:         "cpu",
+		Name:       "HighCPU",
+		MetricType: "gauge",
+		MetricName: "CPUutilization1",
+		Operator:   ">",
+		Threshold:  90,
+		For:        entities.Duration(time.Minute),
+	}
+
+	run := func() (*alerting.Engine, *Escalator) {
+		engine := alerting.NewEngine(ctx, ms, nil)
+		_, err := engine.CreateRule(ctx, rule)
+		require.NoError(t, err)
+		e := newTestEscalator(t, state, receivers)
+		engine.AddListener(e.Add)
+		engine.AddEvaluateListener(e.Sync)
+		return engine, e
+	}
+
+	require.NoError(t, ms.Put(ctx, "gauge", "CPUutilization1", "95"))
+	engine, e := run()
+	engine.Evaluate(ctx, start)
+	engine.Evaluate(ctx, start.Add(time.Minute))
+	require.Len(t, e.Escalations(), 1)
+	e.Escalate(ctx, start.Add(time.Minute))
+	require.Len(t, receivers["a"].sent, 1)
+
+	// после перезапуска оповещение правила с периодом for снова ожидает
+	// срабатывания, а его эскалация сохраняется
+	engine, e = run()
+	engine.Evaluate(ctx, start.Add(2*time.Minute))
+	require.Len(t, engine.Alerts(ctx), 1)
+	assert.Equal(t, entities.StatePending, engine.Alerts(ctx)[0].State)
+	require.Len(t, e.Escalations(), 1)
+
+	engine.Evaluate(ctx, start.Add(3*time.Minute))
+	escalations := e.Escalations()
+	require.Len(t, escalations, 1)
+	for _, esc := range escalations {
+		assert.Equal(t, entities.StateFiring, esc.Alert.State)
+		assert.Equal(t, []string{"a"}, esc.Notified)
+	}
+	e.Escalate(ctx, start.Add(3*time.Minute))
+	assert.Len(t, receivers["a"].sent, 1)
+
+	// после перезапуска эскалация удаляется, если оповещение завершилось
+	require.NoError(t, ms.Put(ctx, "gauge", "CPUutilization1", "20"))
+	engine, e = run()
+	engine.Evaluate(ctx, start.Add(4*time.Minute))
+	assert.Empty(t, e.Escalations())
+}
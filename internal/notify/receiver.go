@@ -0,0 +1,29 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pavlegich/metrics-alerting/internal/entities"
+)
+
+// Receiver содержит канал доставки уведомлений получателю.
+type Receiver interface {
+	Notify(ctx context.Context, n entities.Notification) error
+}
+
+// NewReceiver создаёт канал доставки уведомлений по настройкам получателя.
+func NewReceiver(cfg entities.Receiver) (Receiver, error) {
+	switch {
+	case cfg.Webhook != nil:
+		return NewWebhook(*cfg.Webhook), nil
+	case cfg.Email != nil:
+		return NewEmail(*cfg.Email)
+	case cfg.Slack != nil:
+		return NewSlack(*cfg.Slack), nil
+	case cfg.Telegram != nil:
+		return NewTelegram(*cfg.Telegram), nil
+	default:
+		return nil, fmt.Errorf("NewReceiver: receiver %q has no channel", cfg.Name)
+	}
+}
@@ -0,0 +1,97 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pavlegich/metrics-alerting/internal/entities"
+)
+
+type (
+	// slackMessage содержит сообщение входящего вебхука Slack.
+	slackMessage struct {
+		Channel     string            `json:"channel,omitempty"`
+		Username    string            `json:"username,omitempty"`
+		Text        string            `json:"text"`
+		Attachments []slackAttachment `json:"attachments"`
+	}
+
+	// slackAttachment содержит вложение сообщения Slack с описанием оповещения.
+	slackAttachment struct {
+		Color     string       `json:"color"`
+		Title     string       `json:"title"`
+		TitleLink string       `json:"title_link,omitempty"`
+		Text      string       `json:"text"`
+		Fields    []slackField `json:"fields"`
+		Ts        int64        `json:"ts,omitempty"`
+	}
+
+	// slackField содержит поле вложения сообщения Slack.
+	slackField struct {
+		Title string `json:"title"`
+		Value string `json:"value"`
+		Short bool   `json:"short"`
+	}
+)
+
+// Slack содержит канал отправки уведомлений во входящий вебхук
+// в формате Slack, совместимом с Mattermost.
+type Slack struct {
+	cfg    entities.SlackConfig
+	client *http.Client
+}
+
+// NewSlack создаёт новый канал отправки уведомлений в формате Slack.
+func NewSlack(cfg entities.SlackConfig) *Slack {
+	return &Slack{
+		cfg:    cfg,
+		client: &http.Client{Timeout: sendTimeout},
+	}
+}
+
+// Notify отправляет уведомление с отдельным вложением для каждого оповещения.
+func (s *Slack) Notify(ctx context.Context, n entities.Notification) error {
+	body, err := json.Marshal(s.message(n))
+	if err != nil {
+		return fmt.Errorf("Notify: message marshal %w", err)
+	}
+	return postJSON(ctx, s.client, s.cfg.URL, body)
+}
+
+// message формирует сообщение Slack по уведомлению.
+func (s *Slack) message(n entities.Notification) slackMessage {
+	msg := slackMessage{
+		Channel:     s.cfg.Channel,
+		Username:    s.cfg.Username,
+		Text:        fmt.Sprintf("%s: %d alert(s)", strings.ToUpper(string(n.Status)), len(n.Alerts)),
+		Attachments: make([]slackAttachment, 0, len(n.Alerts)),
+	}
+	if n.ExternalURL != "" {
+		msg.Text += fmt.Sprintf(" <%s|open>", n.ExternalURL)
+	}
+
+	for _, a := range n.Alerts {
+		att := slackAttachment{
+			Color:     severityColor(a),
+			Title:     alertTitle(a),
+			TitleLink: alertURL(n.ExternalURL, a),
+			Text:      strings.Join(sortedLabels(a.Labels), ", "),
+			Fields: []slackField{
+				{Title: "Value", Value: strconv.FormatFloat(a.Value, 'g', -1, 64), Short: true},
+				{Title: "Metric", Value: a.Labels["metric"], Short: true},
+			},
+		}
+		if a.Message != "" {
+			att.Text = a.Message + "\n" + att.Text
+		}
+		if !a.FiredAt.IsZero() {
+			att.Ts = a.FiredAt.Unix()
+		}
+		msg.Attachments = append(msg.Attachments, att)
+	}
+	return msg
+}
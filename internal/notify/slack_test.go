@@ -0,0 +1,57 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pavlegich/metrics-alerting/internal/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlack_Notify(t *testing.T) {
+	var got slackMessage
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	fired := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	n := entities.Notification{
+		Status:      entities.StateFiring,
+		ExternalURL: "http://metrics.local:8080/",
+		Alerts: []entities.Alert{
+			{
+				RuleName: "HighCPU",
+				State:    entities.StateFiring,
+				Value:    97.5,
+				FiredAt:  fired,
+				Labels:   map[string]string{"metric": "CPUutilization1", "severity": "critical"},
+			},
+			{
+				RuleName: "HighHeap",
+				State:    entities.StateResolved,
+				Value:    10,
+				Labels:   map[string]string{"metric": "HeapAlloc", "severity": "warning"},
+			},
+		},
+	}
+
+	s := NewSlack(entities.SlackConfig{URL: ts.URL, Channel: "#ops"})
+	require.NoError(t, s.Notify(context.Background(), n))
+
+	assert.Equal(t, "#ops", got.Channel)
+	assert.Equal(t, "FIRING: 2 alert(s) <http://metrics.local:8080/|open>", got.Text)
+	require.Len(t, got.Attachments, 2)
+	assert.Equal(t, "#E01E5A", got.Attachments[0].Color)
+	assert.Equal(t, "[FIRING] HighCPU", got.Attachments[0].Title)
+	assert.Equal(t, "http://metrics.local:8080/#CPUutilization1", got.Attachments[0].TitleLink)
+	assert.Equal(t, "97.5", got.Attachments[0].Fields[0].Value)
+	assert.Equal(t, fired.Unix(), got.Attachments[0].Ts)
+	assert.Equal(t, "#2EB886", got.Attachments[1].Color)
+}
@@ -0,0 +1,121 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/pavlegich/metrics-alerting/internal/entities"
+)
+
+// defaultTelegramAPI содержит адрес Telegram Bot API по умолчанию.
+const defaultTelegramAPI = "https://api.telegram.org"
+
+type (
+	// telegramMessage содержит запрос метода sendMessage Telegram Bot API.
+	telegramMessage struct {
+		ChatID                string `json:"chat_id"`
+		Text                  string `json:"text"`
+		ParseMode             string `json:"parse_mode"`
+		DisableWebPagePreview bool   `json:"disable_web_page_preview"`
+	}
+
+	// telegramResponse содержит ответ Telegram Bot API.
+	telegramResponse struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+)
+
+// Telegram содержит канал отправки уведомлений через Telegram Bot API.
+type Telegram struct {
+	cfg    entities.TelegramConfig
+	client *http.Client
+}
+
+// NewTelegram создаёт новый канал отправки уведомлений через Telegram Bot API.
+func NewTelegram(cfg entities.TelegramConfig) *Telegram {
+	if cfg.APIURL == "" {
+		cfg.APIURL = defaultTelegramAPI
+	}
+	return &Telegram{
+		cfg:    cfg,
+		client: &http.Client{Timeout: sendTimeout},
+	}
+}
+
+// Notify отправляет уведомление сообщением с HTML разметкой.
+func (t *Telegram) Notify(ctx context.Context, n entities.Notification) error {
+	body, err := json.Marshal(telegramMessage{
+		ChatID:                t.cfg.ChatID,
+		Text:                  t.text(n),
+		ParseMode:             "HTML",
+		DisableWebPagePreview: true,
+	})
+	if err != nil {
+		return fmt.Errorf("Notify: message marshal %w", err)
+	}
+
+	endpoint := strings.TrimSuffix(t.cfg.APIURL, "/") + "/bot" + t.cfg.BotToken + "/sendMessage"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("Notify: new request failed %w", redactURL(err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Notify: send request failed %w", redactURL(err))
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("Notify: read response failed %w", err)
+	}
+	var tr telegramResponse
+	if err := json.Unmarshal(data, &tr); err != nil || !tr.OK {
+		return fmt.Errorf("Notify: telegram api error, status %d: %s", resp.StatusCode, tr.Description)
+	}
+	return nil
+}
+
+// redactURL убирает из ошибки HTTP клиента адрес запроса, так как он содержит
+// токен бота, а ошибки отправки записываются в журнал.
+func redactURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return fmt.Errorf("%s: %w", urlErr.Op, urlErr.Err)
+	}
+	return err
+}
+
+// text формирует текст сообщения с HTML разметкой по уведомлению.
+func (t *Telegram) text(n entities.Notification) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<b>%s</b>: %d alert(s)\n", strings.ToUpper(string(n.Status)), len(n.Alerts))
+
+	for _, a := range n.Alerts {
+		title := html.EscapeString(alertTitle(a))
+		if link := alertURL(n.ExternalURL, a); link != "" {
+			title = fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(link), title)
+		}
+		fmt.Fprintf(&b, "\n%s %s\nValue: <code>%s</code>\n", severityEmoji(a), title,
+			strconv.FormatFloat(a.Value, 'g', -1, 64))
+		if a.Message != "" {
+			fmt.Fprintf(&b, "%s\n", html.EscapeString(a.Message))
+		}
+		for _, l := range sortedLabels(a.Labels) {
+			fmt.Fprintf(&b, "%s\n", html.EscapeString(l))
+		}
+	}
+	return b.String()
+}
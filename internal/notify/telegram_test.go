@@ -0,0 +1,80 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pavlegich/metrics-alerting/internal/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTelegram_Notify(t *testing.T) {
+	var got telegramMessage
+	var path string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		if got.ChatID == "unknown" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"ok":false,"description":"Bad Request: chat not found"}`))
+			return
+		}
+		w.Write([]byte(`{"ok":true,"result":{}}`))
+	}))
+	defer ts.Close()
+
+	n := entities.Notification{
+		Status:      entities.StateFiring,
+		ExternalURL: "http://metrics.local:8080",
+		Alerts: []entities.Alert{{
+			RuleName: "Heap<Limit>",
+			State:    entities.StateFiring,
+			Value:    1.5e9,
+			Labels:   map[string]string{"metric": "HeapAlloc", "severity": "warning"},
+		}},
+	}
+
+	tg := NewTelegram(entities.TelegramConfig{APIURL: ts.URL, BotToken: "123:abc", ChatID: "-100"})
+	require.NoError(t, tg.Notify(context.Background(), n))
+
+	assert.Equal(t, "/bot123:abc/sendMessage", path)
+	assert.Equal(t, "-100", got.ChatID)
+	assert.Equal(t, "HTML", got.ParseMode)
+	assert.Contains(t, got.Text, "🟠")
+	assert.Contains(t, got.Text, `<a href="http://metrics.local:8080/#HeapAlloc">[FIRING] Heap&lt;Limit&gt;</a>`)
+	assert.Contains(t, got.Text, "Value: <code>1.5e+09</code>")
+
+	tg = NewTelegram(entities.TelegramConfig{APIURL: ts.URL, BotToken: "123:abc", ChatID: "unknown"})
+	err := tg.Notify(context.Background(), n)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "chat not found")
+}
+
+func TestTelegram_NotifyErrorHidesToken(t *testing.T) {
+	const token = "123456:SECRET-token"
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	closedURL := ts.URL
+	ts.Close()
+
+	tests := []struct {
+		name   string
+		apiURL string
+	}{
+		{name: "connection_refused", apiURL: closedURL},
+		{name: "invalid_url", apiURL: "http://bad host"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tg := NewTelegram(entities.TelegramConfig{APIURL: tc.apiURL, BotToken: token, ChatID: "-100"})
+			err := tg.Notify(context.Background(), entities.Notification{Status: entities.StateFiring})
+			require.Error(t, err)
+			assert.NotContains(t, err.Error(), token)
+			assert.NotContains(t, err.Error(), "SECRET")
+		})
+	}
+}
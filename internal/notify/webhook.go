@@ -0,0 +1,59 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pavlegich/metrics-alerting/internal/entities"
+)
+
+// sendTimeout содержит максимальное время отправки уведомления.
+const sendTimeout = 10 * time.Second
+
+// Webhook содержит канал отправки уведомлений в формате JSON по HTTP.
+type Webhook struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhook создаёт новый канал отправки уведомлений по HTTP.
+func NewWebhook(cfg entities.WebhookConfig) *Webhook {
+	return &Webhook{
+		url:    cfg.URL,
+		client: &http.Client{Timeout: sendTimeout},
+	}
+}
+
+// Notify отправляет уведомление POST-запросом с телом в формате JSON.
+func (w *Webhook) Notify(ctx context.Context, n entities.Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return fmt.Errorf("Notify: notification marshal %w", err)
+	}
+	return postJSON(ctx, w.client, w.url, body)
+}
+
+// postJSON отправляет POST-запрос с телом в формате JSON
+// и проверяет код ответа получателя.
+func postJSON(ctx context.Context, client *http.Client, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("postJSON: new request failed %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("postJSON: send request failed %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("postJSON: unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pavlegich/metrics-alerting/internal/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhook_Notify(t *testing.T) {
+	var got entities.Notification
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	n := entities.Notification{
+		Receiver: "ops",
+		Status:   entities.StateFiring,
+		GroupKey: `{alertname="HighCPU"}`,
+		Alerts:   []entities.Alert{testAlert("a", "host", entities.StateFiring)},
+	}
+	w := NewWebhook(entities.WebhookConfig{URL: ts.URL})
+	require.NoError(t, w.Notify(context.Background(), n))
+	assert.Equal(t, n.GroupKey, got.GroupKey)
+	assert.Len(t, got.Alerts, 1)
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer failing.Close()
+
+	w = NewWebhook(entities.WebhookConfig{URL: failing.URL})
+	assert.Error(t, w.Notify(context.Background(), n))
+}
@@ -0,0 +1,40 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+
+	"github.com/pavlegich/metrics-alerting/internal/alerting"
+	pb "github.com/pavlegich/metrics-alerting/internal/proto"
+	utils "github.com/pavlegich/metrics-alerting/internal/utils/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Acknowledge подтверждает срабатывающее оповещение и останавливает его эскалацию.
+func (c *Controller) Acknowledge(ctx context.Context, in *pb.AcknowledgeRequest) (*pb.AlertResponse, error) {
+	if c.Alerting == nil {
+		return nil, status.Error(codes.Unimplemented, "Acknowledge: alerting is not used")
+	}
+
+	alert, err := c.Alerting.Acknowledge(ctx, in.Fingerprint, in.By)
+	if err != nil {
+		return nil, status.Errorf(alertErrorCode(err), "Acknowledge: acknowledge alert failed %s", err)
+	}
+
+	return &pb.AlertResponse{Alert: utils.ConvertFromAlertToGRPC(alert)}, nil
+}
+
+// alertErrorCode возвращает код ответа для ошибки работы с оповещениями.
+func alertErrorCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, alerting.ErrAlertNotFound):
+		return codes.NotFound
+	case errors.Is(err, alerting.ErrAlertNotFiring):
+		return codes.FailedPrecondition
+	case errors.Is(err, alerting.ErrInvalidAck):
+		return codes.InvalidArgument
+	default:
+		return codes.Internal
+	}
+}
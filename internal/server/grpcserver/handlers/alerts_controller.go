@@ -0,0 +1,111 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+
+	"github.com/pavlegich/metrics-alerting/internal/alerting"
+	pb "github.com/pavlegich/metrics-alerting/internal/proto"
+	utils "github.com/pavlegich/metrics-alerting/internal/utils/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// ListRules возвращает список правил оповещений.
+func (c *Controller) ListRules(ctx context.Context, _ *emptypb.Empty) (*pb.ListRulesResponse, error) {
+	if c.Alerting == nil {
+		return nil, status.Error(codes.Unimplemented, "ListRules: alerting is not used")
+	}
+
+	rules := c.Alerting.ListRules(ctx)
+	resp := &pb.ListRulesResponse{
+		Rules: make([]*pb.Rule, 0, len(rules)),
+	}
+	for _, r := range rules {
+		resp.Rules = append(resp.Rules, utils.ConvertFromRuleToGRPC(r))
+	}
+
+	return resp, nil
+}
+
+// GetRule возвращает правило оповещения по идентификатору.
+func (c *Controller) GetRule(ctx context.Context, in *pb.GetRuleRequest) (*pb.RuleResponse, error) {
+	if c.Alerting == nil {
+		return nil, status.Error(codes.Unimplemented, "GetRule: alerting is not used")
+	}
+
+	rule, err := c.Alerting.GetRule(ctx, in.Id)
+	if err != nil {
+		return nil, status.Errorf(ruleErrorCode(err), "GetRule: get rule failed %s", err)
+	}
+
+	return &pb.RuleResponse{Rule: utils.ConvertFromRuleToGRPC(rule)}, nil
+}
+
+// CreateRule проверяет и сохраняет новое правило оповещения.
+func (c *Controller) CreateRule(ctx context.Context, in *pb.CreateRuleRequest) (*pb.RuleResponse, error) {
+	if c.Alerting == nil {
+		return nil, status.Error(codes.Unimplemented, "CreateRule: alerting is not used")
+	}
+
+	req, err := utils.ConvertFromGRPCToRule(in.Rule)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "CreateRule: %s", err)
+	}
+
+	rule, err := c.Alerting.CreateRule(ctx, req)
+	if err != nil {
+		return nil, status.Errorf(ruleErrorCode(err), "CreateRule: create rule failed %s", err)
+	}
+
+	return &pb.RuleResponse{Rule: utils.ConvertFromRuleToGRPC(rule)}, nil
+}
+
+// UpdateRule проверяет и сохраняет изменённое правило оповещения.
+func (c *Controller) UpdateRule(ctx context.Context, in *pb.UpdateRuleRequest) (*pb.RuleResponse, error) {
+	if c.Alerting == nil {
+		return nil, status.Error(codes.Unimplemented, "UpdateRule: alerting is not used")
+	}
+
+	req, err := utils.ConvertFromGRPCToRule(in.Rule)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "UpdateRule: %s", err)
+	}
+
+	rule, err := c.Alerting.UpdateRule(ctx, req.ID, req)
+	if err != nil {
+		return nil, status.Errorf(ruleErrorCode(err), "UpdateRule: update rule failed %s", err)
+	}
+
+	return &pb.RuleResponse{Rule: utils.ConvertFromRuleToGRPC(rule)}, nil
+}
+
+// DeleteRule удаляет правило оповещения.
+func (c *Controller) DeleteRule(ctx context.Context, in *pb.DeleteRuleRequest) (*emptypb.Empty, error) {
+	if c.Alerting == nil {
+		return nil, status.Error(codes.Unimplemented, "DeleteRule: alerting is not used")
+	}
+
+	if err := c.Alerting.DeleteRule(ctx, in.Id); err != nil {
+		return nil, status.Errorf(ruleErrorCode(err), "DeleteRule: delete rule failed %s", err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// ruleErrorCode возвращает код ответа для ошибки работы с правилами.
+func ruleErrorCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, alerting.ErrRuleNotFound):
+		return codes.NotFound
+	case errors.Is(err, alerting.ErrRuleExists):
+		return codes.AlreadyExists
+	case errors.Is(err, alerting.ErrInvalidRule):
+		return codes.InvalidArgument
+	case errors.Is(err, alerting.ErrRuleReadOnly):
+		return codes.FailedPrecondition
+	default:
+		return codes.Internal
+	}
+}
@@ -7,80 +7,109 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"net/http"
 	"strconv"
 
+	"github.com/pavlegich/metrics-alerting/internal/entities"
+	"github.com/pavlegich/metrics-alerting/internal/infra/config"
 	"github.com/pavlegich/metrics-alerting/internal/interfaces"
 	pb "github.com/pavlegich/metrics-alerting/internal/proto"
+	"github.com/pavlegich/metrics-alerting/internal/server"
 	utils "github.com/pavlegich/metrics-alerting/internal/utils/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
 )
 
+// maxUpdatesMetrics содержит наибольшее количество метрик в потоке Updates,
+// так как метрики потока накапливаются в памяти до его завершения.
+const maxUpdatesMetrics = 10000
+
 // Controller содержит данные для работы с grpc-сервером
 type Controller struct {
 	pb.UnimplementedMetricsServer
+	pb.UnimplementedAlertsServer
 
 	MemStorage interfaces.MetricStorage
 	Database   interfaces.Storage
 	File       interfaces.Storage
+	Config     *config.ServerConfig
+
+	// Alerting содержит движок оповещений, может отсутствовать.
+	Alerting interfaces.Alerting
+	// Stream содержит рассылку событий обновления метрик и оповещений,
+	// может отсутствовать.
+	Stream interfaces.Broadcaster
 }
 
 // NewController создаёт новый контроллер для grpc-сервера
-func NewController(ctx context.Context, ms interfaces.MetricStorage, db interfaces.Storage, file interfaces.Storage) *Controller {
+func NewController(ctx context.Context, ms interfaces.MetricStorage, db interfaces.Storage, file interfaces.Storage,
+	cfg *config.ServerConfig) *Controller {
 	return &Controller{
 		MemStorage: ms,
 		Database:   db,
 		File:       file,
+		Config:     cfg,
 	}
 }
 
-// Updates обрабатывает и сохраняет полученные метрики.
+// Updates принимает поток метрик и после его завершения сохраняет все
+// полученные метрики как пачку UpdateBatch. По умолчанию при ошибке в любой
+// метрике ни одна метрика не сохраняется, а ошибки метрик передаются в деталях
+// статуса. С флагом best_effort в первом сообщении потока сохраняются
+// корректные метрики, а отклонённые перечисляются в ответе. Поток длиннее
+// maxUpdatesMetrics метрик отклоняется.
 func (c *Controller) Updates(stream pb.Metrics_UpdatesServer) error {
+	metrics := make([]entities.Metrics, 0)
+	bestEffort := false
 	for {
 		in, err := stream.Recv()
 		if errors.Is(err, io.EOF) {
-			return stream.SendAndClose(&emptypb.Empty{})
+			break
 		}
 		if err != nil {
 			return status.Errorf(codes.Internal, "Updates: recieve stream failed %s", err)
 		}
-
-		var mValue string
-		switch in.Metric.Type {
-		case "gauge":
-			mValue = fmt.Sprint(in.Metric.Value)
-		case "counter":
-			mValue = fmt.Sprint(in.Metric.Delta)
-		default:
-			return status.Errorf(codes.InvalidArgument, "Updates: invalid metric type %s", in.Metric.Type)
+		if len(metrics) == 0 {
+			bestEffort = in.BestEffort
 		}
-
-		codePut := c.MemStorage.Put(stream.Context(), in.Metric.Type, in.Metric.Id, mValue)
-		if codePut != http.StatusOK {
-			return status.Error(utils.ConvertCodeHTTPtoGRPC(codePut), "Updates: put metric error")
+		if len(metrics) == maxUpdatesMetrics {
+			return status.Errorf(codes.ResourceExhausted, "Updates: stream exceeds %d metrics", maxUpdatesMetrics)
 		}
+		metrics = append(metrics, utils.ConvertFromGRPCToMetrics(in.Metric))
+	}
+
+	result, err := c.MemStorage.PutBatch(stream.Context(), metrics, bestEffort)
+	if err != nil {
+		return utils.ConvertBatchErrorToGRPC(fmt.Errorf("Updates: put metrics failed %w", err), result)
 	}
+
+	// в синхронном режиме сохраняем метрики до ответа клиенту
+	if err := server.SaveSync(stream.Context(), c.Config, c.MemStorage, c.Database, c.File); err != nil {
+		return utils.ConvertErrorToGRPC(fmt.Errorf("Updates: sync save failed %w", err), "")
+	}
+	return stream.SendAndClose(utils.ConvertFromBatchResultToGRPC(result))
 }
 
 // Update обрабатывает и сохраняет полученную в proto-формате метрику.
 // В случае успешного сохранения обработчик получает новое значение метрики
 // из хранилища и отправляет в ответ метрику в proto-формате.
 func (c *Controller) Update(ctx context.Context, in *pb.UpdateRequest) (*pb.UpdateResponse, error) {
+	// значение метрики неизвестного типа не заполняется, такую метрику отклонит хранилище
 	var mValue string
 	switch in.Metric.Type {
 	case "gauge":
 		mValue = fmt.Sprint(in.Metric.Value)
 	case "counter":
 		mValue = fmt.Sprint(in.Metric.Delta)
-	default:
-		return nil, status.Errorf(codes.InvalidArgument, "Update: invalid metric type %s", in.Metric.Type)
 	}
 
-	codePut := c.MemStorage.Put(ctx, in.Metric.Type, in.Metric.Id, mValue)
-	if codePut != http.StatusOK {
-		return nil, status.Error(utils.ConvertCodeHTTPtoGRPC(codePut), "Update: put metric error")
+	if err := c.MemStorage.Put(ctx, in.Metric.Type, in.Metric.Id, mValue); err != nil {
+		return nil, utils.ConvertErrorToGRPC(fmt.Errorf("Update: put metric failed %w", err), in.Metric.Id)
+	}
+
+	// в синхронном режиме сохраняем метрики до ответа клиенту
+	if err := server.SaveSync(ctx, c.Config, c.MemStorage, c.Database, c.File); err != nil {
+		return nil, utils.ConvertErrorToGRPC(fmt.Errorf("Update: sync save failed %w", err), in.Metric.Id)
 	}
 
 	pbMetric := &pb.Metric{
@@ -88,9 +117,9 @@ func (c *Controller) Update(ctx context.Context, in *pb.UpdateRequest) (*pb.Upda
 		Type: in.Metric.Type,
 	}
 
-	mValue, codeGet := c.MemStorage.Get(ctx, in.Metric.Type, in.Metric.Id)
-	if codeGet != http.StatusOK {
-		return nil, status.Errorf(utils.ConvertCodeHTTPtoGRPC(codeGet), "Update: get metric error")
+	mValue, err := c.MemStorage.Get(ctx, in.Metric.Type, in.Metric.Id)
+	if err != nil {
+		return nil, utils.ConvertErrorToGRPC(fmt.Errorf("Update: get metric failed %w", err), in.Metric.Id)
 	}
 
 	switch pbMetric.Type {
@@ -118,9 +147,9 @@ func (c *Controller) Update(ctx context.Context, in *pb.UpdateRequest) (*pb.Upda
 // в случае успешного получения значения метрики из хранилища,
 // формирует и отправляет ответ с метрикой в proto-формате.
 func (c *Controller) Value(ctx context.Context, in *pb.ValueRequest) (*pb.ValueResponse, error) {
-	metric, code := c.MemStorage.Get(ctx, in.Metric.Type, in.Metric.Id)
-	if code != http.StatusOK {
-		return nil, status.Errorf(utils.ConvertCodeHTTPtoGRPC(code), "Value: get metric error")
+	metric, err := c.MemStorage.Get(ctx, in.Metric.Type, in.Metric.Id)
+	if err != nil {
+		return nil, utils.ConvertErrorToGRPC(fmt.Errorf("Value: get metric failed %w", err), in.Metric.Id)
 	}
 
 	respMetric := &pb.Metric{
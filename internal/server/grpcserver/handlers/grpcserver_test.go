@@ -0,0 +1,114 @@
+package grpcserver
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/pavlegich/metrics-alerting/internal/infra/config"
+	pb "github.com/pavlegich/metrics-alerting/internal/proto"
+	"github.com/pavlegich/metrics-alerting/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// updatesStream содержит поток метрик Updates для тестов.
+type updatesStream struct {
+	pb.Metrics_UpdatesServer
+	ctx  context.Context
+	in   []*pb.UpdatesRequest
+	resp *pb.UpdateBatchResponse
+}
+
+func (s *updatesStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *updatesStream) Recv() (*pb.UpdatesRequest, error) {
+	if len(s.in) == 0 {
+		return nil, io.EOF
+	}
+	in := s.in[0]
+	s.in = s.in[1:]
+	return in, nil
+}
+
+func (s *updatesStream) SendAndClose(resp *pb.UpdateBatchResponse) error {
+	s.resp = resp
+	return nil
+}
+
+func TestController_Updates(t *testing.T) {
+	gauge := func(id string, value float64) *pb.UpdatesRequest {
+		return &pb.UpdatesRequest{Metric: &pb.Metric{Id: id, Type: "gauge", Value: value}}
+	}
+	invalid := &pb.UpdatesRequest{Metric: &pb.Metric{Id: "Bad", Type: "histogram"}}
+
+	tooMany := make([]*pb.UpdatesRequest, maxUpdatesMetrics+1)
+	for i := range tooMany {
+		tooMany[i] = gauge("Alloc", float64(i))
+	}
+
+	tests := []struct {
+		name        string
+		in          []*pb.UpdatesRequest
+		bestEffort  bool
+		wantCode    codes.Code
+		wantApplied int32
+		wantErrors  int
+		wantMetrics map[string]string
+	}{
+		{
+			name:        "atomic",
+			in:          []*pb.UpdatesRequest{gauge("Alloc", 1.5), gauge("HeapAlloc", 2)},
+			wantCode:    codes.OK,
+			wantApplied: 2,
+			wantMetrics: map[string]string{"Alloc": "1.5", "HeapAlloc": "2"},
+		},
+		{
+			name:        "atomic_rejected",
+			in:          []*pb.UpdatesRequest{gauge("Alloc", 1.5), invalid},
+			wantCode:    codes.InvalidArgument,
+			wantMetrics: map[string]string{},
+		},
+		{
+			name:        "best_effort",
+			in:          []*pb.UpdatesRequest{gauge("Alloc", 1.5), invalid},
+			bestEffort:  true,
+			wantCode:    codes.OK,
+			wantApplied: 1,
+			wantErrors:  1,
+			wantMetrics: map[string]string{"Alloc": "1.5"},
+		},
+		{
+			name:        "too_many",
+			in:          tooMany,
+			wantCode:    codes.ResourceExhausted,
+			wantMetrics: map[string]string{},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			ms := storage.NewMemStorage(ctx)
+			c := NewController(ctx, ms, nil, nil, &config.ServerConfig{})
+
+			// флаг учитывается в первом сообщении потока
+			in := append([]*pb.UpdatesRequest(nil), tc.in...)
+			in[0] = &pb.UpdatesRequest{Metric: in[0].Metric, BestEffort: tc.bestEffort}
+			stream := &updatesStream{ctx: ctx, in: in}
+
+			err := c.Updates(stream)
+			require.Equal(t, tc.wantCode, status.Code(err), err)
+			assert.Equal(t, tc.wantMetrics, ms.GetAll(ctx))
+			if tc.wantCode != codes.OK {
+				return
+			}
+			require.NotNil(t, stream.resp)
+			assert.Equal(t, tc.wantApplied, stream.resp.Applied)
+			assert.Len(t, stream.resp.Errors, tc.wantErrors)
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package grpcserver
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pavlegich/metrics-alerting/internal/entities"
+	pb "github.com/pavlegich/metrics-alerting/internal/proto"
+	"github.com/pavlegich/metrics-alerting/internal/server"
+	utils "github.com/pavlegich/metrics-alerting/internal/utils/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+const (
+	// defaultPageSize содержит размер страницы списка метрик по умолчанию.
+	defaultPageSize = 100
+	// maxPageSize содержит наибольший размер страницы списка метрик.
+	maxPageSize = 1000
+)
+
+// List возвращает метрики, подходящие под префикс имени и тип, постранично
+// в порядке имён. Следующая страница запрашивается с полученным page_token.
+func (c *Controller) List(ctx context.Context, in *pb.ListRequest) (*pb.ListResponse, error) {
+	if in.Type != "" && in.Type != "gauge" && in.Type != "counter" {
+		return nil, status.Errorf(codes.InvalidArgument, "List: invalid metric type %s", in.Type)
+	}
+	if in.PageSize < 0 || in.PageSize > maxPageSize {
+		return nil, status.Errorf(codes.InvalidArgument, "List: invalid page size %d", in.PageSize)
+	}
+
+	filter := entities.MetricFilter{
+		Prefix: in.Prefix,
+		Type:   in.Type,
+		After:  in.PageToken,
+		Limit:  int(in.PageSize),
+	}
+	if filter.Limit == 0 {
+		filter.Limit = defaultPageSize
+	}
+
+	metrics, next := c.MemStorage.List(ctx, filter)
+	resp := &pb.ListResponse{
+		Metrics:       make([]*pb.Metric, 0, len(metrics)),
+		NextPageToken: next,
+	}
+	for _, m := range metrics {
+		pbMetric, err := utils.ConvertFromMetricsToGRPC(m)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "List: %s", err)
+		}
+		resp.Metrics = append(resp.Metrics, pbMetric)
+	}
+
+	return resp, nil
+}
+
+// UpdateBatch сохраняет пачку метрик. По умолчанию пачка применяется
+// атомарно: при ошибке в любой метрике ни одна метрика не сохраняется,
+// а ошибки метрик передаются в деталях статуса. С флагом best_effort
+// сохраняются корректные метрики, а отклонённые перечисляются в ответе.
+func (c *Controller) UpdateBatch(ctx context.Context, in *pb.UpdateBatchRequest) (*pb.UpdateBatchResponse, error) {
+	metrics := make([]entities.Metrics, 0, len(in.Metrics))
+	for _, pbMetric := range in.Metrics {
+		metrics = append(metrics, utils.ConvertFromGRPCToMetrics(pbMetric))
+	}
+
+	result, err := c.MemStorage.PutBatch(ctx, metrics, in.BestEffort)
+	if err != nil {
+		return nil, utils.ConvertBatchErrorToGRPC(fmt.Errorf("UpdateBatch: put metrics failed %w", err), result)
+	}
+
+	// в синхронном режиме сохраняем метрики до ответа клиенту
+	if err := server.SaveSync(ctx, c.Config, c.MemStorage, c.Database, c.File); err != nil {
+		return nil, utils.ConvertErrorToGRPC(fmt.Errorf("UpdateBatch: sync save failed %w", err), "")
+	}
+
+	return utils.ConvertFromBatchResultToGRPC(result), nil
+}
+
+// Delete удаляет метрику.
+func (c *Controller) Delete(ctx context.Context, in *pb.DeleteRequest) (*emptypb.Empty, error) {
+	if err := c.MemStorage.Delete(ctx, in.Id); err != nil {
+		return nil, utils.ConvertErrorToGRPC(fmt.Errorf("Delete: delete metric failed %w", err), in.Id)
+	}
+
+	// в синхронном режиме сохраняем удаление до ответа клиенту
+	if err := server.SaveSync(ctx, c.Config, c.MemStorage, c.Database, c.File); err != nil {
+		return nil, utils.ConvertErrorToGRPC(fmt.Errorf("Delete: sync save failed %w", err), in.Id)
+	}
+
+	return &emptypb.Empty{}, nil
+}
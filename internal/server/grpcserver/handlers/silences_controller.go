@@ -0,0 +1,88 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+
+	"github.com/pavlegich/metrics-alerting/internal/alerting"
+	pb "github.com/pavlegich/metrics-alerting/internal/proto"
+	utils "github.com/pavlegich/metrics-alerting/internal/utils/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// ListSilences возвращает список тишин.
+func (c *Controller) ListSilences(ctx context.Context, _ *emptypb.Empty) (*pb.ListSilencesResponse, error) {
+	if c.Alerting == nil {
+		return nil, status.Error(codes.Unimplemented, "ListSilences: alerting is not used")
+	}
+
+	silences := c.Alerting.ListSilences(ctx)
+	resp := &pb.ListSilencesResponse{
+		Silences: make([]*pb.Silence, 0, len(silences)),
+	}
+	for _, s := range silences {
+		resp.Silences = append(resp.Silences, utils.ConvertFromSilenceToGRPC(s))
+	}
+
+	return resp, nil
+}
+
+// GetSilence возвращает тишину по идентификатору.
+func (c *Controller) GetSilence(ctx context.Context, in *pb.GetSilenceRequest) (*pb.SilenceResponse, error) {
+	if c.Alerting == nil {
+		return nil, status.Error(codes.Unimplemented, "GetSilence: alerting is not used")
+	}
+
+	silence, err := c.Alerting.GetSilence(ctx, in.Id)
+	if err != nil {
+		return nil, status.Errorf(silenceErrorCode(err), "GetSilence: get silence failed %s", err)
+	}
+
+	return &pb.SilenceResponse{Silence: utils.ConvertFromSilenceToGRPC(silence)}, nil
+}
+
+// CreateSilence проверяет и сохраняет новую тишину.
+func (c *Controller) CreateSilence(ctx context.Context, in *pb.CreateSilenceRequest) (*pb.SilenceResponse, error) {
+	if c.Alerting == nil {
+		return nil, status.Error(codes.Unimplemented, "CreateSilence: alerting is not used")
+	}
+
+	req, err := utils.ConvertFromGRPCToSilence(in.Silence)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "CreateSilence: %s", err)
+	}
+
+	silence, err := c.Alerting.CreateSilence(ctx, req)
+	if err != nil {
+		return nil, status.Errorf(silenceErrorCode(err), "CreateSilence: create silence failed %s", err)
+	}
+
+	return &pb.SilenceResponse{Silence: utils.ConvertFromSilenceToGRPC(silence)}, nil
+}
+
+// DeleteSilence удаляет тишину.
+func (c *Controller) DeleteSilence(ctx context.Context, in *pb.DeleteSilenceRequest) (*emptypb.Empty, error) {
+	if c.Alerting == nil {
+		return nil, status.Error(codes.Unimplemented, "DeleteSilence: alerting is not used")
+	}
+
+	if err := c.Alerting.DeleteSilence(ctx, in.Id); err != nil {
+		return nil, status.Errorf(silenceErrorCode(err), "DeleteSilence: delete silence failed %s", err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// silenceErrorCode возвращает код ответа для ошибки работы с тишинами.
+func silenceErrorCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, alerting.ErrSilenceNotFound):
+		return codes.NotFound
+	case errors.Is(err, alerting.ErrInvalidSilence):
+		return codes.InvalidArgument
+	default:
+		return codes.Internal
+	}
+}
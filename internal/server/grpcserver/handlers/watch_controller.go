@@ -0,0 +1,56 @@
+package grpcserver
+
+import (
+	"github.com/pavlegich/metrics-alerting/internal/broadcast"
+	pb "github.com/pavlegich/metrics-alerting/internal/proto"
+	utils "github.com/pavlegich/metrics-alerting/internal/utils/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Watch отправляет клиенту обновления метрик и изменения состояния оповещений,
+// подходящие под условия запроса. Размер буфера событий подписчика задаётся
+// в запросе и не превышает broadcast.MaxBuffer, клиент, не успевающий
+// получать события, отключается.
+// Подписка отменяется при завершении контекста потока.
+func (c *Controller) Watch(in *pb.WatchRequest, stream pb.Metrics_WatchServer) error {
+	ctx := stream.Context()
+
+	if c.Stream == nil {
+		return status.Error(codes.Unimplemented, "Watch: stream is not used")
+	}
+	if in.Buffer < 0 {
+		return status.Error(codes.InvalidArgument, "Watch: buffer is negative")
+	}
+	if in.Buffer > broadcast.MaxBuffer {
+		return status.Errorf(codes.InvalidArgument, "Watch: buffer exceeds %d", broadcast.MaxBuffer)
+	}
+	filter, err := utils.ConvertFromGRPCToEventFilter(in)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "Watch: %s", err)
+	}
+
+	events, cancel := c.Stream.Subscribe(ctx, filter, int(in.Buffer))
+	defer cancel()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-events:
+			if !ok {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return status.Error(codes.Unavailable, "Watch: stream closed")
+			}
+			resp, err := utils.ConvertFromEventToGRPC(event)
+			if err != nil {
+				return status.Errorf(codes.Internal, "Watch: %s", err)
+			}
+			if err := stream.Send(resp); err != nil {
+				return status.Errorf(codes.Unavailable, "Watch: send event failed %s", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package grpcserver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pavlegich/metrics-alerting/internal/broadcast"
+	pb "github.com/pavlegich/metrics-alerting/internal/proto"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// watchStream содержит поток событий подписки для тестов.
+type watchStream struct {
+	pb.Metrics_WatchServer
+	ctx  context.Context
+	sent []*pb.WatchResponse
+}
+
+func (s *watchStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *watchStream) Send(resp *pb.WatchResponse) error {
+	s.sent = append(s.sent, resp)
+	return nil
+}
+
+func TestController_WatchBuffer(t *testing.T) {
+	tests := []struct {
+		name   string
+		buffer int32
+		want   codes.Code
+	}{
+		{name: "negative", buffer: -1, want: codes.InvalidArgument},
+		{name: "too_large", buffer: broadcast.MaxBuffer + 1, want: codes.InvalidArgument},
+		{name: "max", buffer: broadcast.MaxBuffer, want: codes.OK},
+		{name: "default", buffer: 0, want: codes.OK},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			c := &Controller{Stream: broadcast.NewBroker(ctx)}
+			err := c.Watch(&pb.WatchRequest{Buffer: tc.buffer}, &watchStream{ctx: ctx})
+			assert.Equal(t, tc.want, status.Code(err))
+		})
+	}
+}
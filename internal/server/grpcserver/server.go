@@ -11,7 +11,6 @@ import (
 	pb "github.com/pavlegich/metrics-alerting/internal/proto"
 	ctrl "github.com/pavlegich/metrics-alerting/internal/server/grpcserver/handlers"
 	"github.com/pavlegich/metrics-alerting/internal/server/grpcserver/interceptors"
-	"github.com/pavlegich/metrics-alerting/internal/storage"
 	"google.golang.org/grpc"
 )
 
@@ -20,9 +19,12 @@ type Server struct {
 	config *config.ServerConfig
 }
 
-func NewServer(ctx context.Context, memStorage *storage.MemStorage,
-	database *storage.Database, file *storage.File, cfg *config.ServerConfig) interfaces.Server {
-	controller := ctrl.NewController(ctx, memStorage, database, file)
+func NewServer(ctx context.Context, memStorage interfaces.MetricStorage,
+	database interfaces.Storage, file interfaces.Storage, alerting interfaces.Alerting,
+	stream interfaces.Broadcaster, cfg *config.ServerConfig) interfaces.Server {
+	controller := ctrl.NewController(ctx, memStorage, database, file, cfg)
+	controller.Alerting = alerting
+	controller.Stream = stream
 	var opts []grpc.ServerOption
 	opts = append(opts, grpc.ChainUnaryInterceptor(
 		interceptors.WithUnaryLogging,
@@ -35,6 +37,7 @@ func NewServer(ctx context.Context, memStorage *storage.MemStorage,
 
 	srv := grpc.NewServer(opts...)
 	pb.RegisterMetricsServer(srv, controller)
+	pb.RegisterAlertsServer(srv, controller)
 
 	return &Server{
 		server: srv,
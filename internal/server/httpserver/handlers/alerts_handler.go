@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/pavlegich/metrics-alerting/internal/alerting"
+	"github.com/pavlegich/metrics-alerting/internal/entities"
+	"github.com/pavlegich/metrics-alerting/internal/infra/logger"
+	"go.uber.org/zap"
+)
+
+// HandleGetAlerts обрабатывает запрос на получение текущих оповещений
+// в состоянии pending или firing со значениями, вызвавшими оповещения.
+func (h *Webhook) HandleGetAlerts(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if h.Alerting == nil {
+		logger.Log.Error("HandleGetAlerts: alerting is not used")
+		writeNotFound(w, "alerting is not used")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, h.Alerting.Alerts(ctx))
+}
+
+// HandleGetAlertsHistory обрабатывает запрос на получение истории изменений
+// состояния оповещений. Отбор задаётся параметрами rule, metric, state,
+// from и to в формате RFC3339, количество записей — параметром limit.
+func (h *Webhook) HandleGetAlertsHistory(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if h.Alerting == nil {
+		logger.Log.Error("HandleGetAlertsHistory: alerting is not used")
+		writeNotFound(w, "alerting is not used")
+		return
+	}
+
+	query := r.URL.Query()
+	filter := entities.AlertFilter{
+		RuleID: query.Get("rule"),
+		Metric: query.Get("metric"),
+		State:  entities.AlertState(query.Get("state")),
+	}
+
+	switch filter.State {
+	case "", entities.StatePending, entities.StateFiring, entities.StateResolved, entities.StateInactive:
+	default:
+		logger.Log.Error("HandleGetAlertsHistory: unsupported state", zap.String("state", string(filter.State)))
+		writeBadRequest(w, "unsupported state")
+		return
+	}
+
+	for param, dst := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		v := query.Get(param)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			logger.Log.Error("HandleGetAlertsHistory: parse time failed", zap.String("param", param), zap.Error(err))
+			writeBadRequest(w, "invalid time parameter")
+			return
+		}
+		*dst = t
+	}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			logger.Log.Error("HandleGetAlertsHistory: invalid limit", zap.String("limit", v))
+			writeBadRequest(w, "invalid limit")
+			return
+		}
+		filter.Limit = limit
+	}
+
+	transitions, err := h.Alerting.Transitions(ctx, filter)
+	if err != nil {
+		logger.Log.Error("HandleGetAlertsHistory: query transitions failed", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, transitions)
+}
+
+// ackRequest содержит данные запроса на подтверждение оповещения.
+type ackRequest struct {
+	By string `json:"by"` // автор подтверждения
+}
+
+// HandleAckAlert обрабатывает запрос на подтверждение срабатывающего
+// оповещения, которое останавливает его эскалацию. Автор подтверждения
+// передаётся в JSON формате, в ответ отправляется подтверждённое оповещение.
+func (h *Webhook) HandleAckAlert(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if h.Alerting == nil {
+		logger.Log.Error("HandleAckAlert: alerting is not used")
+		writeNotFound(w, "alerting is not used")
+		return
+	}
+
+	var req ackRequest
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(r.Body); err != nil {
+		logger.Log.Error("HandleAckAlert: read body failed", zap.Error(err))
+		writeBadRequest(w, "read body failed")
+		return
+	}
+	if err := json.Unmarshal(buf.Bytes(), &req); err != nil {
+		logger.Log.Error("HandleAckAlert: decode body failed", zap.Error(err))
+		writeBadRequest(w, "invalid request body")
+		return
+	}
+
+	alert, err := h.Alerting.Acknowledge(ctx, chi.URLParam(r, "fingerprint"), req.By)
+	if err != nil {
+		logger.Log.Error("HandleAckAlert: acknowledge alert failed", zap.Error(err))
+		writeStatusError(w, alertErrorStatus(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, alert)
+}
+
+// alertErrorStatus возвращает код ответа по ошибке работы с оповещением.
+func alertErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, alerting.ErrAlertNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, alerting.ErrAlertNotFiring):
+		return http.StatusConflict
+	case errors.Is(err, alerting.ErrInvalidAck):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
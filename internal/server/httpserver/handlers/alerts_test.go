@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pavlegich/metrics-alerting/internal/alerting"
+	"github.com/pavlegich/metrics-alerting/internal/entities"
+	"github.com/pavlegich/metrics-alerting/internal/infra/config"
+	"github.com/pavlegich/metrics-alerting/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhook_HandleAlerts(t *testing.T) {
+	ctx := context.Background()
+	ms := storage.NewMemStorage(ctx)
+	cfg := &config.ServerConfig{}
+
+	engine := alerting.NewEngine(ctx, ms, nil)
+	engine.SetTransitions(storage.NewAlertLog(ctx, 100))
+	_, err := engine.CreateRule(ctx, entities.Rule{ID: "cpu", Name: "HighCPU", MetricType: "gauge",
+		MetricName: "CPUutilization1", Operator: ">", Threshold: 90, For: entities.Duration(time.Minute)})
+	require.NoError(t, err)
+
+	require.NoError(t, ms.Put(ctx, "gauge", "CPUutilization1", "95"))
+	engine.Evaluate(ctx, time.Now())
+
+	h, err := NewWebhook(ctx, ms, nil, nil, cfg)
+
+	require.NoError(t, err)
+	h.Alerting = engine
+	ts := httptest.NewServer(h.Route(ctx))
+	defer ts.Close()
+
+	resp, body := testRequest(t, ts, http.MethodGet, "/api/alerts")
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var alerts []entities.Alert
+	require.NoError(t, json.Unmarshal([]byte(body), &alerts))
+	require.Len(t, alerts, 1)
+	assert.Equal(t, entities.StatePending, alerts[0].State)
+	assert.Equal(t, 95.0, alerts[0].Value)
+
+	tests := []struct {
+		name   string
+		target string
+		code   int
+		count  int
+	}{
+		{name: "all", target: "/api/alerts/history", code: http.StatusOK, count: 1},
+		{name: "by_rule", target: "/api/alerts/history?rule=cpu&state=pending", code: http.StatusOK, count: 1},
+		{name: "other_metric", target: "/api/alerts/history?metric=HeapAlloc", code: http.StatusOK, count: 0},
+		{name: "before_range", target: "/api/alerts/history?to=2000-01-01T00:00:00Z", code: http.StatusOK, count: 0},
+		{name: "invalid_state", target: "/api/alerts/history?state=on", code: http.StatusBadRequest},
+		{name: "invalid_time", target: "/api/alerts/history?from=yesterday", code: http.StatusBadRequest},
+		{name: "invalid_limit", target: "/api/alerts/history?limit=-1", code: http.StatusBadRequest},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, body := testRequest(t, ts, http.MethodGet, tc.target)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tc.code, resp.StatusCode)
+			if tc.code != http.StatusOK {
+				return
+			}
+			var transitions []entities.AlertTransition
+			require.NoError(t, json.Unmarshal([]byte(body), &transitions))
+			assert.Len(t, transitions, tc.count)
+		})
+	}
+}
+
+func TestWebhook_HandleAckAlert(t *testing.T) {
+	ctx := context.Background()
+	ms := storage.NewMemStorage(ctx)
+	cfg := &config.ServerConfig{}
+
+	engine := alerting.NewEngine(ctx, ms, nil)
+	_, err := engine.CreateRule(ctx, entities.Rule{ID: "cpu", Name: "HighCPU", MetricType: "gauge",
+		MetricName: "CPUutilization1", Operator: ">", Threshold: 90})
+	require.NoError(t, err)
+	_, err = engine.CreateRule(ctx, entities.Rule{ID: "mem", Name: "HighMemory", MetricType: "gauge",
+		MetricName: "HeapAlloc", Operator: ">", Threshold: 90, For: entities.Duration(time.Hour)})
+	require.NoError(t, err)
+
+	require.NoError(t, ms.Put(ctx, "gauge", "CPUutilization1", "95"))
+	require.NoError(t, ms.Put(ctx, "gauge", "HeapAlloc", "95"))
+	engine.Evaluate(ctx, time.Now())
+
+	fingerprints := make(map[string]string)
+	for _, a := range engine.Alerts(ctx) {
+		fingerprints[a.RuleID] = a.Fingerprint
+	}
+	require.Len(t, fingerprints, 2)
+
+	h, err := NewWebhook(ctx, ms, nil, nil, cfg)
+
+	require.NoError(t, err)
+	h.Alerting = engine
+	ts := httptest.NewServer(h.Route(ctx))
+	defer ts.Close()
+
+	tests := []struct {
+		name   string
+		target string
+		body   string
+		code   int
+	}{
+		{name: "invalid_body", target: "/api/alerts/" + fingerprints["cpu"] + "/ack", body: `{`, code: http.StatusBadRequest},
+		{name: "empty_author", target: "/api/alerts/" + fingerprints["cpu"] + "/ack", body: `{}`, code: http.StatusBadRequest},
+		{name: "not_found", target: "/api/alerts/unknown/ack", body: `{"by":"ops"}`, code: http.StatusNotFound},
+		{name: "pending", target: "/api/alerts/" + fingerprints["mem"] + "/ack", body: `{"by":"ops"}`, code: http.StatusConflict},
+		{name: "firing", target: "/api/alerts/" + fingerprints["cpu"] + "/ack", body: `{"by":"ops"}`, code: http.StatusOK},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, ts.URL+tc.target, strings.NewReader(tc.body))
+			require.NoError(t, err)
+			resp, err := ts.Client().Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tc.code, resp.StatusCode)
+			if tc.code != http.StatusOK {
+				return
+			}
+			var alert entities.Alert
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&alert))
+			assert.Equal(t, "ops", alert.AckedBy)
+			assert.False(t, alert.AckedAt.IsZero())
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/pavlegich/metrics-alerting/internal/entities"
+	"github.com/pavlegich/metrics-alerting/internal/infra/logger"
+	"github.com/pavlegich/metrics-alerting/internal/server"
+	"github.com/pavlegich/metrics-alerting/internal/server/httpserver/openapi"
+	"github.com/pavlegich/metrics-alerting/internal/storage"
+	"go.uber.org/zap"
+)
+
+// apiPrefix содержит префикс путей REST API v2.
+const apiPrefix = "/api/v2"
+
+// metricUpdate содержит новое значение метрики, указанной в пути запроса.
+type metricUpdate struct {
+	MType string   `json:"type"`            // тип метрики
+	Delta *int64   `json:"delta,omitempty"` // значение метрики в случае counter
+	Value *float64 `json:"value,omitempty"` // значение метрики в случае gauge
+}
+
+// HandleGetOpenAPI отправляет спецификацию OpenAPI REST API v2 в JSON формате.
+func (h *Webhook) HandleGetOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(openapi.Spec())
+}
+
+// HandleGetMetricV2 обрабатывает запрос на получение метрики по имени
+// вместе со временем последнего обновления.
+func (h *Webhook) HandleGetMetricV2(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	metricName := chi.URLParam(r, "metricName")
+	metric, err := h.metric(ctx, metricName)
+	if err != nil {
+		logger.Log.Error("HandleGetMetricV2: metric get error", zap.Error(err))
+		writeError(w, err, metricName)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, metric)
+}
+
+// HandlePutMetricV2 обрабатывает запрос на обновление метрики, указанной
+// в пути запроса. В ответ отправляется метрика после обновления.
+func (h *Webhook) HandlePutMetricV2(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	metricName := chi.URLParam(r, "metricName")
+
+	var req metricUpdate
+	var buf bytes.Buffer
+	_, err := buf.ReadFrom(r.Body)
+	defer r.Body.Close()
+	if err != nil {
+		logger.Log.Error("HandlePutMetricV2: read body error")
+		writeBadRequest(w, "read body failed")
+		return
+	}
+	if err := json.Unmarshal(buf.Bytes(), &req); err != nil {
+		logger.Log.Error("HandlePutMetricV2: decoding error")
+		writeBadRequest(w, "invalid request body")
+		return
+	}
+
+	update := entities.Metrics{ID: metricName, MType: req.MType, Delta: req.Delta, Value: req.Value}
+	if _, err := h.MemStorage.PutBatch(ctx, []entities.Metrics{update}, false); err != nil {
+		logger.Log.Error("HandlePutMetricV2: metric put error", zap.Error(err))
+		writeError(w, err, metricName)
+		return
+	}
+
+	// в синхронном режиме сохраняем метрику до ответа клиенту
+	if err := server.SaveSync(ctx, h.Config, h.MemStorage, h.Database, h.File); err != nil {
+		logger.Log.Error("HandlePutMetricV2: sync save failed", zap.Error(err))
+		writeNotPersisted(w)
+		return
+	}
+
+	metric, err := h.metric(ctx, metricName)
+	if err != nil {
+		logger.Log.Error("HandlePutMetricV2: metric get error", zap.Error(err))
+		writeError(w, err, metricName)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, metric)
+}
+
+// metric возвращает метрику с указанным именем из хранилища.
+func (h *Webhook) metric(ctx context.Context, metricName string) (entities.Metrics, error) {
+	metricType, ok := h.MemStorage.GetType(ctx, metricName)
+	if !ok {
+		return entities.Metrics{}, fmt.Errorf("%w: %s", storage.ErrNotFound, metricName)
+	}
+	value, err := h.MemStorage.Get(ctx, metricType, metricName)
+	if err != nil {
+		return entities.Metrics{}, fmt.Errorf("metric: %w", err)
+	}
+
+	metric := entities.Metrics{ID: metricName, MType: metricType}
+	switch metricType {
+	case "counter":
+		delta, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return entities.Metrics{}, fmt.Errorf("metric: parse counter failed %w", err)
+		}
+		metric.Delta = &delta
+	default:
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return entities.Metrics{}, fmt.Errorf("metric: parse gauge failed %w", err)
+		}
+		metric.Value = &v
+	}
+	if updated, ok := h.MemStorage.GetUpdated(ctx, metricName); ok {
+		metric.UpdatedAt = &updated
+		metric.Stale = h.isStale(updated, time.Now())
+	}
+	return metric, nil
+}
+
+// routeAPI инициализирует обработчики REST API v2. Запросы к путям
+// спецификации проверяются по ней до передачи обработчикам.
+func (h *Webhook) routeAPI(r chi.Router, spec *openapi.Document) {
+	r.Get("/openapi.json", h.HandleGetOpenAPI)
+
+	r.Group(func(r chi.Router) {
+		r.Use(spec.Validator(apiPrefix, func(w http.ResponseWriter, r *http.Request, err error) {
+			logger.Log.Error("routeAPI: invalid request", zap.String("path", r.URL.Path), zap.Error(err))
+			writeBadRequest(w, err.Error())
+		}))
+
+		r.Get("/metrics", h.HandleGetMetrics)
+		r.Post("/metrics", h.HandlePostUpdates)
+		r.Get("/metrics/{metricName}", h.HandleGetMetricV2)
+		r.Put("/metrics/{metricName}", h.HandlePutMetricV2)
+		r.Delete("/metrics/{metricName}", h.HandleDeleteMetric)
+		r.Get("/metrics/{metricName}/history", h.HandleGetHistory)
+
+		r.Get("/rules", h.HandleGetRules)
+		r.Post("/rules", h.HandlePostRule)
+		r.Get("/rules/{ruleID}", h.HandleGetRule)
+		r.Put("/rules/{ruleID}", h.HandlePutRule)
+		r.Delete("/rules/{ruleID}", h.HandleDeleteRule)
+
+		r.Get("/alerts", h.HandleGetAlerts)
+		r.Get("/alerts/history", h.HandleGetAlertsHistory)
+		r.Post("/alerts/{fingerprint}/ack", h.HandleAckAlert)
+
+		r.Get("/silences", h.HandleGetSilences)
+		r.Post("/silences", h.HandlePostSilence)
+		r.Get("/silences/{silenceID}", h.HandleGetSilence)
+		r.Delete("/silences/{silenceID}", h.HandleDeleteSilence)
+
+		r.Get("/stats", h.HandleGetStats)
+	})
+}
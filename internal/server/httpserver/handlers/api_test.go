@@ -0,0 +1,313 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/pavlegich/metrics-alerting/internal/alerting"
+	"github.com/pavlegich/metrics-alerting/internal/infra/config"
+	"github.com/pavlegich/metrics-alerting/internal/server/httpserver/openapi"
+	"github.com/pavlegich/metrics-alerting/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhook_HandleAPI(t *testing.T) {
+	ctx := context.Background()
+	ms := storage.NewMemStorage(ctx)
+	cfg := &config.ServerConfig{}
+
+	require.NoError(t, ms.Put(ctx, "gauge", "Alloc", "1.5"))
+
+	h, err := NewWebhook(ctx, ms, nil, nil, cfg)
+
+	require.NoError(t, err)
+	h.Alerting = alerting.NewEngine(ctx, ms, nil)
+	ts := httptest.NewServer(h.Route(ctx))
+	defer ts.Close()
+
+	type want struct {
+		code      int
+		body      string
+		errorCode string
+	}
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		want   want
+	}{
+		{
+			name:   "get_metric",
+			method: http.MethodGet,
+			target: "/api/v2/metrics/Alloc",
+			want:   want{code: http.StatusOK, body: `{"id":"Alloc","type":"gauge","value":1.5}`},
+		},
+		{
+			name:   "get_metric_not_found",
+			method: http.MethodGet,
+			target: "/api/v2/metrics/Allo",
+			want:   want{code: http.StatusNotFound, errorCode: storage.CodeNotFound},
+		},
+		{
+			name:   "put_counter",
+			method: http.MethodPut,
+			target: "/api/v2/metrics/PollCount",
+			body:   `{"type":"counter","delta":3}`,
+			want:   want{code: http.StatusOK, body: `{"id":"PollCount","type":"counter","delta":3}`},
+		},
+		{
+			name:   "put_counter_again",
+			method: http.MethodPut,
+			target: "/api/v2/metrics/PollCount",
+			body:   `{"type":"counter","delta":2}`,
+			want:   want{code: http.StatusOK, body: `{"id":"PollCount","type":"counter","delta":5}`},
+		},
+		{
+			name:   "put_missing_value",
+			method: http.MethodPut,
+			target: "/api/v2/metrics/Alloc",
+			body:   `{"type":"gauge"}`,
+			want:   want{code: http.StatusBadRequest, errorCode: storage.CodeInvalidValue},
+		},
+		{
+			name:   "put_unknown_type",
+			method: http.MethodPut,
+			target: "/api/v2/metrics/Alloc",
+			body:   `{"type":"histogram","value":1}`,
+			want:   want{code: http.StatusBadRequest, errorCode: codeBadRequest},
+		},
+		{
+			name:   "list_metrics",
+			method: http.MethodGet,
+			target: "/api/v2/metrics?type=counter",
+			want:   want{code: http.StatusOK, body: `{"metrics":[{"id":"PollCount","type":"counter","delta":5}]}`},
+		},
+		{
+			name:   "list_metrics_invalid_limit",
+			method: http.MethodGet,
+			target: "/api/v2/metrics?limit=0",
+			want:   want{code: http.StatusBadRequest, errorCode: codeBadRequest},
+		},
+		{
+			name:   "update_metrics",
+			method: http.MethodPost,
+			target: "/api/v2/metrics",
+			body:   `[{"id":"HeapAlloc","type":"gauge","value":42}]`,
+			want:   want{code: http.StatusOK, body: `{"applied":1,"errors":[]}`},
+		},
+		{
+			name:   "update_metrics_not_array",
+			method: http.MethodPost,
+			target: "/api/v2/metrics",
+			body:   `{"id":"HeapAlloc","type":"gauge","value":42}`,
+			want:   want{code: http.StatusBadRequest, errorCode: codeBadRequest},
+		},
+		{
+			name:   "delete_metric",
+			method: http.MethodDelete,
+			target: "/api/v2/metrics/HeapAlloc",
+			want:   want{code: http.StatusNoContent},
+		},
+		{
+			name:   "create_rule",
+			method: http.MethodPost,
+			target: "/api/v2/rules",
+			body:   `{"id":"heap","name":"HighHeap","metric_type":"gauge","metric_name":"HeapAlloc","operator":">","threshold":100,"for":"2m0s"}`,
+			want: want{code: http.StatusCreated,
+				body: `{"id":"heap","name":"HighHeap","metric_type":"gauge","metric_name":"HeapAlloc","operator":">","threshold":100,"for":"2m0s"}`},
+		},
+		{
+			name:   "create_rule_invalid_duration",
+			method: http.MethodPost,
+			target: "/api/v2/rules",
+			body:   `{"name":"HighHeap","for":"soon"}`,
+			want:   want{code: http.StatusBadRequest, errorCode: codeBadRequest},
+		},
+		{
+			name:   "get_rule",
+			method: http.MethodGet,
+			target: "/api/v2/rules/heap",
+			want: want{code: http.StatusOK,
+				body: `{"id":"heap","name":"HighHeap","metric_type":"gauge","metric_name":"HeapAlloc","operator":">","threshold":100,"for":"2m0s"}`},
+		},
+		{
+			name:   "create_rule_exists",
+			method: http.MethodPost,
+			target: "/api/v2/rules",
+			body:   `{"id":"heap","name":"HighHeap","metric_type":"gauge","metric_name":"HeapAlloc","operator":">","threshold":100}`,
+			want:   want{code: http.StatusConflict, errorCode: codeConflict},
+		},
+		{
+			name:   "get_rule_not_found",
+			method: http.MethodGet,
+			target: "/api/v2/rules/unknown",
+			want:   want{code: http.StatusNotFound, errorCode: storage.CodeNotFound},
+		},
+		{
+			name:   "get_silence_not_found",
+			method: http.MethodGet,
+			target: "/api/v2/silences/unknown",
+			want:   want{code: http.StatusNotFound, errorCode: storage.CodeNotFound},
+		},
+		{
+			name:   "ack_alert_not_found",
+			method: http.MethodPost,
+			target: "/api/v2/alerts/unknown/ack",
+			body:   `{"by":"ops"}`,
+			want:   want{code: http.StatusNotFound, errorCode: storage.CodeNotFound},
+		},
+		{
+			name:   "legacy_rule_not_found",
+			method: http.MethodGet,
+			target: "/api/rules/unknown",
+			want:   want{code: http.StatusNotFound, errorCode: storage.CodeNotFound},
+		},
+		{
+			name:   "legacy_history_not_used",
+			method: http.MethodGet,
+			target: "/api/history/Alloc",
+			want:   want{code: http.StatusNotFound, errorCode: storage.CodeNotFound},
+		},
+		{
+			name:   "legacy_route",
+			method: http.MethodGet,
+			target: "/api/rules/heap",
+			want: want{code: http.StatusOK,
+				body: `{"id":"heap","name":"HighHeap","metric_type":"gauge","metric_name":"HeapAlloc","operator":">","threshold":100,"for":"2m0s"}`},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequestWithContext(ctx, tc.method, ts.URL+tc.target, strings.NewReader(tc.body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := ts.Client().Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want.code, resp.StatusCode)
+			if tc.want.body != "" {
+				assert.JSONEq(t, tc.want.body, stripUpdatedAt(t, body))
+			}
+			if tc.want.errorCode != "" {
+				var got errorResponse
+				require.NoError(t, json.Unmarshal(body, &got))
+				assert.Equal(t, tc.want.errorCode, got.Code)
+				assert.NotEmpty(t, got.Message)
+			}
+		})
+	}
+}
+
+func TestWebhook_HandleGetOpenAPI(t *testing.T) {
+	ctx := context.Background()
+	h, err := NewWebhook(ctx, storage.NewMemStorage(ctx), nil, nil, &config.ServerConfig{})
+	require.NoError(t, err)
+	ts := httptest.NewServer(h.Route(ctx))
+	defer ts.Close()
+
+	resp, err := ts.Client().Get(ts.URL + "/api/v2/openapi.json")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+	var doc struct {
+		OpenAPI string                     `json:"openapi"`
+		Paths   map[string]json.RawMessage `json:"paths"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&doc))
+	assert.Equal(t, "3.0.3", doc.OpenAPI)
+	assert.Contains(t, doc.Paths, "/metrics/{metricName}")
+}
+
+// stripUpdatedAt удаляет время обновления метрик из ответа для сравнения.
+func stripUpdatedAt(t *testing.T, body []byte) string {
+	t.Helper()
+	var v any
+	require.NoError(t, json.Unmarshal(body, &v))
+	var strip func(v any)
+	strip = func(v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			delete(v, "updated_at")
+			for _, item := range v {
+				strip(item)
+			}
+		case []any:
+			for _, item := range v {
+				strip(item)
+			}
+		}
+	}
+	strip(v)
+	out, err := json.Marshal(v)
+	require.NoError(t, err)
+	return string(out)
+}
+
+func TestWebhook_RoutesMatchSpec(t *testing.T) {
+	ctx := context.Background()
+	h, err := NewWebhook(ctx, storage.NewMemStorage(ctx), nil, nil, &config.ServerConfig{})
+	require.NoError(t, err)
+
+	spec, err := openapi.Load(openapi.Spec())
+	require.NoError(t, err)
+
+	// каждый путь REST API v2 описан в спецификации
+	routes := make(map[string]struct{})
+	err = chi.Walk(h.Route(ctx), func(method string, route string, handler http.Handler,
+		middlewares ...func(http.Handler) http.Handler) error {
+		path, ok := strings.CutPrefix(route, apiPrefix)
+		if !ok || path == "/openapi.json" {
+			return nil
+		}
+		routes[method+" "+path] = struct{}{}
+
+		item, ok := spec.Paths[path]
+		if !assert.True(t, ok, "path %s is not in spec", path) {
+			return nil
+		}
+		assert.NotNil(t, operation(item, method), "operation %s %s is not in spec", method, path)
+		return nil
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, routes)
+
+	// каждая операция спецификации обрабатывается сервером
+	for path, item := range spec.Paths {
+		for _, method := range []string{http.MethodGet, http.MethodPut, http.MethodPost, http.MethodDelete} {
+			if operation(item, method) == nil || path == "/openapi.json" {
+				continue
+			}
+			assert.Contains(t, routes, method+" "+path)
+		}
+	}
+}
+
+// operation возвращает операцию пути спецификации по методу запроса.
+func operation(item *openapi.PathItem, method string) *openapi.Operation {
+	switch method {
+	case http.MethodGet:
+		return item.Get
+	case http.MethodPut:
+		return item.Put
+	case http.MethodPost:
+		return item.Post
+	case http.MethodDelete:
+		return item.Delete
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/pavlegich/metrics-alerting/internal/infra/logger"
+	"github.com/pavlegich/metrics-alerting/internal/storage"
+	"go.uber.org/zap"
+)
+
+const (
+	// codeBadRequest содержит код ошибки некорректного запроса.
+	codeBadRequest = "bad_request"
+	// codeConflict содержит код ошибки конфликта с текущим состоянием ресурса.
+	codeConflict = "conflict"
+)
+
+// errorResponse содержит описание ошибки обработки запроса в JSON формате.
+type errorResponse struct {
+	Code    string `json:"code"`             // код ошибки
+	Message string `json:"message"`          // описание ошибки
+	Metric  string `json:"metric,omitempty"` // имя метрики
+}
+
+// writeError отправляет ошибку хранилища метрик в JSON формате с кодом ответа,
+// соответствующим ошибке. Описание ошибки без соответствующего кода ответа
+// записывается в журнал, а клиенту отправляется внутренняя ошибка сервера.
+func writeError(w http.ResponseWriter, err error, metric string) {
+	status := storageErrorStatus(err)
+	if status == http.StatusInternalServerError {
+		writeServerError(w, err)
+		return
+	}
+	writeErrorStatus(w, status, err, metric)
+}
+
+// writeStatusError отправляет ошибку правил, тишин или оповещений в JSON
+// формате с указанным кодом ответа и соответствующим ему кодом ошибки.
+func writeStatusError(w http.ResponseWriter, status int, err error) {
+	switch status {
+	case http.StatusBadRequest:
+		writeBadRequest(w, err.Error())
+	case http.StatusNotFound:
+		writeNotFound(w, err.Error())
+	case http.StatusConflict:
+		writeJSON(w, status, errorResponse{Code: codeConflict, Message: err.Error()})
+	default:
+		writeServerError(w, err)
+	}
+}
+
+// writeErrorStatus отправляет ошибку хранилища метрик в JSON формате
+// с указанным кодом ответа.
+func writeErrorStatus(w http.ResponseWriter, status int, err error, metric string) {
+	writeJSON(w, status, errorResponse{
+		Code:    storage.ErrorCode(err),
+		Message: err.Error(),
+		Metric:  metric,
+	})
+}
+
+// writeBadRequest отправляет ошибку некорректного запроса в JSON формате.
+func writeBadRequest(w http.ResponseWriter, message string) {
+	writeJSON(w, http.StatusBadRequest, errorResponse{
+		Code:    codeBadRequest,
+		Message: message,
+	})
+}
+
+// writeNotFound отправляет ошибку отсутствия ресурса в JSON формате.
+func writeNotFound(w http.ResponseWriter, message string) {
+	writeJSON(w, http.StatusNotFound, errorResponse{
+		Code:    storage.CodeNotFound,
+		Message: message,
+	})
+}
+
+// writeServerError записывает ошибку в журнал и отправляет клиенту
+// внутреннюю ошибку сервера без её описания.
+func writeServerError(w http.ResponseWriter, err error) {
+	logger.Log.Error("writeServerError: internal error", zap.Error(err))
+	writeInternalError(w, "internal server error")
+}
+
+// writeInternalError отправляет внутреннюю ошибку сервера в JSON формате.
+// Описание исходной ошибки клиенту не передаётся.
+func writeInternalError(w http.ResponseWriter, message string) {
+	writeJSON(w, http.StatusInternalServerError, errorResponse{
+		Code:    storage.CodeInternal,
+		Message: message,
+	})
+}
+
+// writeNotPersisted отправляет ошибку синхронного сохранения в JSON формате.
+// Изменение к этому моменту уже применено к хранилищу, поэтому код ошибки
+// сообщает клиенту, что запрос нельзя отправлять повторно.
+func writeNotPersisted(w http.ResponseWriter) {
+	writeJSON(w, http.StatusInternalServerError, errorResponse{
+		Code:    storage.CodeNotPersisted,
+		Message: storage.ErrNotPersisted.Error(),
+	})
+}
+
+// storageErrorStatus возвращает код ответа для ошибки хранилища метрик.
+func storageErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, storage.ErrInvalidValue):
+		return http.StatusBadRequest
+	case errors.Is(err, storage.ErrUnknownType):
+		return http.StatusNotImplemented
+	default:
+		return http.StatusInternalServerError
+	}
+}
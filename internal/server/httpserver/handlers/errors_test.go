@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pavlegich/metrics-alerting/internal/infra/config"
+	"github.com/pavlegich/metrics-alerting/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhook_ErrorResponse(t *testing.T) {
+	ctx := context.Background()
+	ms := storage.NewMemStorage(ctx)
+	cfg := &config.ServerConfig{}
+
+	require.NoError(t, ms.Put(ctx, "counter", "PollCount", "1"))
+
+	h, err := NewWebhook(ctx, ms, nil, nil, cfg)
+
+	require.NoError(t, err)
+	ts := httptest.NewServer(h.Route(ctx))
+	defer ts.Close()
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		code   int
+		want   errorResponse
+	}{
+		{name: "update_invalid_value", method: http.MethodPost, target: "/update/counter/PollCount/1.5",
+			code: http.StatusBadRequest, want: errorResponse{Code: storage.CodeInvalidValue, Metric: "PollCount"}},
+		{name: "update_unknown_type", method: http.MethodPost, target: "/update/",
+			body: `{"id":"Histogram","type":"histogram"}`,
+			code: http.StatusUnprocessableEntity, want: errorResponse{Code: storage.CodeUnknownType, Metric: "Histogram"}},
+		{name: "update_missing_value", method: http.MethodPost, target: "/update/",
+			body: `{"id":"Alloc","type":"gauge"}`,
+			code: http.StatusBadRequest, want: errorResponse{Code: storage.CodeInvalidValue, Metric: "Alloc"}},
+		{name: "update_invalid_body", method: http.MethodPost, target: "/update/", body: `{`,
+			code: http.StatusBadRequest, want: errorResponse{Code: codeBadRequest}},
+		{name: "value_not_found", method: http.MethodPost, target: "/value/",
+			body: `{"id":"Alloc","type":"gauge"}`,
+			code: http.StatusNotFound, want: errorResponse{Code: storage.CodeNotFound, Metric: "Alloc"}},
+		{name: "value_empty_name", method: http.MethodPost, target: "/value/",
+			body: `{"id":"","type":"gauge"}`,
+			code: http.StatusNotFound, want: errorResponse{Code: storage.CodeNotFound}},
+		{name: "delete_not_found", method: http.MethodDelete, target: "/api/metrics/Alloc",
+			code: http.StatusNotFound, want: errorResponse{Code: storage.CodeNotFound, Metric: "Alloc"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequestWithContext(ctx, tc.method, ts.URL+tc.target, strings.NewReader(tc.body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := ts.Client().Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			require.Equal(t, tc.code, resp.StatusCode)
+			assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+			var got errorResponse
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&got))
+			assert.Equal(t, tc.want.Code, got.Code)
+			assert.Equal(t, tc.want.Metric, got.Metric)
+			assert.NotEmpty(t, got.Message)
+		})
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		code    int
+		want    errorResponse
+		private string
+	}{
+		{name: "not_found", err: fmt.Errorf("Get: %w: Alloc", storage.ErrNotFound),
+			code: http.StatusNotFound, want: errorResponse{Code: storage.CodeNotFound, Metric: "Alloc"}},
+		{name: "internal", err: errors.New("dial tcp db.internal:5432: password authentication failed"),
+			code: http.StatusInternalServerError, want: errorResponse{Code: storage.CodeInternal}, private: "db.internal"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			writeError(w, tc.err, "Alloc")
+
+			require.Equal(t, tc.code, w.Code)
+			var got errorResponse
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+			assert.Equal(t, tc.want.Code, got.Code)
+			assert.Equal(t, tc.want.Metric, got.Metric)
+			assert.NotEmpty(t, got.Message)
+			if tc.private != "" {
+				assert.NotContains(t, got.Message, tc.private)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/pavlegich/metrics-alerting/internal/entities"
+	"github.com/pavlegich/metrics-alerting/internal/infra/logger"
+	"go.uber.org/zap"
+)
+
+// defaultHistoryRange содержит период истории, возвращаемый по умолчанию.
+const defaultHistoryRange = time.Hour
+
+// HandleGetHistory обрабатывает запрос на получение истории значений метрики.
+// Период задаётся параметрами from и to в формате RFC3339, разрешение
+// выбирается автоматически или задаётся параметром resolution (raw, 1m, 1h).
+func (h *Webhook) HandleGetHistory(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if h.History == nil {
+		logger.Log.Error("HandleGetHistory: history is not used")
+		writeNotFound(w, "history is not used")
+		return
+	}
+
+	metricName := chi.URLParam(r, "metricName")
+	query := r.URL.Query()
+
+	to := time.Now()
+	if v := query.Get("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			logger.Log.Error("HandleGetHistory: parse to failed", zap.Error(err))
+			writeBadRequest(w, "invalid to parameter")
+			return
+		}
+		to = t
+	}
+
+	from := to.Add(-defaultHistoryRange)
+	if v := query.Get("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			logger.Log.Error("HandleGetHistory: parse from failed", zap.Error(err))
+			writeBadRequest(w, "invalid from parameter")
+			return
+		}
+		from = t
+	}
+	if from.After(to) {
+		logger.Log.Error("HandleGetHistory: from is after to")
+		writeBadRequest(w, "from is after to")
+		return
+	}
+
+	var series entities.Series
+	var ok bool
+	switch res := entities.Resolution(query.Get("resolution")); res {
+	case "":
+		series, ok = h.History.Query(ctx, metricName, from, to)
+	case entities.ResolutionRaw, entities.ResolutionMinute, entities.ResolutionHour:
+		series, ok = h.History.QueryResolution(ctx, metricName, from, to, res)
+	default:
+		logger.Log.Error("HandleGetHistory: unsupported resolution", zap.String("resolution", string(res)))
+		writeBadRequest(w, "unsupported resolution")
+		return
+	}
+	if !ok {
+		writeNotFound(w, "metric history not found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, series)
+}
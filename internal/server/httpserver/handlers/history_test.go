@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pavlegich/metrics-alerting/internal/entities"
+	"github.com/pavlegich/metrics-alerting/internal/infra/config"
+	"github.com/pavlegich/metrics-alerting/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhook_HandleGetHistory(t *testing.T) {
+	ctx := context.Background()
+	ms := storage.NewMemStorage(ctx)
+	cfg := &config.ServerConfig{}
+
+	history := storage.NewHistory(ctx, storage.Retention{Raw: time.Hour, Minute: 24 * time.Hour, Hour: 720 * time.Hour})
+	history.Record(ctx, entities.Update{
+		ID:    "Alloc",
+		MType: "gauge",
+		Value: 1.5,
+		Time:  time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
+	})
+
+	h, err := NewWebhook(ctx, ms, nil, nil, cfg)
+
+	require.NoError(t, err)
+	h.History = history
+	ts := httptest.NewServer(h.Route(ctx))
+	defer ts.Close()
+
+	type want struct {
+		code int
+		body string
+	}
+	tests := []struct {
+		name   string
+		target string
+		want   want
+	}{
+		{
+			name:   "raw_values",
+			target: "/api/history/Alloc?from=2024-01-01T09:30:00Z&to=2024-01-01T10:30:00Z&resolution=raw",
+			want: want{
+				code: http.StatusOK,
+				body: `{"id":"Alloc","type":"gauge","resolution":"raw","points":[` +
+					`{"time":"2024-01-01T10:00:00Z","count":1,"min":1.5,"max":1.5,"avg":1.5,"last":1.5}]}`,
+			},
+		},
+		{
+			name:   "unknown_metric",
+			target: "/api/history/Unknown",
+			want: want{
+				code: http.StatusNotFound,
+			},
+		},
+		{
+			name:   "invalid_range",
+			target: "/api/history/Alloc?from=2024-01-01T11:00:00Z&to=2024-01-01T10:00:00Z",
+			want: want{
+				code: http.StatusBadRequest,
+			},
+		},
+		{
+			name:   "invalid_resolution",
+			target: "/api/history/Alloc?resolution=5m",
+			want: want{
+				code: http.StatusBadRequest,
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, body := testRequest(t, ts, http.MethodGet, tc.target)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tc.want.code, resp.StatusCode)
+			if tc.want.body != "" {
+				assert.JSONEq(t, tc.want.body, body)
+			}
+		})
+	}
+}
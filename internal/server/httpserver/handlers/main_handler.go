@@ -3,19 +3,23 @@ package handlers
 import (
 	"net/http"
 	"text/template"
+	"time"
 
 	"github.com/pavlegich/metrics-alerting/internal/entities"
 )
 
 // HandleMain обрабатывает запрос получения корневой веб-страницы,
 // формирумя страницу, содержащую таблицу с информацией о текущих
-// значениях метрик.
+// значениях метрик, времени их обновления и признаке устаревания.
 func (h *Webhook) HandleMain(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	metrics := h.MemStorage.GetAll(ctx)
+	updated := h.MemStorage.GetAllUpdated(ctx)
+	now := time.Now()
 	table := entities.NewTable()
 	for metric, value := range metrics {
-		table.Put(metric, value)
+		t := updated[metric]
+		table.Put(metric, value, t, h.isStale(t, now))
 	}
 	tmpl, err := template.New("index").Parse(entities.IndexTemplate)
 	if err != nil {
@@ -29,3 +33,12 @@ func (h *Webhook) HandleMain(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+// isStale проверяет, что метрика с указанным временем обновления устарела.
+// Проверка отключена, если в конфигурации не задан период устаревания.
+func (h *Webhook) isStale(updated time.Time, now time.Time) bool {
+	if h.Config.StaleAfter <= 0 || updated.IsZero() {
+		return false
+	}
+	return now.Sub(updated) > time.Duration(h.Config.StaleAfter)
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/pavlegich/metrics-alerting/internal/entities"
+	"github.com/pavlegich/metrics-alerting/internal/infra/logger"
+	"github.com/pavlegich/metrics-alerting/internal/server"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultPageSize содержит размер страницы списка метрик по умолчанию.
+	defaultPageSize = 100
+	// maxPageSize содержит наибольший размер страницы списка метрик.
+	maxPageSize = 1000
+)
+
+// listResponse содержит страницу списка метрик.
+type listResponse struct {
+	Metrics []entities.Metrics `json:"metrics"`        // метрики страницы
+	Next    string             `json:"next,omitempty"` // значение after для следующей страницы
+}
+
+// HandleGetMetrics обрабатывает запрос на получение списка метрик.
+// Метрики отбираются по параметрам prefix (префикс имени) и type (тип метрики)
+// и возвращаются постранично в порядке имён: размер страницы задаётся
+// параметром limit, следующая страница запрашивается с параметром after.
+func (h *Webhook) HandleGetMetrics(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	query := r.URL.Query()
+	filter := entities.MetricFilter{
+		Prefix: query.Get("prefix"),
+		Type:   query.Get("type"),
+		After:  query.Get("after"),
+		Limit:  defaultPageSize,
+	}
+	if filter.Type != "" && filter.Type != "gauge" && filter.Type != "counter" {
+		logger.Log.Error("HandleGetMetrics: invalid type", zap.String("type", filter.Type))
+		writeBadRequest(w, "invalid metric type "+filter.Type)
+		return
+	}
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > maxPageSize {
+			logger.Log.Error("HandleGetMetrics: invalid limit", zap.String("limit", v))
+			writeBadRequest(w, "invalid limit "+v)
+			return
+		}
+		filter.Limit = limit
+	}
+
+	metrics, next := h.MemStorage.List(ctx, filter)
+	writeJSON(w, http.StatusOK, listResponse{Metrics: metrics, Next: next})
+}
+
+// HandleDeleteMetric обрабатывает запрос на удаление метрики.
+func (h *Webhook) HandleDeleteMetric(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	metricName := chi.URLParam(r, "metricName")
+	if err := h.MemStorage.Delete(ctx, metricName); err != nil {
+		logger.Log.Error("HandleDeleteMetric: metric delete error", zap.Error(err))
+		writeError(w, err, metricName)
+		return
+	}
+
+	// в синхронном режиме сохраняем удаление до ответа клиенту
+	if err := server.SaveSync(ctx, h.Config, h.MemStorage, h.Database, h.File); err != nil {
+		logger.Log.Error("HandleDeleteMetric: sync save failed", zap.Error(err))
+		writeNotPersisted(w)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
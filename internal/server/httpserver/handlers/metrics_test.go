@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pavlegich/metrics-alerting/internal/infra/config"
+	"github.com/pavlegich/metrics-alerting/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhook_HandleGetMetrics(t *testing.T) {
+	ctx := context.Background()
+	ms := storage.NewMemStorage(ctx)
+	cfg := &config.ServerConfig{}
+
+	require.NoError(t, ms.Put(ctx, "gauge", "CPUutilization1", "95"))
+	require.NoError(t, ms.Put(ctx, "gauge", "CPUutilization2", "10"))
+	require.NoError(t, ms.Put(ctx, "gauge", "HeapAlloc", "1024"))
+	require.NoError(t, ms.Put(ctx, "counter", "PollCount", "3"))
+
+	h, err := NewWebhook(ctx, ms, nil, nil, cfg)
+
+	require.NoError(t, err)
+	ts := httptest.NewServer(h.Route(ctx))
+	defer ts.Close()
+
+	tests := []struct {
+		name   string
+		target string
+		code   int
+		want   []string
+		next   string
+	}{
+		{name: "all", target: "/api/metrics", code: http.StatusOK,
+			want: []string{"CPUutilization1", "CPUutilization2", "HeapAlloc", "PollCount"}},
+		{name: "by_prefix_and_type", target: "/api/metrics?prefix=CPU&type=gauge", code: http.StatusOK,
+			want: []string{"CPUutilization1", "CPUutilization2"}},
+		{name: "first_page", target: "/api/metrics?limit=3", code: http.StatusOK,
+			want: []string{"CPUutilization1", "CPUutilization2", "HeapAlloc"}, next: "HeapAlloc"},
+		{name: "next_page", target: "/api/metrics?limit=3&after=HeapAlloc", code: http.StatusOK,
+			want: []string{"PollCount"}},
+		{name: "invalid_type", target: "/api/metrics?type=histogram", code: http.StatusBadRequest},
+		{name: "invalid_limit", target: "/api/metrics?limit=0", code: http.StatusBadRequest},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, body := testRequest(t, ts, http.MethodGet, tc.target)
+			defer resp.Body.Close()
+
+			require.Equal(t, tc.code, resp.StatusCode)
+			if tc.code != http.StatusOK {
+				return
+			}
+			var got listResponse
+			require.NoError(t, json.Unmarshal([]byte(body), &got))
+			names := make([]string, 0, len(got.Metrics))
+			for _, m := range got.Metrics {
+				names = append(names, m.ID)
+			}
+			assert.Equal(t, tc.want, names)
+			assert.Equal(t, tc.next, got.Next)
+		})
+	}
+}
+
+func TestWebhook_HandleDeleteMetric(t *testing.T) {
+	ctx := context.Background()
+	ms := storage.NewMemStorage(ctx)
+	cfg := &config.ServerConfig{}
+
+	require.NoError(t, ms.Put(ctx, "gauge", "HeapAlloc", "1024"))
+
+	h, err := NewWebhook(ctx, ms, nil, nil, cfg)
+
+	require.NoError(t, err)
+	ts := httptest.NewServer(h.Route(ctx))
+	defer ts.Close()
+
+	resp, _ := testRequest(t, ts, http.MethodDelete, "/api/metrics/HeapAlloc")
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	resp, _ = testRequest(t, ts, http.MethodDelete, "/api/metrics/HeapAlloc")
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, _ = testRequest(t, ts, http.MethodGet, "/value/gauge/HeapAlloc")
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestWebhook_HandlePostUpdates(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.ServerConfig{}
+
+	tests := []struct {
+		name    string
+		target  string
+		body    string
+		code    int
+		applied int
+		errors  []int
+		want    map[string]string
+	}{
+		{name: "atomic", target: "/updates/",
+			body: `[{"id":"Alloc","type":"gauge","value":1.5},{"id":"PollCount","type":"counter","delta":2}]`,
+			code: http.StatusOK, applied: 2, want: map[string]string{"Alloc": "1.5", "PollCount": "3"}},
+		{name: "atomic_missing_value", target: "/updates/?mode=atomic",
+			body: `[{"id":"Alloc","type":"gauge","value":1.5},{"id":"PollCount","type":"counter"}]`,
+			code: http.StatusBadRequest, errors: []int{1}, want: map[string]string{"PollCount": "1"}},
+		{name: "atomic_unknown_type", target: "/updates/",
+			body: `[{"id":"Alloc","type":"gauge","value":1.5},{"id":"Histogram","type":"histogram"}]`,
+			code: http.StatusUnprocessableEntity, errors: []int{1}, want: map[string]string{"PollCount": "1"}},
+		{name: "atomic_empty_name", target: "/updates/",
+			body: `[{"id":"","type":"gauge","value":1.5}]`,
+			code: http.StatusNotFound, errors: []int{0}, want: map[string]string{"PollCount": "1"}},
+		{name: "best_effort", target: "/updates/?mode=best-effort",
+			body: `[{"id":"Alloc","type":"gauge","value":1.5},{"id":"Histogram","type":"histogram"}]`,
+			code: http.StatusOK, applied: 1, errors: []int{1}, want: map[string]string{"Alloc": "1.5", "PollCount": "1"}},
+		{name: "invalid_mode", target: "/updates/?mode=partial", body: `[]`,
+			code: http.StatusBadRequest, want: map[string]string{"PollCount": "1"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ms := storage.NewMemStorage(ctx)
+			require.NoError(t, ms.Put(ctx, "counter", "PollCount", "1"))
+
+			h, err := NewWebhook(ctx, ms, nil, nil, cfg)
+
+			require.NoError(t, err)
+			ts := httptest.NewServer(h.Route(ctx))
+			defer ts.Close()
+
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, ts.URL+tc.target, strings.NewReader(tc.body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := ts.Client().Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			require.Equal(t, tc.code, resp.StatusCode)
+			assert.Equal(t, tc.want, ms.GetAll(ctx))
+			if tc.errors == nil && tc.applied == 0 {
+				return
+			}
+
+			var result batchErrorResponse
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+			assert.Equal(t, tc.applied, result.Applied)
+			if tc.code != http.StatusOK {
+				assert.Equal(t, result.Errors[0].Code, result.Code)
+				assert.Equal(t, result.Errors[0].ID, result.Metric)
+			}
+			indexes := make([]int, 0, len(result.Errors))
+			for _, e := range result.Errors {
+				indexes = append(indexes, e.Index)
+			}
+			if tc.errors == nil {
+				tc.errors = []int{}
+			}
+			assert.Equal(t, tc.errors, indexes)
+		})
+	}
+}
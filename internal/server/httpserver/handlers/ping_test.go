@@ -9,6 +9,7 @@ import (
 
 	"github.com/pavlegich/metrics-alerting/internal/infra/config"
 	"github.com/pavlegich/metrics-alerting/internal/storage"
+	"github.com/stretchr/testify/require"
 )
 
 func ExampleWebhook_HandlePing() {
@@ -22,7 +23,11 @@ func ExampleWebhook_HandlePing() {
 	cfg := &config.ServerConfig{}
 
 	// Контроллер
-	h := NewWebhook(ctx, ms, nil, nil, cfg)
+	h, err := NewWebhook(ctx, ms, nil, nil, cfg)
+	if err != nil {
+		fmt.Println("create webhook failed", err)
+		return
+	}
 
 	// Запрос к серверу
 	url := `http://localhost:8080/ping`
@@ -51,7 +56,8 @@ func BenchmarkWebhook_HandlePing(b *testing.B) {
 	cfg := &config.ServerConfig{}
 
 	// Контроллер
-	h := NewWebhook(ctx, ms, nil, nil, cfg)
+	h, err := NewWebhook(ctx, ms, nil, nil, cfg)
+	require.NoError(b, err)
 
 	// Запрос к серверу
 	url := `http://localhost:8080/ping`
@@ -2,11 +2,13 @@ package handlers
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/pavlegich/metrics-alerting/internal/infra/config"
 	"github.com/pavlegich/metrics-alerting/internal/interfaces"
 	"github.com/pavlegich/metrics-alerting/internal/server/httpserver/middlewares"
+	"github.com/pavlegich/metrics-alerting/internal/server/httpserver/openapi"
 )
 
 // Webhook содержит локальное хранилище метрик и базу данных для сервера.
@@ -15,16 +17,36 @@ type Webhook struct {
 	Database   interfaces.Storage
 	File       interfaces.Storage
 	Config     *config.ServerConfig
+
+	// History содержит историю значений метрик, может отсутствовать.
+	History interfaces.HistoryStorage
+	// Alerting содержит движок оповещений, может отсутствовать.
+	Alerting interfaces.Alerting
+	// Stream содержит рассылку событий обновления метрик и оповещений,
+	// может отсутствовать.
+	Stream interfaces.Broadcaster
+	// Failures содержит счётчики неудачных отправок уведомлений,
+	// могут отсутствовать.
+	Failures []interfaces.FailureReporter
+
+	spec *openapi.Document // разобранная спецификация REST API v2
 }
 
-// NewWebhook создаёт новое хранилище сервера.
-func NewWebhook(ctx context.Context, memStorage interfaces.MetricStorage, database interfaces.Storage, file interfaces.Storage, cfg *config.ServerConfig) *Webhook {
+// NewWebhook создаёт новое хранилище сервера и разбирает встроенную
+// спецификацию REST API v2.
+func NewWebhook(ctx context.Context, memStorage interfaces.MetricStorage, database interfaces.Storage, file interfaces.Storage, cfg *config.ServerConfig) (*Webhook, error) {
+	spec, err := openapi.Load(openapi.Spec())
+	if err != nil {
+		return nil, fmt.Errorf("NewWebhook: load OpenAPI specification failed %w", err)
+	}
+
 	return &Webhook{
 		MemStorage: memStorage,
 		Database:   database,
 		File:       file,
 		Config:     cfg,
-	}
+		spec:       spec,
+	}, nil
 }
 
 // Route инициализирует обработчики запросов сервера.
@@ -45,8 +67,40 @@ func (h *Webhook) Route(ctx context.Context) *chi.Mux {
 	r.Post("/update/{metricType}/{metricName}/{metricValue}", h.HandlePostMetric)
 
 	r.Get("/ping", h.HandlePing)
+	r.Get("/api/stats", h.HandleGetStats)
 
 	r.Post("/updates/", h.HandlePostUpdates)
 
+	r.Get("/api/metrics", h.HandleGetMetrics)
+	r.Delete("/api/metrics/{metricName}", h.HandleDeleteMetric)
+
+	r.Get("/api/history/{metricName}", h.HandleGetHistory)
+	r.Get("/api/stream", h.HandleStream)
+
+	r.Route("/api/rules", func(r chi.Router) {
+		r.Get("/", h.HandleGetRules)
+		r.Post("/", h.HandlePostRule)
+		r.Get("/{ruleID}", h.HandleGetRule)
+		r.Put("/{ruleID}", h.HandlePutRule)
+		r.Delete("/{ruleID}", h.HandleDeleteRule)
+	})
+
+	r.Route("/api/alerts", func(r chi.Router) {
+		r.Get("/", h.HandleGetAlerts)
+		r.Get("/history", h.HandleGetAlertsHistory)
+		r.Post("/{fingerprint}/ack", h.HandleAckAlert)
+	})
+
+	r.Route("/api/silences", func(r chi.Router) {
+		r.Get("/", h.HandleGetSilences)
+		r.Post("/", h.HandlePostSilence)
+		r.Get("/{silenceID}", h.HandleGetSilence)
+		r.Delete("/{silenceID}", h.HandleDeleteSilence)
+	})
+
+	r.Route(apiPrefix, func(r chi.Router) {
+		h.routeAPI(r, h.spec)
+	})
+
 	return r
 }
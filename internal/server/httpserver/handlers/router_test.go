@@ -2,6 +2,8 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -71,7 +73,9 @@ func TestWebhook_HandleMain(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			ms.Metrics = tc.existedValues
 
-			h := NewWebhook(ctx, ms, mockDB, nil, cfg)
+			h, err := NewWebhook(ctx, ms, mockDB, nil, cfg)
+
+			require.NoError(t, err)
 			ts := httptest.NewServer(h.Route(ctx))
 			defer ts.Close()
 
@@ -95,7 +99,9 @@ func TestCounterPost(t *testing.T) {
 	defer ctrl.Finish()
 	mockDB := mocks.NewMockStorage(ctrl)
 
-	h := NewWebhook(ctx, ms, mockDB, nil, cfg)
+	h, err := NewWebhook(ctx, ms, mockDB, nil, cfg)
+
+	require.NoError(t, err)
 	ts := httptest.NewServer(h.Route(ctx))
 	defer ts.Close()
 
@@ -115,7 +121,7 @@ func TestCounterPost(t *testing.T) {
 			target: "/update/counter//42",
 			want: want{
 				code:        http.StatusNotFound,
-				contentType: "text/plain",
+				contentType: "application/json",
 			},
 		},
 		{
@@ -124,7 +130,7 @@ func TestCounterPost(t *testing.T) {
 			target: "/update/counter/someMetric/42.1",
 			want: want{
 				code:        http.StatusBadRequest,
-				contentType: "text/plain",
+				contentType: "application/json",
 			},
 		},
 		{
@@ -158,7 +164,9 @@ func TestGaugePost(t *testing.T) {
 	defer ctrl.Finish()
 	mockDB := mocks.NewMockStorage(ctrl)
 
-	h := NewWebhook(ctx, ms, mockDB, nil, cfg)
+	h, err := NewWebhook(ctx, ms, mockDB, nil, cfg)
+
+	require.NoError(t, err)
 	ts := httptest.NewServer(h.Route(ctx))
 	defer ts.Close()
 
@@ -178,7 +186,7 @@ func TestGaugePost(t *testing.T) {
 			target: "/update/gauge//42",
 			want: want{
 				code:        http.StatusNotFound,
-				contentType: "text/plain",
+				contentType: "application/json",
 			},
 		},
 		{
@@ -187,7 +195,7 @@ func TestGaugePost(t *testing.T) {
 			target: "/update/gauge/someMetric/42e",
 			want: want{
 				code:        http.StatusBadRequest,
-				contentType: "text/plain",
+				contentType: "application/json",
 			},
 		},
 		{
@@ -255,8 +263,8 @@ func TestGaugeGet(t *testing.T) {
 			},
 			want: want{
 				code:        http.StatusNotFound,
-				contentType: "text/plain",
-				body:        "",
+				contentType: "application/json",
+				body:        `{"code":"not_found","message":"metric not found: anotherMetric","metric":"anotherMetric"}`,
 			},
 		},
 		{
@@ -268,8 +276,8 @@ func TestGaugeGet(t *testing.T) {
 			},
 			want: want{
 				code:        http.StatusNotImplemented,
-				contentType: "text/plain",
-				body:        "",
+				contentType: "application/json",
+				body:        `{"code":"unknown_type","message":"unknown metric type: yota","metric":"someMetric"}`,
 			},
 		},
 	}
@@ -277,7 +285,9 @@ func TestGaugeGet(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			ms.Metrics = tc.existedValues
 
-			h := NewWebhook(ctx, ms, mockDB, nil, cfg)
+			h, err := NewWebhook(ctx, ms, mockDB, nil, cfg)
+
+			require.NoError(t, err)
 			ts := httptest.NewServer(h.Route(ctx))
 			defer ts.Close()
 
@@ -290,3 +300,76 @@ func TestGaugeGet(t *testing.T) {
 		})
 	}
 }
+
+func TestSyncStorePost(t *testing.T) {
+	ctx := context.Background()
+	ms := storage.NewMemStorage(ctx)
+	cfg := &config.ServerConfig{
+		Database:      "postgres://localhost/metrics",
+		StoreInterval: 0,
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockDB := mocks.NewMockStorage(ctrl)
+
+	h, err := NewWebhook(ctx, ms, mockDB, nil, cfg)
+
+	require.NoError(t, err)
+	ts := httptest.NewServer(h.Route(ctx))
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		method   string
+		target   string
+		saveErr  error
+		want     int
+		wantCode string
+	}{
+		{
+			name:    "saved",
+			method:  http.MethodPost,
+			target:  "/update/gauge/someMetric/10.1",
+			saveErr: nil,
+			want:    http.StatusOK,
+		},
+		{
+			name:     "counter_save_failed",
+			method:   http.MethodPost,
+			target:   "/update/counter/someCounter/5",
+			saveErr:  errors.New("connection refused"),
+			want:     http.StatusInternalServerError,
+			wantCode: storage.CodeNotPersisted,
+		},
+		{
+			name:     "save_failed",
+			method:   http.MethodPost,
+			target:   "/update/gauge/someMetric/10.1",
+			saveErr:  errors.New("connection refused"),
+			want:     http.StatusInternalServerError,
+			wantCode: storage.CodeNotPersisted,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockDB.EXPECT().Save(gomock.Any(), gomock.Any()).Return(tc.saveErr)
+
+			resp, body := testRequest(t, ts, tc.method, tc.target)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tc.want, resp.StatusCode)
+			if tc.wantCode != "" {
+				var got errorResponse
+				require.NoError(t, json.Unmarshal([]byte(body), &got))
+				assert.Equal(t, tc.wantCode, got.Code)
+			}
+		})
+	}
+
+	// изменение применено к хранилищу, несмотря на ошибку сохранения,
+	// поэтому повторная отправка учла бы значение счётчика дважды
+	value, err := ms.Get(ctx, "counter", "someCounter")
+	require.NoError(t, err)
+	assert.Equal(t, "5", value)
+}
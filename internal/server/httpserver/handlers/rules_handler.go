@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/pavlegich/metrics-alerting/internal/alerting"
+	"github.com/pavlegich/metrics-alerting/internal/entities"
+	"github.com/pavlegich/metrics-alerting/internal/infra/logger"
+	"go.uber.org/zap"
+)
+
+// HandleGetRules обрабатывает запрос на получение списка правил оповещений.
+func (h *Webhook) HandleGetRules(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if h.Alerting == nil {
+		logger.Log.Error("HandleGetRules: alerting is not used")
+		writeNotFound(w, "alerting is not used")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, h.Alerting.ListRules(ctx))
+}
+
+// HandleGetRule обрабатывает запрос на получение правила оповещения.
+func (h *Webhook) HandleGetRule(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if h.Alerting == nil {
+		logger.Log.Error("HandleGetRule: alerting is not used")
+		writeNotFound(w, "alerting is not used")
+		return
+	}
+
+	rule, err := h.Alerting.GetRule(ctx, chi.URLParam(r, "ruleID"))
+	if err != nil {
+		logger.Log.Error("HandleGetRule: get rule failed", zap.Error(err))
+		writeStatusError(w, ruleErrorStatus(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, rule)
+}
+
+// HandlePostRule обрабатывает запрос на создание правила оповещения.
+func (h *Webhook) HandlePostRule(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if h.Alerting == nil {
+		logger.Log.Error("HandlePostRule: alerting is not used")
+		writeNotFound(w, "alerting is not used")
+		return
+	}
+
+	req, err := decodeRule(r)
+	if err != nil {
+		logger.Log.Error("HandlePostRule: decoding error", zap.Error(err))
+		writeBadRequest(w, "invalid request body")
+		return
+	}
+
+	rule, err := h.Alerting.CreateRule(ctx, req)
+	if err != nil {
+		logger.Log.Error("HandlePostRule: create rule failed", zap.Error(err))
+		writeStatusError(w, ruleErrorStatus(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, rule)
+}
+
+// HandlePutRule обрабатывает запрос на изменение правила оповещения.
+func (h *Webhook) HandlePutRule(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if h.Alerting == nil {
+		logger.Log.Error("HandlePutRule: alerting is not used")
+		writeNotFound(w, "alerting is not used")
+		return
+	}
+
+	req, err := decodeRule(r)
+	if err != nil {
+		logger.Log.Error("HandlePutRule: decoding error", zap.Error(err))
+		writeBadRequest(w, "invalid request body")
+		return
+	}
+
+	rule, err := h.Alerting.UpdateRule(ctx, chi.URLParam(r, "ruleID"), req)
+	if err != nil {
+		logger.Log.Error("HandlePutRule: update rule failed", zap.Error(err))
+		writeStatusError(w, ruleErrorStatus(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, rule)
+}
+
+// HandleDeleteRule обрабатывает запрос на удаление правила оповещения.
+func (h *Webhook) HandleDeleteRule(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if h.Alerting == nil {
+		logger.Log.Error("HandleDeleteRule: alerting is not used")
+		writeNotFound(w, "alerting is not used")
+		return
+	}
+
+	if err := h.Alerting.DeleteRule(ctx, chi.URLParam(r, "ruleID")); err != nil {
+		logger.Log.Error("HandleDeleteRule: delete rule failed", zap.Error(err))
+		writeStatusError(w, ruleErrorStatus(err), err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodeRule десериализует правило оповещения из тела запроса.
+func decodeRule(r *http.Request) (entities.Rule, error) {
+	var buf bytes.Buffer
+	var rule entities.Rule
+
+	_, err := buf.ReadFrom(r.Body)
+	defer r.Body.Close()
+	if err != nil {
+		return rule, err
+	}
+	if err := json.Unmarshal(buf.Bytes(), &rule); err != nil {
+		return rule, err
+	}
+	return rule, nil
+}
+
+// ruleErrorStatus возвращает код ответа для ошибки работы с правилами.
+func ruleErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, alerting.ErrRuleNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, alerting.ErrRuleExists), errors.Is(err, alerting.ErrRuleReadOnly):
+		return http.StatusConflict
+	case errors.Is(err, alerting.ErrInvalidRule):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeJSON сериализует данные и отправляет их в ответе с указанным кодом.
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	respJSON, err := json.Marshal(v)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(respJSON)
+}
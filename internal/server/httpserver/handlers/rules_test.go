@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pavlegich/metrics-alerting/internal/alerting"
+	"github.com/pavlegich/metrics-alerting/internal/infra/config"
+	"github.com/pavlegich/metrics-alerting/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhook_HandleRules(t *testing.T) {
+	ctx := context.Background()
+	ms := storage.NewMemStorage(ctx)
+	cfg := &config.ServerConfig{}
+
+	h, err := NewWebhook(ctx, ms, nil, nil, cfg)
+
+	require.NoError(t, err)
+	h.Alerting = alerting.NewEngine(ctx, ms, nil)
+	ts := httptest.NewServer(h.Route(ctx))
+	defer ts.Close()
+
+	rule := `{"id":"heap","name":"HighHeap","metric_type":"gauge","metric_name":"HeapAlloc",` +
+		`"operator":">","threshold":100,"for":"2m0s"}`
+
+	type want struct {
+		code int
+		body string
+	}
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		want   want
+	}{
+		{
+			name:   "create",
+			method: http.MethodPost,
+			target: "/api/rules",
+			body:   rule,
+			want:   want{code: http.StatusCreated, body: rule},
+		},
+		{
+			name:   "create_existed",
+			method: http.MethodPost,
+			target: "/api/rules",
+			body:   rule,
+			want:   want{code: http.StatusConflict},
+		},
+		{
+			name:   "create_invalid",
+			method: http.MethodPost,
+			target: "/api/rules",
+			body:   `{"name":"Bad","metric_type":"gauge","metric_name":"HeapAlloc","operator":"~"}`,
+			want:   want{code: http.StatusBadRequest},
+		},
+		{
+			name:   "list",
+			method: http.MethodGet,
+			target: "/api/rules",
+			want:   want{code: http.StatusOK, body: "[" + rule + "]"},
+		},
+		{
+			name:   "update",
+			method: http.MethodPut,
+			target: "/api/rules/heap",
+			body:   strings.Replace(rule, "100", "200", 1),
+			want:   want{code: http.StatusOK, body: strings.Replace(rule, "100", "200", 1)},
+		},
+		{
+			name:   "get",
+			method: http.MethodGet,
+			target: "/api/rules/heap",
+			want:   want{code: http.StatusOK, body: strings.Replace(rule, "100", "200", 1)},
+		},
+		{
+			name:   "delete",
+			method: http.MethodDelete,
+			target: "/api/rules/heap",
+			want:   want{code: http.StatusNoContent},
+		},
+		{
+			name:   "get_deleted",
+			method: http.MethodGet,
+			target: "/api/rules/heap",
+			want:   want{code: http.StatusNotFound},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequestWithContext(ctx, tc.method, ts.URL+tc.target, strings.NewReader(tc.body))
+			require.NoError(t, err)
+
+			resp, err := ts.Client().Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.want.code, resp.StatusCode)
+			if tc.want.body != "" {
+				assert.JSONEq(t, tc.want.body, string(body))
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/pavlegich/metrics-alerting/internal/alerting"
+	"github.com/pavlegich/metrics-alerting/internal/entities"
+	"github.com/pavlegich/metrics-alerting/internal/infra/logger"
+	"go.uber.org/zap"
+)
+
+// HandleGetSilences обрабатывает запрос на получение списка тишин.
+func (h *Webhook) HandleGetSilences(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if h.Alerting == nil {
+		logger.Log.Error("HandleGetSilences: alerting is not used")
+		writeNotFound(w, "alerting is not used")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, h.Alerting.ListSilences(ctx))
+}
+
+// HandleGetSilence обрабатывает запрос на получение тишины.
+func (h *Webhook) HandleGetSilence(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if h.Alerting == nil {
+		logger.Log.Error("HandleGetSilence: alerting is not used")
+		writeNotFound(w, "alerting is not used")
+		return
+	}
+
+	silence, err := h.Alerting.GetSilence(ctx, chi.URLParam(r, "silenceID"))
+	if err != nil {
+		logger.Log.Error("HandleGetSilence: get silence failed", zap.Error(err))
+		writeStatusError(w, silenceErrorStatus(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, silence)
+}
+
+// HandlePostSilence обрабатывает запрос на создание тишины.
+func (h *Webhook) HandlePostSilence(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if h.Alerting == nil {
+		logger.Log.Error("HandlePostSilence: alerting is not used")
+		writeNotFound(w, "alerting is not used")
+		return
+	}
+
+	var buf bytes.Buffer
+	var req entities.Silence
+
+	_, err := buf.ReadFrom(r.Body)
+	defer r.Body.Close()
+	if err != nil {
+		logger.Log.Error("HandlePostSilence: read body error")
+		writeBadRequest(w, "read body failed")
+		return
+	}
+	if err := json.Unmarshal(buf.Bytes(), &req); err != nil {
+		logger.Log.Error("HandlePostSilence: decoding error", zap.Error(err))
+		writeBadRequest(w, "invalid request body")
+		return
+	}
+
+	silence, err := h.Alerting.CreateSilence(ctx, req)
+	if err != nil {
+		logger.Log.Error("HandlePostSilence: create silence failed", zap.Error(err))
+		writeStatusError(w, silenceErrorStatus(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, silence)
+}
+
+// HandleDeleteSilence обрабатывает запрос на удаление тишины.
+func (h *Webhook) HandleDeleteSilence(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if h.Alerting == nil {
+		logger.Log.Error("HandleDeleteSilence: alerting is not used")
+		writeNotFound(w, "alerting is not used")
+		return
+	}
+
+	if err := h.Alerting.DeleteSilence(ctx, chi.URLParam(r, "silenceID")); err != nil {
+		logger.Log.Error("HandleDeleteSilence: delete silence failed", zap.Error(err))
+		writeStatusError(w, silenceErrorStatus(err), err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// silenceErrorStatus возвращает код ответа для ошибки работы с тишинами.
+func silenceErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, alerting.ErrSilenceNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, alerting.ErrInvalidSilence):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pavlegich/metrics-alerting/internal/alerting"
+	"github.com/pavlegich/metrics-alerting/internal/entities"
+	"github.com/pavlegich/metrics-alerting/internal/infra/config"
+	"github.com/pavlegich/metrics-alerting/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhook_HandleSilences(t *testing.T) {
+	ctx := context.Background()
+	ms := storage.NewMemStorage(ctx)
+	cfg := &config.ServerConfig{}
+
+	h, err := NewWebhook(ctx, ms, nil, nil, cfg)
+
+	require.NoError(t, err)
+	h.Alerting = alerting.NewEngine(ctx, ms, nil)
+	ts := httptest.NewServer(h.Route(ctx))
+	defer ts.Close()
+
+	do := func(method, target, body string) (int, []byte) {
+		req, err := http.NewRequestWithContext(ctx, method, ts.URL+target, strings.NewReader(body))
+		require.NoError(t, err)
+		resp, err := ts.Client().Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		respBody, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, respBody
+	}
+
+	code, _ := do(http.MethodPost, "/api/silences", `{"matchers":[],"created_by":"ops"}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, body := do(http.MethodPost, "/api/silences", `{"matchers":[{"name":"alertname","value":"HighCPU"}],`+
+		`"ends_at":"2099-01-01T00:00:00Z","created_by":"ops","comment":"deploy"}`)
+	require.Equal(t, http.StatusCreated, code)
+
+	var created entities.Silence
+	require.NoError(t, json.Unmarshal(body, &created))
+	assert.NotEmpty(t, created.ID)
+	assert.Equal(t, "deploy", created.Comment)
+
+	code, body = do(http.MethodGet, "/api/silences/"+created.ID, "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, string(body), `"created_by":"ops"`)
+
+	code, _ = do(http.MethodDelete, "/api/silences/"+created.ID, "")
+	assert.Equal(t, http.StatusNoContent, code)
+
+	code, _ = do(http.MethodGet, "/api/silences/"+created.ID, "")
+	assert.Equal(t, http.StatusNotFound, code)
+}
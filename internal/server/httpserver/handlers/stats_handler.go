@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/pavlegich/metrics-alerting/internal/entities"
+	"github.com/pavlegich/metrics-alerting/internal/interfaces"
+)
+
+// statsResponse содержит статистику работы сервера.
+type statsResponse struct {
+	Storage              *entities.SaveStats `json:"storage,omitempty"`               // сохранение метрик в базу данных
+	NotificationFailures map[string]uint64   `json:"notification_failures,omitempty"` // неудачные отправки уведомлений по получателям
+	StreamDropped        *uint64             `json:"stream_dropped,omitempty"`        // отключённые медленные подписчики потока событий
+}
+
+// HandleGetStats отправляет статистику работы сервера в JSON формате.
+// Статистика сохранения метрик отправляется при использовании базы данных,
+// неудачные отправки уведомлений и эскалаций суммируются по получателям,
+// количество отключённых подписчиков отправляется при наличии потока событий.
+func (h *Webhook) HandleGetStats(w http.ResponseWriter, r *http.Request) {
+	var resp statsResponse
+
+	if reporter, ok := h.Database.(interfaces.SaveStatsReporter); ok && h.Config.Database != "" {
+		stats := reporter.Stats()
+		resp.Storage = &stats
+	}
+	for _, reporter := range h.Failures {
+		for receiver, n := range reporter.Failures() {
+			if resp.NotificationFailures == nil {
+				resp.NotificationFailures = make(map[string]uint64)
+			}
+			resp.NotificationFailures[receiver] += n
+		}
+	}
+
+	if reporter, ok := h.Stream.(interfaces.DropReporter); ok {
+		dropped := reporter.Dropped()
+		resp.StreamDropped = &dropped
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
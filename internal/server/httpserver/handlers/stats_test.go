@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pavlegich/metrics-alerting/internal/broadcast"
+	"github.com/pavlegich/metrics-alerting/internal/infra/config"
+	"github.com/pavlegich/metrics-alerting/internal/interfaces"
+	"github.com/pavlegich/metrics-alerting/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhook_HandleGetStats(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		target   string
+		cfg      *config.ServerConfig
+		failures []interfaces.FailureReporter
+		stream   interfaces.Broadcaster
+		want     string
+	}{
+		{
+			name:   "without_database",
+			target: "/api/stats",
+			cfg:    &config.ServerConfig{},
+			want:   `{}`,
+		},
+		{
+			name:   "database",
+			target: "/api/stats",
+			cfg:    &config.ServerConfig{Database: "postgres://localhost/metrics"},
+			want:   `{"storage":{"saves":0,"rows_written":0,"last_rows":0,"last_latency":"0s"}}`,
+		},
+		{
+			name:   "notification_failures",
+			target: "/api/stats",
+			cfg:    &config.ServerConfig{},
+			failures: []interfaces.FailureReporter{
+				failureCounter{"slack": 2},
+				failureCounter{"slack": 1, "telegram": 3},
+			},
+			want: `{"notification_failures":{"slack":3,"telegram":3}}`,
+		},
+		{
+			name:   "stream",
+			target: "/api/stats",
+			cfg:    &config.ServerConfig{},
+			stream: broadcast.NewBroker(ctx),
+			want:   `{"stream_dropped":0}`,
+		},
+		{
+			name:   "api_v2",
+			target: "/api/v2/stats",
+			cfg:    &config.ServerConfig{Database: "postgres://localhost/metrics"},
+			want:   `{"storage":{"saves":0,"rows_written":0,"last_rows":0,"last_latency":"0s"}}`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ms := storage.NewMemStorage(ctx)
+			h, err := NewWebhook(ctx, ms, storage.NewDatabase(nil), nil, tc.cfg)
+			require.NoError(t, err)
+			h.Failures = tc.failures
+			h.Stream = tc.stream
+			ts := httptest.NewServer(h.Route(ctx))
+			defer ts.Close()
+
+			resp, err := ts.Client().Get(ts.URL + tc.target)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.JSONEq(t, tc.want, string(body))
+		})
+	}
+}
+
+// failureCounter содержит количество неудачных отправок уведомлений по получателям.
+type failureCounter map[string]uint64
+
+// Failures возвращает количество неудачных отправок уведомлений по получателям.
+func (f failureCounter) Failures() map[string]uint64 {
+	return f
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pavlegich/metrics-alerting/internal/entities"
+	"github.com/pavlegich/metrics-alerting/internal/infra/logger"
+	"go.uber.org/zap"
+)
+
+// streamKeepAlive содержит интервал отправки комментариев, поддерживающих
+// соединение потока событий при отсутствии обновлений.
+const streamKeepAlive = 15 * time.Second
+
+// HandleStream обрабатывает запрос на получение потока обновлений метрик
+// и изменений состояния оповещений в формате Server-Sent Events.
+// События отбираются по параметрам event (metric или alert), type
+// (тип метрики), prefix (префикс имени метрики) и label (условие на метку
+// вида name=value или name=~regex), параметры event, type и label можно повторять.
+// Клиент, не успевающий получать события, отключается.
+func (h *Webhook) HandleStream(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if h.Stream == nil {
+		writeNotFound(w, "stream is not used")
+		return
+	}
+
+	filter, err := parseEventFilter(r)
+	if err != nil {
+		logger.Log.Error("HandleStream: parse filter failed", zap.Error(err))
+		writeBadRequest(w, "invalid event filter")
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	events, cancel := h.Stream.Subscribe(ctx, filter, 0)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		logger.Log.Error("HandleStream: flush failed", zap.Error(err))
+		return
+	}
+
+	ticker := time.NewTicker(streamKeepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case event, ok := <-events:
+			if !ok {
+				// клиент отключён как медленный или сервер останавливается
+				return
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				logger.Log.Error("HandleStream: event marshal failed", zap.Error(err))
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// parseEventFilter формирует условия отбора событий по параметрам запроса.
+func parseEventFilter(r *http.Request) (entities.EventFilter, error) {
+	query := r.URL.Query()
+	filter := entities.EventFilter{Prefix: query.Get("prefix")}
+
+	for _, v := range query["event"] {
+		for _, e := range strings.Split(v, ",") {
+			if e != entities.EventMetric && e != entities.EventAlert {
+				return entities.EventFilter{}, fmt.Errorf("parseEventFilter: unknown event type %q", e)
+			}
+			filter.Events = append(filter.Events, e)
+		}
+	}
+	for _, v := range query["type"] {
+		for _, t := range strings.Split(v, ",") {
+			if t != "gauge" && t != "counter" {
+				return entities.EventFilter{}, fmt.Errorf("parseEventFilter: unknown metric type %q", t)
+			}
+			filter.Types = append(filter.Types, t)
+		}
+	}
+	for _, v := range query["label"] {
+		m, err := parseMatcher(v)
+		if err != nil {
+			return entities.EventFilter{}, fmt.Errorf("parseEventFilter: %w", err)
+		}
+		filter.Matchers = append(filter.Matchers, m)
+	}
+
+	return filter, nil
+}
+
+// parseMatcher разбирает условие на метку вида name=value или name=~regex.
+func parseMatcher(s string) (entities.Matcher, error) {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok || name == "" {
+		return entities.Matcher{}, fmt.Errorf("invalid label matcher %q", s)
+	}
+	m := entities.Matcher{Name: name, Value: value}
+	if strings.HasPrefix(value, "~") {
+		m.Value = strings.TrimPrefix(value, "~")
+		m.IsRegex = true
+		if _, err := regexp.Compile(m.Value); err != nil {
+			return entities.Matcher{}, fmt.Errorf("invalid label matcher regex %q", m.Value)
+		}
+	}
+	return m, nil
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pavlegich/metrics-alerting/internal/broadcast"
+	"github.com/pavlegich/metrics-alerting/internal/entities"
+	"github.com/pavlegich/metrics-alerting/internal/infra/config"
+	"github.com/pavlegich/metrics-alerting/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhook_HandleStream(t *testing.T) {
+	ctx := context.Background()
+	ms := storage.NewMemStorage(ctx)
+	cfg := &config.ServerConfig{}
+
+	broker := broadcast.NewBroker(ctx)
+	ms.AddListener(broker.PublishUpdate)
+
+	h, err := NewWebhook(ctx, ms, nil, nil, cfg)
+
+	require.NoError(t, err)
+	h.Stream = broker
+	ts := httptest.NewServer(h.Route(ctx))
+	defer ts.Close()
+
+	tests := []struct {
+		name   string
+		target string
+		code   int
+	}{
+		{name: "invalid_type", target: "/api/stream?type=histogram", code: http.StatusBadRequest},
+		{name: "invalid_label", target: "/api/stream?label=metric", code: http.StatusBadRequest},
+		{name: "invalid_regex", target: "/api/stream?label=metric=~(", code: http.StatusBadRequest},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, body := testRequest(t, ts, http.MethodGet, tc.target)
+			defer resp.Body.Close()
+			assert.Equal(t, tc.code, resp.StatusCode)
+			assert.JSONEq(t, `{"code":"bad_request","message":"invalid event filter"}`, body)
+		})
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/api/stream?type=gauge&prefix=CPU", nil)
+	require.NoError(t, err)
+	resp, err := ts.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	require.NoError(t, ms.Put(ctx, "counter", "PollCount", "1"))
+	require.NoError(t, ms.Put(ctx, "gauge", "HeapAlloc", "1024"))
+	require.NoError(t, ms.Put(ctx, "gauge", "CPUutilization1", "95"))
+
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "event: metric\n", line)
+	line, err = reader.ReadString('\n')
+	require.NoError(t, err)
+
+	var event entities.Event
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event))
+	require.NotNil(t, event.Metric)
+	assert.Equal(t, "CPUutilization1", event.Metric.ID)
+	assert.Equal(t, 95.0, *event.Metric.Value)
+
+	// остановка рассылки завершает поток
+	broker.Close()
+	_, err = reader.ReadString('\n')
+	require.NoError(t, err)
+	_, err = reader.ReadString('\n')
+	assert.Error(t, err)
+}
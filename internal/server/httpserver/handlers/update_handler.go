@@ -3,6 +3,7 @@ package handlers
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -10,14 +11,39 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/pavlegich/metrics-alerting/internal/entities"
 	"github.com/pavlegich/metrics-alerting/internal/infra/logger"
+	"github.com/pavlegich/metrics-alerting/internal/server"
+	"github.com/pavlegich/metrics-alerting/internal/storage"
+	"go.uber.org/zap"
 )
 
-// HandlePostUpdates обрабатывает и сохраняет полученные метрики.
+// batchErrorResponse содержит ошибку применения пачки метрик и ошибки
+// отклонённых метрик.
+type batchErrorResponse struct {
+	errorResponse
+	entities.BatchResult
+}
+
+// HandlePostUpdates обрабатывает и сохраняет полученные метрики. Сначала
+// проверяются все метрики пачки, затем пачка применяется атомарно: при ошибке
+// в любой метрике ни одна метрика не сохраняется. С параметром mode=best-effort
+// сохраняются корректные метрики. В ответ отправляются количество сохранённых
+// метрик и ошибки отклонённых метрик в JSON формате.
 func (h *Webhook) HandlePostUpdates(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	req := make([]entities.Metrics, 0)
 
+	bestEffort := false
+	switch mode := r.URL.Query().Get("mode"); mode {
+	case "", "atomic":
+	case "best-effort":
+		bestEffort = true
+	default:
+		logger.Log.Error("HandlePostUpdates: unknown mode", zap.String("mode", mode))
+		writeBadRequest(w, "unknown batch mode "+mode)
+		return
+	}
+
 	// десериализуем запрос в структуру модели
 	var buf bytes.Buffer
 
@@ -25,51 +51,42 @@ func (h *Webhook) HandlePostUpdates(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
 	if err != nil {
 		logger.Log.Error("HandlePostUpdates: read body error")
-		w.WriteHeader(http.StatusBadRequest)
+		writeBadRequest(w, "read body failed")
 		return
 	}
 	if err := json.Unmarshal(buf.Bytes(), &req); err != nil {
 		logger.Log.Error("HandlePostUpdates: decoding error")
-		w.WriteHeader(http.StatusBadRequest)
+		writeBadRequest(w, "invalid request body")
 		return
 	}
 
-	for _, metric := range req {
-		// проверяем, то пришел запрос понятного типа
-		if metric.MType != "gauge" && metric.MType != "counter" {
-			logger.Log.Error("HandlePostUpdates: unsupported request type")
-			w.WriteHeader(http.StatusUnprocessableEntity)
-			return
-		}
-		metricType := metric.MType
-
-		// при правильном имени метрики, помещаем метрику в хранилище
-		if metric.ID == "" {
-			logger.Log.Error("HandlePostUpdates: got metric with bad name")
-			w.WriteHeader(http.StatusNotFound)
-			return
+	result, err := h.MemStorage.PutBatch(ctx, req, bestEffort)
+	if err != nil {
+		logger.Log.Error("HandlePostUpdates: metrics put error",
+			zap.Error(err), zap.Int("rejected", len(result.Errors)))
+		status := storageErrorStatus(err)
+		// неподдерживаемый тип метрики в JSON формате, как и в HandlePostUpdate
+		if errors.Is(err, storage.ErrUnknownType) {
+			status = http.StatusUnprocessableEntity
 		}
-		metricName := metric.ID
-
-		var metricValue string
-		switch metric.MType {
-		case "gauge":
-			metricValue = fmt.Sprintf("%v", *metric.Value)
-		case "counter":
-			metricValue = fmt.Sprintf("%v", *metric.Delta)
+		resp := batchErrorResponse{BatchResult: result}
+		resp.Code = storage.ErrorCode(err)
+		resp.Message = err.Error()
+		if len(result.Errors) > 0 {
+			resp.Metric = result.Errors[0].ID
 		}
+		writeJSON(w, status, resp)
+		return
+	}
 
-		status := h.MemStorage.Put(ctx, metricType, metricName, metricValue)
-		if status != http.StatusOK {
-			logger.Log.Error("HandlePostUpdates: metric put error")
-			w.WriteHeader(status)
-			return
-		}
+	// в синхронном режиме сохраняем метрики до ответа клиенту
+	if err := server.SaveSync(ctx, h.Config, h.MemStorage, h.Database, h.File); err != nil {
+		logger.Log.Error("HandlePostUpdates: sync save failed", zap.Error(err))
+		writeNotPersisted(w)
+		return
 	}
 
-	// установим правильный заголовок для типа данных
-	w.Header().Set("Content-Type", "text/plain")
-	w.WriteHeader(http.StatusOK)
+	writeJSON(w, http.StatusOK, result)
 }
 
 // HandlePostMetric обрабатывает и сохраняет полученную метрику.
@@ -79,10 +96,19 @@ func (h *Webhook) HandlePostMetric(w http.ResponseWriter, r *http.Request) {
 	metricType := chi.URLParam(r, "metricType")
 	metricName := chi.URLParam(r, "metricName")
 	metricValue := chi.URLParam(r, "metricValue")
-	status := h.MemStorage.Put(ctx, metricType, metricName, metricValue)
+	if err := h.MemStorage.Put(ctx, metricType, metricName, metricValue); err != nil {
+		logger.Log.Error("HandlePostMetric: metric put error", zap.Error(err))
+		writeError(w, err, metricName)
+		return
+	}
+	if err := server.SaveSync(ctx, h.Config, h.MemStorage, h.Database, h.File); err != nil {
+		logger.Log.Error("HandlePostMetric: sync save failed", zap.Error(err))
+		writeNotPersisted(w)
+		return
+	}
 
 	w.Header().Set("Content-Type", "text/plain")
-	w.WriteHeader(status)
+	w.WriteHeader(http.StatusOK)
 }
 
 // HandlePostUpdate обрабатывает и сохраняет полученную в JSON формате метрику.
@@ -99,19 +125,20 @@ func (h *Webhook) HandlePostUpdate(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
 	if err != nil {
 		logger.Log.Error("HandlePostUpdate: read body error")
-		w.WriteHeader(http.StatusBadRequest)
+		writeBadRequest(w, "read body failed")
 		return
 	}
 	if err := json.Unmarshal(buf.Bytes(), &req); err != nil {
 		logger.Log.Error("HandlePostUpdate: decoding error")
-		w.WriteHeader(http.StatusBadRequest)
+		writeBadRequest(w, "invalid request body")
 		return
 	}
 
 	// проверяем, то пришел запрос понятного типа
 	if req.MType != "gauge" && req.MType != "counter" {
 		logger.Log.Error("unsupported request type")
-		w.WriteHeader(http.StatusUnprocessableEntity)
+		writeErrorStatus(w, http.StatusUnprocessableEntity,
+			fmt.Errorf("%w: %s", storage.ErrUnknownType, req.MType), req.ID)
 		return
 	}
 	metricType := req.MType
@@ -119,31 +146,41 @@ func (h *Webhook) HandlePostUpdate(w http.ResponseWriter, r *http.Request) {
 	// при правильном имени метрики, помещаем метрику в хранилище
 	if req.ID == "" {
 		logger.Log.Error("HandlePostUpdate: got metric with bad name")
-		w.WriteHeader(http.StatusNotFound)
+		writeError(w, fmt.Errorf("%w: metric name is empty", storage.ErrNotFound), "")
 		return
 	}
 	metricName := req.ID
 
 	var metricValue string
-	switch req.MType {
-	case "gauge":
+	switch {
+	case req.MType == "gauge" && req.Value != nil:
 		metricValue = fmt.Sprintf("%v", *req.Value)
-	case "counter":
+	case req.MType == "counter" && req.Delta != nil:
 		metricValue = fmt.Sprintf("%v", *req.Delta)
+	default:
+		logger.Log.Error("HandlePostUpdate: got metric without value")
+		writeError(w, fmt.Errorf("%w: %s value is missing", storage.ErrInvalidValue, req.MType), metricName)
+		return
 	}
 
-	status := h.MemStorage.Put(ctx, metricType, metricName, metricValue)
-	if status != http.StatusOK {
-		logger.Log.Error("HandlePostUpdate: metric put error")
-		w.WriteHeader(status)
+	if err := h.MemStorage.Put(ctx, metricType, metricName, metricValue); err != nil {
+		logger.Log.Error("HandlePostUpdate: metric put error", zap.Error(err))
+		writeError(w, err, metricName)
+		return
+	}
+
+	// в синхронном режиме сохраняем метрики до ответа клиенту
+	if err := server.SaveSync(ctx, h.Config, h.MemStorage, h.Database, h.File); err != nil {
+		logger.Log.Error("HandlePostUpdate: sync save failed", zap.Error(err))
+		writeNotPersisted(w)
 		return
 	}
 
 	// заполняем модель ответа
-	newValue, status := h.MemStorage.Get(ctx, metricType, metricName)
-	if status != http.StatusOK {
-		logger.Log.Error("HandlePostUpdate: metric get error")
-		w.WriteHeader(status)
+	newValue, err := h.MemStorage.Get(ctx, metricType, metricName)
+	if err != nil {
+		logger.Log.Error("HandlePostUpdate: metric get error", zap.Error(err))
+		writeError(w, err, metricName)
 		return
 	}
 
@@ -153,7 +190,7 @@ func (h *Webhook) HandlePostUpdate(w http.ResponseWriter, r *http.Request) {
 	case "gauge":
 		v, err := strconv.ParseFloat(newValue, 64)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
+			writeInternalError(w, "stored metric value is invalid")
 			return
 		}
 		resp = entities.Metrics{
@@ -164,7 +201,7 @@ func (h *Webhook) HandlePostUpdate(w http.ResponseWriter, r *http.Request) {
 	case "counter":
 		v, err := strconv.ParseInt(newValue, 10, 64)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
+			writeInternalError(w, "stored metric value is invalid")
 			return
 		}
 		resp = entities.Metrics{
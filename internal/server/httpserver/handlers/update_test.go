@@ -13,6 +13,7 @@ import (
 	"github.com/pavlegich/metrics-alerting/internal/entities"
 	"github.com/pavlegich/metrics-alerting/internal/infra/config"
 	"github.com/pavlegich/metrics-alerting/internal/storage"
+	"github.com/stretchr/testify/require"
 )
 
 func ExampleWebhook_HandlePostUpdates() {
@@ -26,7 +27,11 @@ func ExampleWebhook_HandlePostUpdates() {
 	cfg := &config.ServerConfig{}
 
 	// Контроллер
-	h := NewWebhook(ctx, ms, nil, nil, cfg)
+	h, err := NewWebhook(ctx, ms, nil, nil, cfg)
+	if err != nil {
+		fmt.Println("create webhook failed", err)
+		return
+	}
 
 	// Запрос к серверу
 	url := `http://localhost:8080/updates/`
@@ -72,7 +77,11 @@ func ExampleWebhook_HandlePostMetric() {
 	cfg := &config.ServerConfig{}
 
 	// Контроллер
-	h := NewWebhook(ctx, ms, nil, nil, cfg)
+	h, err := NewWebhook(ctx, ms, nil, nil, cfg)
+	if err != nil {
+		fmt.Println("create webhook failed", err)
+		return
+	}
 
 	// Запрос к серверу
 	url := `http://localhost:8080/update/gauge/someMetric/10.1`
@@ -101,7 +110,11 @@ func ExampleWebhook_HandlePostUpdate() {
 	cfg := &config.ServerConfig{}
 
 	// Контроллер
-	h := NewWebhook(ctx, ms, nil, nil, cfg)
+	h, err := NewWebhook(ctx, ms, nil, nil, cfg)
+	if err != nil {
+		fmt.Println("create webhook failed", err)
+		return
+	}
 
 	// Подготовка данных для запроса
 	url := `http://localhost:8080/update/`
@@ -151,7 +164,8 @@ func BenchmarkWebhook_HandlePostUpdates(b *testing.B) {
 	cfg := &config.ServerConfig{}
 
 	// Контроллер
-	h := NewWebhook(ctx, ms, nil, nil, cfg)
+	h, err := NewWebhook(ctx, ms, nil, nil, cfg)
+	require.NoError(b, err)
 
 	// Запрос к серверу
 	url := `http://localhost:8080/updates/`
@@ -191,7 +205,8 @@ func BenchmarkWebhook_HandlePostMetric(b *testing.B) {
 	cfg := &config.ServerConfig{}
 
 	// Контроллер
-	h := NewWebhook(ctx, ms, nil, nil, cfg)
+	h, err := NewWebhook(ctx, ms, nil, nil, cfg)
+	require.NoError(b, err)
 
 	// Запрос к серверу
 	url := `http://localhost:8080/update/gauge/someMetric/10.1`
@@ -214,7 +229,8 @@ func BenchmarkWebhook_HandlePostUpdate(b *testing.B) {
 	cfg := &config.ServerConfig{}
 
 	// Контроллер
-	h := NewWebhook(ctx, ms, nil, nil, cfg)
+	h, err := NewWebhook(ctx, ms, nil, nil, cfg)
+	require.NoError(b, err)
 
 	// Подготовка данных для запроса
 	url := `http://localhost:8080/update/`
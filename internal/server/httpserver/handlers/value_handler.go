@@ -3,12 +3,15 @@ package handlers
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/pavlegich/metrics-alerting/internal/entities"
 	"github.com/pavlegich/metrics-alerting/internal/infra/logger"
+	"github.com/pavlegich/metrics-alerting/internal/storage"
 )
 
 // HandleGetMetric обрабатывает запрос на получение метрики,
@@ -18,13 +21,13 @@ func (h *Webhook) HandleGetMetric(w http.ResponseWriter, r *http.Request) {
 
 	metricType := chi.URLParam(r, "metricType")
 	metricName := chi.URLParam(r, "metricName")
-	w.Header().Set("Content-Type", "text/plain")
-	value, status := h.MemStorage.Get(ctx, metricType, metricName)
-	if status != http.StatusOK {
-		w.WriteHeader(status)
+	value, err := h.MemStorage.Get(ctx, metricType, metricName)
+	if err != nil {
+		writeError(w, err, metricName)
 		return
 	}
-	w.WriteHeader(status)
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusOK)
 	w.Write([]byte(value))
 }
 
@@ -32,6 +35,7 @@ func (h *Webhook) HandleGetMetric(w http.ResponseWriter, r *http.Request) {
 // Обработчик принимает в JSON формате название и тип метрики,
 // в случае успешного получения значения метрики из хранилища,
 // формирует и отправляет ответ с метрикой в JSON формате.
+// Ответ содержит время последнего обновления метрики и признак устаревания.
 func (h *Webhook) HandlePostValue(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
@@ -42,19 +46,20 @@ func (h *Webhook) HandlePostValue(w http.ResponseWriter, r *http.Request) {
 	_, err := buf.ReadFrom(r.Body)
 	if err != nil {
 		logger.Log.Error("read body error")
-		w.WriteHeader(http.StatusBadRequest)
+		writeBadRequest(w, "read body failed")
 		return
 	}
 	if err := json.Unmarshal(buf.Bytes(), &req); err != nil {
 		logger.Log.Error("decoding error")
-		w.WriteHeader(http.StatusBadRequest)
+		writeBadRequest(w, "invalid request body")
 		return
 	}
 
 	// проверяем, что пришел запрос понятного типа
 	if req.MType != "gauge" && req.MType != "counter" {
 		logger.Log.Error("unsupported request type")
-		w.WriteHeader(http.StatusUnprocessableEntity)
+		writeErrorStatus(w, http.StatusUnprocessableEntity,
+			fmt.Errorf("%w: %s", storage.ErrUnknownType, req.MType), req.ID)
 		return
 	}
 	metricType := req.MType
@@ -62,17 +67,17 @@ func (h *Webhook) HandlePostValue(w http.ResponseWriter, r *http.Request) {
 	// при правильном имени метрики, помещаем метрику в хранилище
 	if req.ID == "" {
 		logger.Log.Error("got metric with bad name")
-		w.WriteHeader(http.StatusNotFound)
+		writeError(w, fmt.Errorf("%w: metric name is empty", storage.ErrNotFound), "")
 		return
 	}
 	metricName := req.ID
 
 	// заполняем модель ответа
-	metricValue, status := h.MemStorage.Get(ctx, metricType, metricName)
+	metricValue, err := h.MemStorage.Get(ctx, metricType, metricName)
 
-	if status != http.StatusOK {
+	if err != nil {
 		logger.Log.Error("metric get error")
-		w.WriteHeader(status)
+		writeError(w, err, metricName)
 		return
 	}
 
@@ -81,7 +86,7 @@ func (h *Webhook) HandlePostValue(w http.ResponseWriter, r *http.Request) {
 	case "gauge":
 		v, err := strconv.ParseFloat(metricValue, 64)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
+			writeInternalError(w, "stored metric value is invalid")
 			return
 		}
 		resp = entities.Metrics{
@@ -92,7 +97,7 @@ func (h *Webhook) HandlePostValue(w http.ResponseWriter, r *http.Request) {
 	case "counter":
 		v, err := strconv.ParseInt(metricValue, 10, 64)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
+			writeInternalError(w, "stored metric value is invalid")
 			return
 		}
 		resp = entities.Metrics{
@@ -101,6 +106,10 @@ func (h *Webhook) HandlePostValue(w http.ResponseWriter, r *http.Request) {
 			Delta: &v,
 		}
 	}
+	if updated, ok := h.MemStorage.GetUpdated(ctx, metricName); ok {
+		resp.UpdatedAt = &updated
+		resp.Stale = h.isStale(updated, time.Now())
+	}
 
 	// сериализуем ответ сервера
 	respJSON, err := json.Marshal(resp)
@@ -8,11 +8,15 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/pavlegich/metrics-alerting/internal/entities"
 	"github.com/pavlegich/metrics-alerting/internal/infra/config"
 	"github.com/pavlegich/metrics-alerting/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func ExampleWebhook_HandleGetMetric() {
@@ -29,7 +33,11 @@ func ExampleWebhook_HandleGetMetric() {
 	cfg := &config.ServerConfig{}
 
 	// Контроллер
-	h := NewWebhook(ctx, ms, nil, nil, cfg)
+	h, err := NewWebhook(ctx, ms, nil, nil, cfg)
+	if err != nil {
+		fmt.Println("create webhook failed", err)
+		return
+	}
 
 	// Запрос к серверу
 	url := `http://localhost:8080/value/gauge/Gauger`
@@ -68,7 +76,11 @@ func ExampleWebhook_HandlePostValue() {
 	cfg := &config.ServerConfig{}
 
 	// Контроллер
-	h := NewWebhook(ctx, ms, nil, nil, cfg)
+	h, err := NewWebhook(ctx, ms, nil, nil, cfg)
+	if err != nil {
+		fmt.Println("create webhook failed", err)
+		return
+	}
 
 	// Подготовка данных для запроса
 	url := `http://localhost:8080/value/`
@@ -119,7 +131,8 @@ func BenchmarkWebhook_HandleGetMetric(b *testing.B) {
 	cfg := &config.ServerConfig{}
 
 	// Контроллер
-	h := NewWebhook(ctx, ms, nil, nil, cfg)
+	h, err := NewWebhook(ctx, ms, nil, nil, cfg)
+	require.NoError(b, err)
 
 	// Запрос к серверу
 	url := `http://localhost:8080/value/gauge/Gauger`
@@ -145,7 +158,8 @@ func BenchmarkWebhook_HandlePostValue(b *testing.B) {
 	cfg := &config.ServerConfig{}
 
 	// Контроллер
-	h := NewWebhook(ctx, ms, nil, nil, cfg)
+	h, err := NewWebhook(ctx, ms, nil, nil, cfg)
+	require.NoError(b, err)
 
 	// Подготовка данных для запроса
 	url := `http://localhost:8080/value/`
@@ -166,3 +180,48 @@ func BenchmarkWebhook_HandlePostValue(b *testing.B) {
 		h.Route(ctx).ServeHTTP(w, req)
 	}
 }
+
+func TestWebhook_StaleMetrics(t *testing.T) {
+	ctx := context.Background()
+	ms := storage.NewMemStorage(ctx)
+	require.NoError(t, ms.Put(ctx, "gauge", "Alloc", "1.5"))
+	ms.Metrics["Restored"] = "7"
+
+	tests := []struct {
+		name       string
+		staleAfter time.Duration
+		wantStale  bool
+	}{
+		{name: "disabled", staleAfter: 0, wantStale: false},
+		{name: "fresh", staleAfter: time.Hour, wantStale: false},
+		{name: "stale", staleAfter: time.Nanosecond, wantStale: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h, err := NewWebhook(ctx, ms, nil, nil, &config.ServerConfig{StaleAfter: config.Duration(tc.staleAfter)})
+			require.NoError(t, err)
+			time.Sleep(time.Millisecond)
+
+			body, err := json.Marshal(entities.Metrics{ID: "Alloc", MType: "gauge"})
+			require.NoError(t, err)
+			req := httptest.NewRequest(http.MethodPost, "/value/", bytes.NewBuffer(body))
+			w := httptest.NewRecorder()
+			h.Route(ctx).ServeHTTP(w, req)
+			resp := w.Result()
+			defer resp.Body.Close()
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+
+			var got entities.Metrics
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&got))
+			require.NotNil(t, got.UpdatedAt)
+			assert.Equal(t, tc.wantStale, got.Stale)
+
+			req = httptest.NewRequest(http.MethodGet, "/", nil)
+			w = httptest.NewRecorder()
+			h.Route(ctx).ServeHTTP(w, req)
+			page := w.Body.String()
+			assert.Contains(t, page, "<td>Restored</td><td>7</td><td></td>")
+			assert.Equal(t, tc.wantStale, strings.Contains(page, `<tr id="Alloc" class="stale"><td>Alloc</td>`))
+		})
+	}
+}
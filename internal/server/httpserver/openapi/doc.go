@@ -0,0 +1,3 @@
+// Пакет openapi содержит спецификацию OpenAPI REST API v2 сервера
+// и проверку запросов по этой спецификации.
+package openapi
@@ -0,0 +1,230 @@
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// spec содержит спецификацию OpenAPI REST API v2 сервера.
+//
+//go:embed openapi.json
+var spec []byte
+
+// Префиксы ссылок на компоненты спецификации.
+const (
+	schemaRefPrefix    = "#/components/schemas/"
+	parameterRefPrefix = "#/components/parameters/"
+)
+
+type (
+	// Document содержит используемую для проверки запросов часть документа
+	// спецификации OpenAPI 3.0.
+	Document struct {
+		OpenAPI    string               `json:"openapi"`    // версия спецификации
+		Paths      map[string]*PathItem `json:"paths"`      // пути относительно адреса API
+		Components Components           `json:"components"` // переиспользуемые компоненты
+	}
+
+	// Components содержит переиспользуемые схемы и параметры спецификации.
+	Components struct {
+		Schemas    map[string]*Schema    `json:"schemas"`    // схемы данных
+		Parameters map[string]*Parameter `json:"parameters"` // параметры запросов
+	}
+
+	// PathItem содержит операции пути и общие для них параметры.
+	PathItem struct {
+		Parameters []*Parameter `json:"parameters"`
+		Get        *Operation   `json:"get"`
+		Put        *Operation   `json:"put"`
+		Post       *Operation   `json:"post"`
+		Delete     *Operation   `json:"delete"`
+	}
+
+	// Operation содержит параметры и тело запроса операции.
+	Operation struct {
+		OperationID string       `json:"operationId"` // идентификатор операции
+		Parameters  []*Parameter `json:"parameters"`  // параметры запроса
+		RequestBody *RequestBody `json:"requestBody"` // тело запроса
+	}
+
+	// Parameter содержит параметр запроса в пути или строке запроса.
+	Parameter struct {
+		Ref      string  `json:"$ref"`     // ссылка на параметр из компонентов
+		Name     string  `json:"name"`     // имя параметра
+		In       string  `json:"in"`       // расположение параметра: path или query
+		Required bool    `json:"required"` // параметр обязателен
+		Schema   *Schema `json:"schema"`   // схема значения параметра
+	}
+
+	// RequestBody содержит описание тела запроса по типам содержимого.
+	RequestBody struct {
+		Required bool                  `json:"required"`
+		Content  map[string]*MediaType `json:"content"`
+	}
+
+	// MediaType содержит схему тела запроса указанного типа содержимого.
+	MediaType struct {
+		Schema *Schema `json:"schema"`
+	}
+
+	// Schema содержит поддерживаемое подмножество схемы JSON Schema:
+	// ссылки на компоненты, типы, перечисления, ограничения чисел, строк
+	// и массивов, свойства объектов и allOf. Форматы date-time и duration
+	// проверяются разбором значения. В additionalProperties поддерживается
+	// только схема, логическое значение не поддерживается.
+	Schema struct {
+		Ref                  string             `json:"$ref"`
+		Type                 string             `json:"type"`
+		Format               string             `json:"format"`
+		Nullable             bool               `json:"nullable"`
+		Enum                 []any              `json:"enum"`
+		Minimum              *float64           `json:"minimum"`
+		Maximum              *float64           `json:"maximum"`
+		MinLength            *int               `json:"minLength"`
+		MaxLength            *int               `json:"maxLength"`
+		Pattern              string             `json:"pattern"`
+		Items                *Schema            `json:"items"`
+		MinItems             *int               `json:"minItems"`
+		MaxItems             *int               `json:"maxItems"`
+		Required             []string           `json:"required"`
+		Properties           map[string]*Schema `json:"properties"`
+		AdditionalProperties *Schema            `json:"additionalProperties"`
+		AllOf                []*Schema          `json:"allOf"`
+	}
+)
+
+// Spec возвращает спецификацию OpenAPI REST API v2 сервера в JSON формате.
+func Spec() []byte {
+	return spec
+}
+
+// Load разбирает документ спецификации и проверяет, что все ссылки
+// на схемы и параметры указывают на существующие компоненты.
+func Load(data []byte) (*Document, error) {
+	var doc Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("Load: spec unmarshal %w", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return nil, fmt.Errorf("Load: unsupported openapi version %q", doc.OpenAPI)
+	}
+
+	for path, item := range doc.Paths {
+		if item == nil {
+			return nil, fmt.Errorf("Load: path %s is empty", path)
+		}
+		params := item.Parameters
+		for _, op := range item.operations() {
+			params = append(params, op.Parameters...)
+			if op.RequestBody == nil {
+				continue
+			}
+			for _, mt := range op.RequestBody.Content {
+				if err := doc.checkSchema(mt.Schema); err != nil {
+					return nil, fmt.Errorf("Load: path %s: %w", path, err)
+				}
+			}
+		}
+		for _, p := range params {
+			param, err := doc.parameter(p)
+			if err != nil {
+				return nil, fmt.Errorf("Load: path %s: %w", path, err)
+			}
+			if err := doc.checkSchema(param.Schema); err != nil {
+				return nil, fmt.Errorf("Load: path %s: parameter %s: %w", path, param.Name, err)
+			}
+		}
+	}
+	for name, s := range doc.Components.Schemas {
+		if err := doc.checkSchema(s); err != nil {
+			return nil, fmt.Errorf("Load: schema %s: %w", name, err)
+		}
+	}
+
+	return &doc, nil
+}
+
+// operation возвращает операцию пути для метода запроса.
+func (p *PathItem) operation(method string) *Operation {
+	switch method {
+	case http.MethodGet:
+		return p.Get
+	case http.MethodPut:
+		return p.Put
+	case http.MethodPost:
+		return p.Post
+	case http.MethodDelete:
+		return p.Delete
+	default:
+		return nil
+	}
+}
+
+// operations возвращает все операции пути.
+func (p *PathItem) operations() []*Operation {
+	ops := make([]*Operation, 0, 4)
+	for _, op := range []*Operation{p.Get, p.Put, p.Post, p.Delete} {
+		if op != nil {
+			ops = append(ops, op)
+		}
+	}
+	return ops
+}
+
+// parameter возвращает параметр с учётом ссылки на компоненты.
+func (d *Document) parameter(p *Parameter) (*Parameter, error) {
+	if p.Ref == "" {
+		return p, nil
+	}
+	param, ok := d.Components.Parameters[strings.TrimPrefix(p.Ref, parameterRefPrefix)]
+	if !strings.HasPrefix(p.Ref, parameterRefPrefix) || !ok {
+		return nil, fmt.Errorf("unresolved parameter reference %s", p.Ref)
+	}
+	return param, nil
+}
+
+// schema возвращает схему с учётом ссылки на компоненты.
+func (d *Document) schema(s *Schema) (*Schema, error) {
+	if s == nil || s.Ref == "" {
+		return s, nil
+	}
+	resolved, ok := d.Components.Schemas[strings.TrimPrefix(s.Ref, schemaRefPrefix)]
+	if !strings.HasPrefix(s.Ref, schemaRefPrefix) || !ok {
+		return nil, fmt.Errorf("unresolved schema reference %s", s.Ref)
+	}
+	return resolved, nil
+}
+
+// checkSchema проверяет ссылки схемы и вложенных в неё схем. Ссылки
+// на компоненты не раскрываются, так как компоненты проверяются отдельно.
+func (d *Document) checkSchema(s *Schema) error {
+	if s == nil {
+		return nil
+	}
+	if s.Ref != "" {
+		_, err := d.schema(s)
+		return err
+	}
+	if s.Pattern != "" {
+		if _, err := regexp.Compile(s.Pattern); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", s.Pattern, err)
+		}
+	}
+
+	nested := make([]*Schema, 0, len(s.Properties)+len(s.AllOf)+2)
+	nested = append(nested, s.Items, s.AdditionalProperties)
+	nested = append(nested, s.AllOf...)
+	for _, p := range s.Properties {
+		nested = append(nested, p)
+	}
+	for _, n := range nested {
+		if err := d.checkSchema(n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,394 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidRequest возвращается, если запрос не соответствует спецификации.
+var ErrInvalidRequest = errors.New("invalid request")
+
+// Validator возвращает middleware, которое проверяет запросы к путям
+// спецификации относительно префикса prefix: параметры пути и строки запроса
+// и тело запроса. При несоответствии спецификации вызывается fail с ошибкой,
+// оборачивающей ErrInvalidRequest. Запросы к путям и методам, отсутствующим
+// в спецификации, передаются дальше без проверки.
+func (d *Document) Validator(prefix string, fail func(w http.ResponseWriter, r *http.Request, err error)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := d.Validate(r, prefix); err != nil {
+				fail(w, r, err)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Validate проверяет запрос по операции спецификации, соответствующей
+// методу и пути запроса без префикса. Прочитанное тело запроса
+// восстанавливается для дальнейшей обработки.
+func (d *Document) Validate(r *http.Request, prefix string) error {
+	path, ok := strings.CutPrefix(r.URL.Path, prefix)
+	if !ok {
+		return nil
+	}
+	item, pathParams := d.match(path)
+	if item == nil {
+		return nil
+	}
+	op := item.operation(r.Method)
+	if op == nil {
+		return nil
+	}
+
+	query := r.URL.Query()
+	for _, p := range append(append([]*Parameter(nil), item.Parameters...), op.Parameters...) {
+		param, err := d.parameter(p)
+		if err != nil {
+			return fmt.Errorf("Validate: %w", err)
+		}
+
+		var raw string
+		var found bool
+		switch param.In {
+		case "path":
+			raw, found = pathParams[param.Name]
+		case "query":
+			found = query.Has(param.Name)
+			raw = query.Get(param.Name)
+		default:
+			continue
+		}
+		if !found {
+			if param.Required {
+				return fmt.Errorf("%w: %s parameter %s is required", ErrInvalidRequest, param.In, param.Name)
+			}
+			continue
+		}
+		if err := d.validateParameter(param, raw); err != nil {
+			return fmt.Errorf("%w: %s parameter %s: %s", ErrInvalidRequest, param.In, param.Name, err)
+		}
+	}
+
+	if op.RequestBody != nil {
+		if err := d.validateBody(r, op.RequestBody); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// match находит путь спецификации для пути запроса и значения параметров пути.
+// При нескольких подходящих путях выбирается путь с наибольшим количеством
+// совпавших постоянных сегментов.
+func (d *Document) match(path string) (*PathItem, map[string]string) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+
+	var best *PathItem
+	var bestParams map[string]string
+	bestLiterals := -1
+
+	templates := make([]string, 0, len(d.Paths))
+	for tmpl := range d.Paths {
+		templates = append(templates, tmpl)
+	}
+	sort.Strings(templates)
+
+	for _, tmpl := range templates {
+		parts := strings.Split(strings.Trim(tmpl, "/"), "/")
+		if len(parts) != len(segments) {
+			continue
+		}
+		params := make(map[string]string)
+		literals := 0
+		matched := true
+		for i, part := range parts {
+			if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+				if segments[i] == "" {
+					matched = false
+					break
+				}
+				params[part[1:len(part)-1]] = segments[i]
+				continue
+			}
+			if part != segments[i] {
+				matched = false
+				break
+			}
+			literals++
+		}
+		if matched && literals > bestLiterals {
+			best, bestParams, bestLiterals = d.Paths[tmpl], params, literals
+		}
+	}
+	return best, bestParams
+}
+
+// validateParameter преобразует строковое значение параметра к типу схемы
+// и проверяет его по схеме.
+func (d *Document) validateParameter(param *Parameter, raw string) error {
+	s, err := d.schema(param.Schema)
+	if err != nil {
+		return err
+	}
+	if s == nil {
+		return nil
+	}
+
+	var value any = raw
+	switch s.Type {
+	case "integer":
+		v, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("must be an integer")
+		}
+		value = json.Number(strconv.FormatInt(v, 10))
+	case "number":
+		if _, err := strconv.ParseFloat(raw, 64); err != nil {
+			return fmt.Errorf("must be a number")
+		}
+		value = json.Number(raw)
+	case "boolean":
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("must be a boolean")
+		}
+		value = v
+	}
+	return d.validateValue(s, value, "")
+}
+
+// validateBody проверяет тело запроса в JSON формате и восстанавливает его.
+func (d *Document) validateBody(r *http.Request, body *RequestBody) error {
+	var buf bytes.Buffer
+	if r.Body != nil {
+		if _, err := buf.ReadFrom(r.Body); err != nil {
+			return fmt.Errorf("%w: read body failed", ErrInvalidRequest)
+		}
+		r.Body.Close()
+	}
+	r.Body = io.NopCloser(bytes.NewReader(buf.Bytes()))
+
+	if buf.Len() == 0 {
+		if body.Required {
+			return fmt.Errorf("%w: request body is required", ErrInvalidRequest)
+		}
+		return nil
+	}
+
+	mt, ok := body.Content["application/json"]
+	if !ok {
+		return nil
+	}
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		if media, _, err := mime.ParseMediaType(ct); err != nil || media != "application/json" {
+			return fmt.Errorf("%w: content type must be application/json", ErrInvalidRequest)
+		}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(buf.Bytes()))
+	dec.UseNumber()
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return fmt.Errorf("%w: body is not valid JSON", ErrInvalidRequest)
+	}
+	if err := d.validateValue(mt.Schema, value, "body"); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidRequest, err)
+	}
+	return nil
+}
+
+// validateValue проверяет значение JSON по схеме. Путь значения указывается
+// в описании ошибки.
+func (d *Document) validateValue(s *Schema, value any, path string) error {
+	s, err := d.schema(s)
+	if err != nil {
+		return err
+	}
+	if s == nil {
+		return nil
+	}
+	for _, sub := range s.AllOf {
+		if err := d.validateValue(sub, value, path); err != nil {
+			return err
+		}
+	}
+	if value == nil {
+		if s.Nullable || s.Type == "" {
+			return nil
+		}
+		return pathError(path, "must not be null")
+	}
+	if len(s.Enum) > 0 && !inEnum(s.Enum, value) {
+		return pathError(path, fmt.Sprintf("must be one of %s", enumString(s.Enum)))
+	}
+
+	switch s.Type {
+	case "object":
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return pathError(path, "must be an object")
+		}
+		return d.validateObject(s, obj, path)
+	case "array":
+		arr, ok := value.([]any)
+		if !ok {
+			return pathError(path, "must be an array")
+		}
+		if s.MinItems != nil && len(arr) < *s.MinItems {
+			return pathError(path, fmt.Sprintf("must contain at least %d items", *s.MinItems))
+		}
+		if s.MaxItems != nil && len(arr) > *s.MaxItems {
+			return pathError(path, fmt.Sprintf("must contain at most %d items", *s.MaxItems))
+		}
+		for i, item := range arr {
+			if err := d.validateValue(s.Items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return pathError(path, "must be a string")
+		}
+		return validateString(s, str, path)
+	case "integer", "number":
+		num, ok := value.(json.Number)
+		if !ok {
+			return pathError(path, "must be a "+s.Type)
+		}
+		return validateNumber(s, num, path)
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return pathError(path, "must be a boolean")
+		}
+	}
+	return nil
+}
+
+// validateObject проверяет обязательные и описанные свойства объекта.
+func (d *Document) validateObject(s *Schema, obj map[string]any, path string) error {
+	for _, name := range s.Required {
+		if _, ok := obj[name]; !ok {
+			return pathError(joinPath(path, name), "is required")
+		}
+	}
+
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		prop, ok := s.Properties[name]
+		if !ok {
+			prop = s.AdditionalProperties
+		}
+		if err := d.validateValue(prop, obj[name], joinPath(path, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateString проверяет длину, шаблон и формат строки.
+func validateString(s *Schema, str string, path string) error {
+	if s.MinLength != nil && len([]rune(str)) < *s.MinLength {
+		return pathError(path, fmt.Sprintf("must be at least %d characters long", *s.MinLength))
+	}
+	if s.MaxLength != nil && len([]rune(str)) > *s.MaxLength {
+		return pathError(path, fmt.Sprintf("must be at most %d characters long", *s.MaxLength))
+	}
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil || !re.MatchString(str) {
+			return pathError(path, fmt.Sprintf("must match pattern %s", s.Pattern))
+		}
+	}
+	switch s.Format {
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, str); err != nil {
+			return pathError(path, "must be a date-time in RFC 3339 format")
+		}
+	case "duration":
+		if _, err := time.ParseDuration(str); err != nil {
+			return pathError(path, "must be a duration such as 1m30s")
+		}
+	}
+	return nil
+}
+
+// validateNumber проверяет тип и диапазон числа.
+func validateNumber(s *Schema, num json.Number, path string) error {
+	if s.Type == "integer" {
+		if _, err := num.Int64(); err != nil {
+			return pathError(path, "must be an integer")
+		}
+	}
+	v, err := num.Float64()
+	if err != nil {
+		return pathError(path, "must be a number")
+	}
+	if s.Minimum != nil && v < *s.Minimum {
+		return pathError(path, fmt.Sprintf("must be >= %v", *s.Minimum))
+	}
+	if s.Maximum != nil && v > *s.Maximum {
+		return pathError(path, fmt.Sprintf("must be <= %v", *s.Maximum))
+	}
+	return nil
+}
+
+// inEnum проверяет, входит ли значение в перечисление. Числа сравниваются
+// по значению, так как тело запроса разбирается в json.Number.
+func inEnum(enum []any, value any) bool {
+	if num, ok := value.(json.Number); ok {
+		f, err := num.Float64()
+		if err != nil {
+			return false
+		}
+		value = f
+	}
+	for _, e := range enum {
+		if reflect.DeepEqual(e, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// enumString возвращает перечисление в виде строки.
+func enumString(enum []any) string {
+	values := make([]string, 0, len(enum))
+	for _, e := range enum {
+		values = append(values, fmt.Sprintf("%q", fmt.Sprint(e)))
+	}
+	return "[" + strings.Join(values, ", ") + "]"
+}
+
+// joinPath добавляет имя свойства к пути значения.
+func joinPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// pathError формирует ошибку проверки значения по указанному пути.
+func pathError(path string, msg string) error {
+	if path == "" {
+		return errors.New(msg)
+	}
+	return fmt.Errorf("%s %s", path, msg)
+}
@@ -0,0 +1,155 @@
+package openapi
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{name: "embedded_spec", data: string(Spec())},
+		{name: "invalid_json", data: `{`, wantErr: true},
+		{name: "unsupported_version", data: `{"openapi":"2.0","paths":{}}`, wantErr: true},
+		{name: "unresolved_schema_ref", data: `{"openapi":"3.0.3","paths":{"/a":{"post":{
+			"requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/Missing"}}}}}}}}`,
+			wantErr: true},
+		{name: "unresolved_parameter_ref", data: `{"openapi":"3.0.3","paths":{"/a":{"get":{
+			"parameters":[{"$ref":"#/components/parameters/Missing"}]}}}}`,
+			wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			doc, err := Load([]byte(tc.data))
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotEmpty(t, doc.Paths)
+		})
+	}
+}
+
+func TestDocument_Validate(t *testing.T) {
+	doc, err := Load(Spec())
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		method  string
+		target  string
+		body    string
+		wantErr string
+	}{
+		{name: "list_metrics", method: http.MethodGet, target: "/api/v2/metrics?type=gauge&limit=10"},
+		{name: "list_metrics_invalid_type", method: http.MethodGet, target: "/api/v2/metrics?type=histogram",
+			wantErr: "query parameter type"},
+		{name: "list_metrics_limit_not_integer", method: http.MethodGet, target: "/api/v2/metrics?limit=ten",
+			wantErr: "must be an integer"},
+		{name: "list_metrics_limit_too_large", method: http.MethodGet, target: "/api/v2/metrics?limit=5000",
+			wantErr: "must be <= 1000"},
+		{name: "update_metrics", method: http.MethodPost, target: "/api/v2/metrics?mode=best-effort",
+			body: `[{"id":"Alloc","type":"gauge","value":1.5},{"id":"PollCount","type":"counter","delta":2}]`},
+		{name: "update_metrics_invalid_mode", method: http.MethodPost, target: "/api/v2/metrics?mode=partial",
+			body: `[]`, wantErr: "query parameter mode"},
+		{name: "update_metrics_not_array", method: http.MethodPost, target: "/api/v2/metrics",
+			body: `{"id":"Alloc"}`, wantErr: "body must be an array"},
+		{name: "update_metrics_missing_id", method: http.MethodPost, target: "/api/v2/metrics",
+			body: `[{"type":"gauge","value":1}]`, wantErr: "body[0].id is required"},
+		{name: "update_metrics_fractional_delta", method: http.MethodPost, target: "/api/v2/metrics",
+			body: `[{"id":"PollCount","type":"counter","delta":1.5}]`, wantErr: "body[0].delta must be an integer"},
+		{name: "update_metrics_missing_body", method: http.MethodPost, target: "/api/v2/metrics",
+			wantErr: "request body is required"},
+		{name: "update_metrics_invalid_json", method: http.MethodPost, target: "/api/v2/metrics",
+			body: `[`, wantErr: "body is not valid JSON"},
+		{name: "put_metric", method: http.MethodPut, target: "/api/v2/metrics/Alloc",
+			body: `{"type":"gauge","value":3}`},
+		{name: "put_metric_unknown_type", method: http.MethodPut, target: "/api/v2/metrics/Alloc",
+			body: `{"type":"histogram","value":3}`, wantErr: "body.type must be one of"},
+		{name: "history", method: http.MethodGet,
+			target: "/api/v2/metrics/Alloc/history?from=2024-01-01T00:00:00Z&resolution=1m"},
+		{name: "history_invalid_from", method: http.MethodGet, target: "/api/v2/metrics/Alloc/history?from=yesterday",
+			wantErr: "query parameter from"},
+		{name: "create_rule", method: http.MethodPost, target: "/api/v2/rules",
+			body: `{"name":"high","kind":"threshold","metric_name":"Alloc","threshold":10,"for":"1m","labels":{"team":"ops"}}`},
+		{name: "create_rule_invalid_duration", method: http.MethodPost, target: "/api/v2/rules",
+			body: `{"name":"high","for":"soon"}`, wantErr: "body.for must be a duration"},
+		{name: "create_rule_invalid_alpha", method: http.MethodPost, target: "/api/v2/rules",
+			body: `{"name":"smooth","kind":"ewma","alpha":2}`, wantErr: "body.alpha must be <= 1"},
+		{name: "create_rule_invalid_label", method: http.MethodPost, target: "/api/v2/rules",
+			body: `{"name":"high","labels":{"team":1}}`, wantErr: "body.labels.team must be a string"},
+		{name: "alerts_history_invalid_state", method: http.MethodGet, target: "/api/v2/alerts/history?state=sleeping",
+			wantErr: "query parameter state"},
+		{name: "ack_alert_missing_by", method: http.MethodPost, target: "/api/v2/alerts/abc/ack",
+			body: `{}`, wantErr: "body.by is required"},
+		{name: "create_silence_without_matchers", method: http.MethodPost, target: "/api/v2/silences",
+			body: `{"matchers":[],"created_by":"ops"}`, wantErr: "body.matchers must contain at least 1 items"},
+		{name: "unknown_path", method: http.MethodGet, target: "/api/v2/unknown"},
+		{name: "unknown_method", method: http.MethodPatch, target: "/api/v2/metrics"},
+		{name: "outside_prefix", method: http.MethodGet, target: "/api/metrics?limit=ten"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+			r.Header.Set("Content-Type", "application/json")
+
+			err := doc.Validate(r, "/api/v2")
+			if tc.wantErr != "" {
+				require.ErrorIs(t, err, ErrInvalidRequest)
+				assert.Contains(t, err.Error(), tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestDocument_Validator(t *testing.T) {
+	doc, err := Load(Spec())
+	require.NoError(t, err)
+
+	var gotBody string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		gotBody = string(body)
+		w.WriteHeader(http.StatusOK)
+	})
+	fail := func(w http.ResponseWriter, r *http.Request, err error) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+	h := doc.Validator("/api/v2", fail)(next)
+
+	tests := []struct {
+		name     string
+		body     string
+		wantCode int
+		wantBody string
+	}{
+		{name: "valid", body: `{"type":"counter","delta":1}`, wantCode: http.StatusOK,
+			wantBody: `{"type":"counter","delta":1}`},
+		{name: "invalid", body: `{"delta":1}`, wantCode: http.StatusBadRequest},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			gotBody = ""
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPut, "/api/v2/metrics/PollCount", strings.NewReader(tc.body))
+			h.ServeHTTP(w, r)
+
+			assert.Equal(t, tc.wantCode, w.Code)
+			// тело запроса восстанавливается после проверки
+			assert.Equal(t, tc.wantBody, gotBody)
+		})
+	}
+}
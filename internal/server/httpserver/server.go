@@ -3,6 +3,7 @@ package httpserver
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
@@ -18,8 +19,16 @@ type Server struct {
 }
 
 func NewServer(ctx context.Context, memStorage interfaces.MetricStorage, database interfaces.Storage,
-	file interfaces.Storage, cfg *config.ServerConfig) interfaces.Server {
-	controller := ctrl.NewWebhook(ctx, memStorage, database, file, cfg)
+	file interfaces.Storage, history interfaces.HistoryStorage, alerting interfaces.Alerting,
+	stream interfaces.Broadcaster, failures []interfaces.FailureReporter, cfg *config.ServerConfig) (interfaces.Server, error) {
+	controller, err := ctrl.NewWebhook(ctx, memStorage, database, file, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("NewServer: %w", err)
+	}
+	controller.History = history
+	controller.Alerting = alerting
+	controller.Stream = stream
+	controller.Failures = failures
 
 	// Роутер
 	r := chi.NewRouter()
@@ -35,7 +44,7 @@ func NewServer(ctx context.Context, memStorage interfaces.MetricStorage, databas
 	return &Server{
 		server: srv,
 		config: cfg,
-	}
+	}, nil
 }
 
 func (s *Server) GetAddress(ctx context.Context) string {
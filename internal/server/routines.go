@@ -6,21 +6,28 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/pavlegich/metrics-alerting/internal/entities"
+	"github.com/pavlegich/metrics-alerting/internal/infra/config"
+	"github.com/pavlegich/metrics-alerting/internal/infra/logger"
 	"github.com/pavlegich/metrics-alerting/internal/interfaces"
+	"github.com/pavlegich/metrics-alerting/internal/storage"
+	"go.uber.org/zap"
 )
 
 // SaveToFileRoutine сохраняет метрики в файл с указанным интервалом времени.
+// При подключённом журнале предзаписи каждое сохранение является контрольной точкой,
+// после которой журнал усекается.
 func SaveToFileRoutine(ctx context.Context, ms interfaces.MetricStorage, db interfaces.Storage,
-	f interfaces.Storage, store time.Duration) error {
+	f interfaces.Storage, wal *storage.WAL, store time.Duration) error {
 	for {
 		select {
 		case <-ctx.Done():
-			if err := f.Save(context.Background(), ms); err != nil {
+			if err := checkpoint(context.Background(), ms, f, wal); err != nil {
 				return fmt.Errorf("SaveToFileRoutine: metrics save error %w", err)
 			}
 			return nil
 		default:
-			if err := f.Save(ctx, ms); err != nil {
+			if err := checkpoint(ctx, ms, f, wal); err != nil {
 				return fmt.Errorf("SaveToFileRoutine: metrics save error %w", err)
 			}
 			time.Sleep(store)
@@ -29,20 +36,95 @@ func SaveToFileRoutine(ctx context.Context, ms interfaces.MetricStorage, db inte
 }
 
 // SaveToDBRoutine сохраняет метрики в базу данных с указанным интервалом времени.
+// При подключённом журнале предзаписи каждое сохранение является контрольной точкой,
+// после которой журнал усекается.
 func SaveToDBRoutine(ctx context.Context, ms interfaces.MetricStorage, db interfaces.Storage,
-	f interfaces.Storage, store time.Duration) error {
+	f interfaces.Storage, wal *storage.WAL, store time.Duration) error {
 	for {
 		select {
 		case <-ctx.Done():
-			if err := db.Save(context.Background(), ms); err != nil {
+			if err := checkpoint(context.Background(), ms, db, wal); err != nil {
 				return fmt.Errorf("SaveToDBRoutine: metrics save error %w", err)
 			}
 			return nil
 		default:
-			if err := db.Save(ctx, ms); err != nil {
+			if err := checkpoint(ctx, ms, db, wal); err != nil {
 				return fmt.Errorf("SaveToDBRoutine: metrics save error %w", err)
 			}
 			time.Sleep(store)
 		}
 	}
 }
+
+// checkpoint сохраняет снимок метрик в хранилище и усекает журнал предзаписи.
+func checkpoint(ctx context.Context, ms interfaces.MetricStorage, s interfaces.Storage, wal *storage.WAL) error {
+	if wal == nil {
+		return s.Save(ctx, ms)
+	}
+	return wal.Checkpoint(ctx, func(ctx context.Context) error {
+		return s.Save(ctx, ms)
+	})
+}
+
+// RollupRoutine сворачивает историю метрик в агрегаты с указанным интервалом времени,
+// сохраняет созданные агрегаты в базу данных и удаляет из неё устаревшие агрегаты.
+// Ошибки базы данных не прерывают сворачивание истории в памяти.
+func RollupRoutine(ctx context.Context, h *storage.History, rs interfaces.RollupStorage,
+	interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			rollups := h.Rollup(ctx, now)
+			if rs == nil {
+				continue
+			}
+
+			if len(rollups) > 0 {
+				if err := rs.SaveRollups(ctx, rollups); err != nil {
+					logger.Log.Error("RollupRoutine: rollups save error", zap.Error(err))
+				}
+			}
+
+			retention := h.Retention()
+			if err := rs.DeleteRollups(ctx, entities.ResolutionMinute, now.Add(-retention.Minute)); err != nil {
+				logger.Log.Error("RollupRoutine: expired rollups delete error", zap.Error(err))
+			}
+			if err := rs.DeleteRollups(ctx, entities.ResolutionHour, now.Add(-retention.Hour)); err != nil {
+				logger.Log.Error("RollupRoutine: expired rollups delete error", zap.Error(err))
+			}
+		}
+	}
+}
+
+// IsSyncStore проверяет, запущен ли сервер в синхронном режиме хранения,
+// при котором каждое обновление метрик сохраняется до ответа клиенту.
+func IsSyncStore(cfg *config.ServerConfig) bool {
+	return cfg.StoreInterval == 0 && (cfg.Database != "" || cfg.KVPath != "" || cfg.StoragePath != "")
+}
+
+// SaveSync сохраняет метрики в базу данных, встроенное хранилище или файл, если сервер
+// запущен в синхронном режиме хранения. В остальных случаях метрики
+// сохраняются горутинами SaveToFileRoutine и SaveToDBRoutine.
+// Метод вызывается после применения изменения к хранилищу, поэтому ошибка
+// сохранения оборачивает storage.ErrNotPersisted: клиент не должен повторять
+// запрос, иначе значение счётчика будет учтено дважды.
+func SaveSync(ctx context.Context, cfg *config.ServerConfig, ms interfaces.MetricStorage,
+	db interfaces.Storage, f interfaces.Storage) error {
+	if !IsSyncStore(cfg) {
+		return nil
+	}
+
+	s := f
+	if cfg.Database != "" || cfg.KVPath != "" {
+		s = db
+	}
+	if err := s.Save(ctx, ms); err != nil {
+		return fmt.Errorf("SaveSync: metrics save error %w: %w", storage.ErrNotPersisted, err)
+	}
+	return nil
+}
@@ -4,38 +4,55 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"net/http"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/pavlegich/metrics-alerting/internal/entities"
+	"github.com/pavlegich/metrics-alerting/internal/infra/logger"
 	"github.com/pavlegich/metrics-alerting/internal/interfaces"
+	"go.uber.org/zap"
 )
 
-// DBMetric содержит название и значение метрики
+// DBMetric содержит название, тип и значение метрики
 // для хранения в базе данных.
 type DBMetric struct {
 	ID    string
+	MType string
 	Value string
 }
 
+// upsertBatchSize содержит максимальное количество метрик в одном запросе вставки.
+const upsertBatchSize = 500
+
 // Database содержит информацию о базе данных.
 type Database struct {
-	db *sql.DB
+	db    *sql.DB
+	mu    *sync.Mutex
+	stats entities.SaveStats
 }
 
 // NewDatabase создаёт новый объект Database для хранения метрик сервера.
 func NewDatabase(db *sql.DB) *Database {
 	return &Database{
 		db: db,
+		mu: &sync.Mutex{},
 	}
 }
 
-// Save сохраняет все метрики из хранилища сервера в базу данных.
+// Save сохраняет в базу данных метрики, изменённые после последнего
+// успешного сохранения, и удаляет удалённые метрики.
+// Метрики записываются многострочными запросами вставки.
 func (d *Database) Save(ctx context.Context, ms interfaces.MetricStorage) error {
-	// Получение всех метрик из хранилища
-	metrics := ms.GetAll(ctx)
-	DBMetrics := make(map[string]string)
-	for m, v := range metrics {
-		DBMetrics[m] = v
+	start := time.Now()
+
+	// Получение изменённых и удалённых метрик из хранилища
+	DBMetrics, version := ms.GetDirty(ctx)
+	deleted := ms.GetDeleted(ctx)
+	if len(DBMetrics) == 0 && len(deleted) == 0 {
+		return nil
 	}
+	types := ms.GetAllTypes(ctx)
 
 	// Проверка базы данных
 	if err := d.db.PingContext(ctx); err != nil {
@@ -48,27 +65,71 @@ func (d *Database) Save(ctx context.Context, ms interfaces.MetricStorage) error
 	}
 	defer tx.Rollback()
 
-	// Сохранение метрик в хранилище
-	statement, err := tx.PrepareContext(ctx, "INSERT INTO storage (id, value) VALUES ($1, $2) ON CONFLICT (id) DO "+
-		"UPDATE SET value=$2 WHERE storage.id=$1")
-	if err != nil {
-		return fmt.Errorf("SaveToDB: insert into table failed %w", err)
+	for _, id := range deleted {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM storage WHERE id = $1", id); err != nil {
+			return fmt.Errorf("SaveToDB: delete metric failed %w", err)
+		}
 	}
-	defer statement.Close()
 
+	// Сохранение метрик в хранилище пачками
+	args := make([]any, 0, 3*upsertBatchSize)
 	for id, value := range DBMetrics {
-		if _, err := statement.ExecContext(ctx, id, value); err != nil {
-			return fmt.Errorf("SaveToDB: statement exec failed %w", err)
+		args = append(args, id, types[id], value)
+		if len(args) == cap(args) {
+			if _, err := tx.ExecContext(ctx, upsertQuery(len(args)/3), args...); err != nil {
+				return fmt.Errorf("SaveToDB: batch insert failed %w", err)
+			}
+			args = args[:0]
+		}
+	}
+	if len(args) > 0 {
+		if _, err := tx.ExecContext(ctx, upsertQuery(len(args)/3), args...); err != nil {
+			return fmt.Errorf("SaveToDB: batch insert failed %w", err)
 		}
 	}
 
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("SaveToDB: commit transaction failed %w", err)
 	}
+	ms.MarkSaved(ctx, version)
+
+	latency := time.Since(start)
+	d.mu.Lock()
+	d.stats.Saves++
+	d.stats.RowsWritten += int64(len(DBMetrics))
+	d.stats.LastRows = len(DBMetrics)
+	d.stats.LastLatency = entities.Duration(latency)
+	d.mu.Unlock()
+
+	logger.Log.Info("SaveToDB: metrics saved",
+		zap.Int("rows", len(DBMetrics)),
+		zap.Duration("latency", latency))
 
 	return nil
 }
 
+// Stats возвращает статистику сохранения метрик в базу данных.
+func (d *Database) Stats() entities.SaveStats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.stats
+}
+
+// upsertQuery формирует запрос вставки или обновления указанного количества метрик.
+func upsertQuery(rows int) string {
+	var b strings.Builder
+	b.WriteString("INSERT INTO storage (id, type, value) VALUES ")
+	for i := 0; i < rows; i++ {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, "($%d, $%d, $%d)", 3*i+1, 3*i+2, 3*i+3)
+	}
+	b.WriteString(" ON CONFLICT (id) DO UPDATE SET type = EXCLUDED.type, value = EXCLUDED.value")
+	return b.String()
+}
+
 // Load получает все метрики из хранилища
 // и сохраняет их в хранилище сервера.
 func (d *Database) Load(ctx context.Context, ms interfaces.MetricStorage) error {
@@ -78,7 +139,7 @@ func (d *Database) Load(ctx context.Context, ms interfaces.MetricStorage) error
 	}
 
 	// Получение метрик из хранилища
-	rows, err := d.db.QueryContext(ctx, "SELECT id, value FROM storage")
+	rows, err := d.db.QueryContext(ctx, "SELECT id, type, value FROM storage")
 	if err != nil {
 		return fmt.Errorf("LoadFromDB: read rows from table failed %w", err)
 	}
@@ -87,7 +148,7 @@ func (d *Database) Load(ctx context.Context, ms interfaces.MetricStorage) error
 	DBMetrics := make([]DBMetric, 0)
 	for rows.Next() {
 		var metric DBMetric
-		err = rows.Scan(&metric.ID, &metric.Value)
+		err = rows.Scan(&metric.ID, &metric.MType, &metric.Value)
 		if err != nil {
 			return fmt.Errorf("LoadFromDB: scan row failed %w", err)
 		}
@@ -101,12 +162,15 @@ func (d *Database) Load(ctx context.Context, ms interfaces.MetricStorage) error
 
 	// Сохранение данных в локальном хранилище
 	for _, metric := range DBMetrics {
-		// Пока все будут gauge, чтобы ошибок с конвертацией не было, тип не хранится в MemStorage
-		if status := ms.Put(ctx, "gauge", metric.ID, metric.Value); status != http.StatusOK {
-			return fmt.Errorf("LoadFromDB: put all metrics status %v", status)
+		if err := ms.Restore(ctx, metric.MType, metric.ID, metric.Value); err != nil {
+			return fmt.Errorf("LoadFromDB: put all metrics failed %w", err)
 		}
 	}
 
+	// Загруженные метрики уже есть в базе данных
+	_, version := ms.GetDirty(ctx)
+	ms.MarkSaved(ctx, version)
+
 	return nil
 }
 
@@ -90,3 +90,31 @@ func TestDatabase_Load(t *testing.T) {
 		})
 	}
 }
+
+func TestDatabase_upsertQuery(t *testing.T) {
+	tests := []struct {
+		name string
+		rows int
+		want string
+	}{
+		{
+			name: "single_row",
+			rows: 1,
+			want: "INSERT INTO storage (id, type, value) VALUES ($1, $2, $3) " +
+				"ON CONFLICT (id) DO UPDATE SET type = EXCLUDED.type, value = EXCLUDED.value",
+		},
+		{
+			name: "multiple_rows",
+			rows: 3,
+			want: "INSERT INTO storage (id, type, value) VALUES ($1, $2, $3), ($4, $5, $6), ($7, $8, $9) " +
+				"ON CONFLICT (id) DO UPDATE SET type = EXCLUDED.type, value = EXCLUDED.value",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := upsertQuery(tt.rows); got != tt.want {
+				t.Errorf("upsertQuery() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
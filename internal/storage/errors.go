@@ -0,0 +1,42 @@
+package storage
+
+import "errors"
+
+var (
+	// ErrNotFound возвращается при отсутствии метрики или пустом имени метрики.
+	ErrNotFound = errors.New("metric not found")
+	// ErrUnknownType возвращается для метрики неизвестного типа.
+	ErrUnknownType = errors.New("unknown metric type")
+	// ErrInvalidValue возвращается при некорректном значении метрики.
+	ErrInvalidValue = errors.New("invalid metric value")
+	// ErrNotPersisted возвращается, если изменение применено к хранилищу,
+	// но не сохранено в синхронном режиме хранения. Повторять такой запрос
+	// нельзя, так как значение счётчика будет учтено дважды.
+	ErrNotPersisted = errors.New("change applied but not persisted, do not retry")
+)
+
+// Коды ошибок хранилища метрик, передаваемые клиентам.
+const (
+	CodeNotFound     = "not_found"
+	CodeUnknownType  = "unknown_type"
+	CodeInvalidValue = "invalid_value"
+	CodeNotPersisted = "not_persisted"
+	CodeInternal     = "internal"
+)
+
+// ErrorCode возвращает код ошибки хранилища метрик. Ошибки, не оборачивающие
+// ошибки хранилища, например ошибки журнала предзаписи, считаются внутренними.
+func ErrorCode(err error) string {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return CodeNotFound
+	case errors.Is(err, ErrUnknownType):
+		return CodeUnknownType
+	case errors.Is(err, ErrInvalidValue):
+		return CodeInvalidValue
+	case errors.Is(err, ErrNotPersisted):
+		return CodeNotPersisted
+	default:
+		return CodeInternal
+	}
+}
@@ -3,51 +3,62 @@ package storage
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"net/http"
 	"os"
+	"path/filepath"
 	"sync"
 
+	"github.com/pavlegich/metrics-alerting/internal/infra/logger"
 	"github.com/pavlegich/metrics-alerting/internal/interfaces"
+	"go.uber.org/zap"
 )
 
-// FileMetrics содержит метрики для хранения в файле.
+// FileMetrics содержит метрики и их типы для хранения в файле.
+// В файлах предыдущих версий сервера типы метрик отсутствуют.
 type FileMetrics struct {
 	Metrics map[string]string `json:"metrics"`
+	Types   map[string]string `json:"types,omitempty"`
 }
 
 // NewFileMetrics создаёт новое хранилище метрик для файла.
 func NewFileMetrics(ctx context.Context) *FileMetrics {
 	return &FileMetrics{
 		Metrics: make(map[string]string),
+		Types:   make(map[string]string),
 	}
 }
 
-// File содержит информацию о пути к файлу.
+// File содержит информацию о пути к файлу и количестве хранимых резервных копий.
 type File struct {
-	path string
-	mu   *sync.Mutex
+	path    string
+	backups int
+	mu      *sync.Mutex
 }
 
 // NewFile создаёт новый объект File для хранения метрик сервера.
-func NewFile(path string) *File {
+// Параметр backups задаёт количество хранимых поколений резервных копий файла.
+func NewFile(path string, backups int) *File {
 	return &File{
-		path: path,
-		mu:   &sync.Mutex{},
+		path:    path,
+		backups: backups,
+		mu:      &sync.Mutex{},
 	}
 }
 
 // Save получает все текущие метрики из хранилища сервера,
-// преобразует их в JSON формат и сохраняет в файл.
+// преобразует их в JSON формат и атомарно сохраняет в файл.
 func (f *File) Save(ctx context.Context, ms interfaces.MetricStorage) error {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
 	// сериализуем структуру в JSON формат
 	metrics := ms.GetAll(ctx)
+	types := ms.GetAllTypes(ctx)
 	storage := NewFileMetrics(ctx)
 	for m, v := range metrics {
 		storage.Metrics[m] = v
+		storage.Types[m] = types[m]
 	}
 
 	data, err := json.Marshal(storage)
@@ -55,38 +66,37 @@ func (f *File) Save(ctx context.Context, ms interfaces.MetricStorage) error {
 		return fmt.Errorf("SaveToFile: data marshal %w", err)
 	}
 	// сохраняем данные в файл
-	if err := os.WriteFile(f.path, data, 0666); err != nil {
+	if err := f.writeAtomic(data); err != nil {
 		return fmt.Errorf("SaveToFile: write file error %w", err)
 	}
 	return nil
 }
 
 // Load получает и конвертирует метрики из JSON формата,
-// сохраняет в хранилище сервера.
+// сохраняет в хранилище сервера. Если основной файл повреждён,
+// метрики загружаются из самой свежей корректной резервной копии.
 func (f *File) Load(ctx context.Context, ms interfaces.MetricStorage) error {
-	if _, err := os.Stat(f.path); err != nil {
-		if _, err := os.Create(f.path); err != nil {
-			return fmt.Errorf("LoadFromFile: file create %w", err)
-		}
-		if err := f.Save(ctx, ms); err != nil {
-			return fmt.Errorf("LoadFromFile: data save %w", err)
-		}
-	}
-	data, err := os.ReadFile(f.path)
+	storage, err := f.read(ctx, f.path)
 	if err != nil {
-		return fmt.Errorf("LoadFromFile: read file error %w", err)
-	}
+		if errors.Is(err, os.ErrNotExist) && !f.hasBackups() {
+			if err := f.Save(ctx, ms); err != nil {
+				return fmt.Errorf("LoadFromFile: data save %w", err)
+			}
+			return nil
+		}
 
-	storage := NewFileMetrics(ctx)
+		logger.Log.Error("LoadFromFile: primary file is corrupted, trying backups",
+			zap.String("path", f.path), zap.Error(err))
 
-	if err := json.Unmarshal(data, &storage); err != nil {
-		return fmt.Errorf("LoadFromFile: data unmarshal %w", err)
+		storage, err = f.readBackup(ctx)
+		if err != nil {
+			return fmt.Errorf("LoadFromFile: %w", err)
+		}
 	}
 
 	for m, v := range storage.Metrics {
-		// Все метрики с типом gauge, чтобы не было проблем с конвертацией
-		if status := ms.Put(ctx, "gauge", m, v); status != http.StatusOK {
-			return fmt.Errorf("LoadFromFile: put metric status %v", status)
+		if err := ms.Restore(ctx, storage.Types[m], m, v); err != nil {
+			return fmt.Errorf("LoadFromFile: put metric failed %w", err)
 		}
 	}
 
@@ -98,3 +108,141 @@ func (f *File) Ping(ctx context.Context) error {
 	_, err := os.Stat(f.path)
 	return err
 }
+
+// read читает и десериализует метрики из указанного файла.
+func (f *File) read(ctx context.Context, path string) (*FileMetrics, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read: read file error %w", err)
+	}
+
+	storage := NewFileMetrics(ctx)
+	if err := json.Unmarshal(data, &storage); err != nil {
+		return nil, fmt.Errorf("read: data unmarshal %w", err)
+	}
+
+	return storage, nil
+}
+
+// readBackup читает метрики из самой свежей корректной резервной копии.
+func (f *File) readBackup(ctx context.Context) (*FileMetrics, error) {
+	for i := 1; i <= f.backups; i++ {
+		storage, err := f.read(ctx, f.backupPath(i))
+		if err == nil {
+			logger.Log.Info("LoadFromFile: metrics restored from backup",
+				zap.String("path", f.backupPath(i)))
+			return storage, nil
+		}
+	}
+	return nil, fmt.Errorf("readBackup: no valid backup found for %s", f.path)
+}
+
+// hasBackups проверяет наличие хотя бы одной резервной копии файла.
+func (f *File) hasBackups() bool {
+	for i := 1; i <= f.backups; i++ {
+		if _, err := os.Stat(f.backupPath(i)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// backupPath возвращает путь к резервной копии файла с указанным номером поколения.
+func (f *File) backupPath(generation int) string {
+	return fmt.Sprintf("%s.%d", f.path, generation)
+}
+
+// rotate сдвигает поколения резервных копий и сохраняет текущий файл
+// в первое поколение. Текущий файл остаётся на месте до замены новым,
+// поэтому основной файл существует в любой момент сохранения.
+func (f *File) rotate() error {
+	if f.backups <= 0 {
+		return nil
+	}
+	if _, err := os.Stat(f.path); err != nil {
+		return nil
+	}
+
+	for i := f.backups - 1; i >= 1; i-- {
+		if err := os.Rename(f.backupPath(i), f.backupPath(i+1)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("rotate: rename backup failed %w", err)
+		}
+	}
+	if err := os.Remove(f.backupPath(1)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("rotate: remove backup failed %w", err)
+	}
+	if err := os.Link(f.path, f.backupPath(1)); err == nil {
+		return nil
+	}
+
+	// файловая система не поддерживает жёсткие ссылки, копируем файл
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return fmt.Errorf("rotate: read file failed %w", err)
+	}
+	if err := writeFileAtomic(f.backupPath(1), data, nil); err != nil {
+		return fmt.Errorf("rotate: copy file failed %w", err)
+	}
+
+	return nil
+}
+
+// writeAtomic атомарно записывает данные в основной файл,
+// предварительно сдвигая поколения резервных копий.
+func (f *File) writeAtomic(data []byte) error {
+	return writeFileAtomic(f.path, data, f.rotate)
+}
+
+// writeFileAtomic записывает данные во временный файл в той же директории,
+// синхронизирует его с диском и атомарно заменяет им указанный файл.
+// Функция beforeRename, если указана, вызывается перед заменой файла.
+func writeFileAtomic(path string, data []byte, beforeRename func() error) error {
+	dir := filepath.Dir(path)
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("writeFileAtomic: create temp file failed %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writeFileAtomic: write temp file failed %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writeFileAtomic: sync temp file failed %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writeFileAtomic: close temp file failed %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0666); err != nil {
+		return fmt.Errorf("writeFileAtomic: chmod temp file failed %w", err)
+	}
+
+	if beforeRename != nil {
+		if err := beforeRename(); err != nil {
+			return fmt.Errorf("writeFileAtomic: %w", err)
+		}
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("writeFileAtomic: rename temp file failed %w", err)
+	}
+
+	return syncDir(dir)
+}
+
+// syncDir синхронизирует с диском содержимое директории,
+// чтобы переименование файла пережило сбой.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("syncDir: open dir failed %w", err)
+	}
+	defer d.Close()
+
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("syncDir: sync dir failed %w", err)
+	}
+	return nil
+}
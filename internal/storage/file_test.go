@@ -2,6 +2,9 @@ package storage
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 )
@@ -13,7 +16,7 @@ func TestFile_New(t *testing.T) {
 	}{
 		{
 			name: "new",
-			want: &FileMetrics{make(map[string]string)},
+			want: &FileMetrics{make(map[string]string), make(map[string]string)},
 		},
 	}
 	for _, tt := range tests {
@@ -52,7 +55,7 @@ func TestFile_Save(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			file := NewFile(tt.args.path)
+			file := NewFile(tt.args.path, 0)
 			ms := NewMemStorage(ctx)
 			ms.Metrics = tt.args.metrics
 
@@ -90,7 +93,7 @@ func TestFile_Load(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			file := NewFile(tt.args.path)
+			file := NewFile(tt.args.path, 0)
 			ms := NewMemStorage(ctx)
 			ms.Metrics = tt.args.metrics
 
@@ -100,3 +103,141 @@ func TestFile_Load(t *testing.T) {
 		})
 	}
 }
+
+func TestFile_SaveAtomic(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "metrics-db.json")
+
+	file := NewFile(filePath, 2)
+	ms := NewMemStorage(ctx)
+	ms.Metrics = map[string]string{"Gauger": "24.1"}
+
+	for i := 0; i < 4; i++ {
+		if err := file.Save(ctx, ms); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	got := make([]string, 0, len(entries))
+	for _, e := range entries {
+		got = append(got, e.Name())
+	}
+	want := []string{"metrics-db.json", "metrics-db.json.1", "metrics-db.json.2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("files in dir = %v, want %v", got, want)
+	}
+}
+
+func TestFile_LoadFromBackup(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		primary string
+		backups []string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:    "primary_valid",
+			primary: `{"metrics":{"Gauger":"1.5"}}`,
+			backups: []string{`{"metrics":{"Gauger":"0.5"}}`},
+			want:    map[string]string{"Gauger": "1.5"},
+			wantErr: false,
+		},
+		{
+			name:    "primary_truncated",
+			primary: `{"metrics":{"Gau`,
+			backups: []string{`{"metrics":{"Gauger":"0.5"}}`},
+			want:    map[string]string{"Gauger": "0.5"},
+			wantErr: false,
+		},
+		{
+			name:    "newest_backup_corrupted",
+			primary: `{"metrics":{"Gau`,
+			backups: []string{`{"metr`, `{"metrics":{"Gauger":"0.25"}}`},
+			want:    map[string]string{"Gauger": "0.25"},
+			wantErr: false,
+		},
+		{
+			name:    "primary_missing",
+			primary: "",
+			backups: []string{`{"metrics":{"Gauger":"0.5"}}`},
+			want:    map[string]string{"Gauger": "0.5"},
+			wantErr: false,
+		},
+		{
+			name:    "all_corrupted",
+			primary: `{"metrics":{"Gau`,
+			backups: []string{`{"metr`},
+			want:    map[string]string{},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "metrics-db.json")
+			if tt.primary != "" {
+				if err := os.WriteFile(filePath, []byte(tt.primary), 0666); err != nil {
+					t.Fatalf("WriteFile() error = %v", err)
+				}
+			}
+			for i, b := range tt.backups {
+				if err := os.WriteFile(fmt.Sprintf("%s.%d", filePath, i+1), []byte(b), 0666); err != nil {
+					t.Fatalf("WriteFile() error = %v", err)
+				}
+			}
+
+			file := NewFile(filePath, len(tt.backups))
+			ms := NewMemStorage(ctx)
+
+			if err := file.Load(ctx, ms); (err != nil) != tt.wantErr {
+				t.Errorf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(ms.Metrics, tt.want) {
+				t.Errorf("Load() metrics = %v, want %v", ms.Metrics, tt.want)
+			}
+		})
+	}
+}
+
+func TestFile_RotateKeepsPrimary(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "metrics-db.json")
+
+	for path, data := range map[string]string{
+		filePath:        `{"metrics":{"Gauger":"3"}}`,
+		filePath + ".1": `{"metrics":{"Gauger":"2"}}`,
+		filePath + ".2": `{"metrics":{"Gauger":"1"}}`,
+	} {
+		if err := os.WriteFile(path, []byte(data), 0666); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+
+	file := NewFile(filePath, 2)
+	if err := file.rotate(); err != nil {
+		t.Fatalf("rotate() error = %v", err)
+	}
+
+	// основной файл не удаляется до замены новым
+	want := map[string]string{
+		filePath:        `{"metrics":{"Gauger":"3"}}`,
+		filePath + ".1": `{"metrics":{"Gauger":"3"}}`,
+		filePath + ".2": `{"metrics":{"Gauger":"2"}}`,
+	}
+	for path, data := range want {
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("ReadFile(%s) error = %v", path, err)
+		}
+		if string(got) != data {
+			t.Errorf("%s = %s, want %s", path, got, data)
+		}
+	}
+}
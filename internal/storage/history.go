@@ -0,0 +1,293 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pavlegich/metrics-alerting/internal/entities"
+)
+
+// Retention содержит периоды хранения истории метрик для каждого разрешения.
+type Retention struct {
+	Raw    time.Duration
+	Minute time.Duration
+	Hour   time.Duration
+}
+
+// series содержит историю значений одной метрики.
+type series struct {
+	mType        string
+	raw          []entities.Point
+	minute       []entities.Point
+	hour         []entities.Point
+	rolledMinute time.Time // начало первой ещё не свёрнутой минуты
+	rolledHour   time.Time // начало первого ещё не свёрнутого часа
+}
+
+// History хранит исходные значения метрик и их агрегаты
+// за минуту и за час с отдельными периодами хранения.
+type History struct {
+	series    map[string]*series
+	retention Retention
+	mu        *sync.RWMutex
+}
+
+// NewHistory создаёт новое хранилище истории метрик.
+func NewHistory(ctx context.Context, retention Retention) *History {
+	return &History{
+		series:    make(map[string]*series),
+		retention: retention,
+		mu:        &sync.RWMutex{},
+	}
+}
+
+// Record сохраняет исходное значение метрики, используется
+// как обработчик обновлений хранилища метрик сервера. Обработчики
+// вызываются вне блокировки хранилища, поэтому значения могут поступать
+// не по порядку и вставляются с сохранением порядка по времени.
+func (h *History) Record(ctx context.Context, update entities.Update) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s, ok := h.series[update.ID]
+	if !ok {
+		s = &series{}
+		h.series[update.ID] = s
+	}
+	s.mType = update.MType
+
+	p := entities.Point{
+		Time:  update.Time,
+		Count: 1,
+		Min:   update.Value,
+		Max:   update.Value,
+		Avg:   update.Value,
+		Last:  update.Value,
+	}
+	if update.MType == "counter" {
+		p.Sum = update.Delta
+	}
+	i := sort.Search(len(s.raw), func(i int) bool {
+		return s.raw[i].Time.After(p.Time)
+	})
+	s.raw = append(s.raw, entities.Point{})
+	copy(s.raw[i+1:], s.raw[i:])
+	s.raw[i] = p
+}
+
+// Rollup сворачивает завершённые минуты исходных значений в минутные агрегаты,
+// завершённые часы минутных агрегатов в часовые агрегаты и удаляет
+// устаревшие значения. История метрики, все значения которой устарели,
+// в том числе удалённой из хранилища, удаляется целиком.
+// Метод возвращает созданные агрегаты.
+func (h *History) Rollup(ctx context.Context, now time.Time) []entities.Series {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	created := make([]entities.Series, 0)
+	minuteEnd := now.Truncate(time.Minute)
+	hourEnd := now.Truncate(time.Hour)
+
+	for id, s := range h.series {
+		minutes := aggregate(s.raw, s.rolledMinute, minuteEnd, entities.ResolutionMinute, s.mType)
+		if len(minutes) > 0 {
+			s.minute = append(s.minute, minutes...)
+			created = append(created, entities.Series{ID: id, MType: s.mType,
+				Resolution: entities.ResolutionMinute, Points: minutes})
+		}
+		if minuteEnd.After(s.rolledMinute) {
+			s.rolledMinute = minuteEnd
+		}
+
+		hours := aggregate(s.minute, s.rolledHour, hourEnd, entities.ResolutionHour, s.mType)
+		if len(hours) > 0 {
+			s.hour = append(s.hour, hours...)
+			created = append(created, entities.Series{ID: id, MType: s.mType,
+				Resolution: entities.ResolutionHour, Points: hours})
+		}
+		if hourEnd.After(s.rolledHour) {
+			s.rolledHour = hourEnd
+		}
+
+		// исходные значения удаляются только после сворачивания
+		rawBefore := now.Add(-h.retention.Raw)
+		if rawBefore.After(s.rolledMinute) {
+			rawBefore = s.rolledMinute
+		}
+		s.raw = expire(s.raw, rawBefore)
+		s.minute = expire(s.minute, now.Add(-h.retention.Minute))
+		s.hour = expire(s.hour, now.Add(-h.retention.Hour))
+
+		if len(s.raw) == 0 && len(s.minute) == 0 && len(s.hour) == 0 {
+			delete(h.series, id)
+		}
+	}
+
+	return created
+}
+
+// Restore добавляет в историю агрегаты, загруженные из базы данных.
+func (h *History) Restore(ctx context.Context, rollups []entities.Series) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, r := range rollups {
+		s, ok := h.series[r.ID]
+		if !ok {
+			s = &series{}
+			h.series[r.ID] = s
+		}
+		s.mType = r.MType
+
+		switch r.Resolution {
+		case entities.ResolutionMinute:
+			s.minute = mergePoints(s.minute, r.Points)
+			if n := len(s.minute); n > 0 && !s.minute[n-1].Time.Before(s.rolledMinute) {
+				s.rolledMinute = s.minute[n-1].Time.Add(time.Minute)
+			}
+		case entities.ResolutionHour:
+			s.hour = mergePoints(s.hour, r.Points)
+			if n := len(s.hour); n > 0 && !s.hour[n-1].Time.Before(s.rolledHour) {
+				s.rolledHour = s.hour[n-1].Time.Add(time.Hour)
+			}
+		}
+	}
+}
+
+// Query возвращает историю метрики за указанный период.
+// Разрешение выбирается автоматически по началу периода и периодам хранения.
+func (h *History) Query(ctx context.Context, metricName string, from time.Time, to time.Time) (entities.Series, bool) {
+	return h.QueryResolution(ctx, metricName, from, to, h.PickResolution(time.Now(), from))
+}
+
+// QueryResolution возвращает историю метрики за указанный период с указанным разрешением.
+func (h *History) QueryResolution(ctx context.Context, metricName string, from time.Time, to time.Time,
+	res entities.Resolution) (entities.Series, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	s, ok := h.series[metricName]
+	if !ok {
+		return entities.Series{}, false
+	}
+
+	var points []entities.Point
+	switch res {
+	case entities.ResolutionMinute:
+		points = s.minute
+	case entities.ResolutionHour:
+		points = s.hour
+	default:
+		points = s.raw
+	}
+
+	result := entities.Series{
+		ID:         metricName,
+		MType:      s.mType,
+		Resolution: res,
+		Points:     make([]entities.Point, 0),
+	}
+	for _, p := range points {
+		if !p.Time.Before(from) && !p.Time.After(to) {
+			result.Points = append(result.Points, p)
+		}
+	}
+
+	return result, true
+}
+
+// PickResolution выбирает самое подробное разрешение,
+// период хранения которого покрывает начало запрошенного периода.
+func (h *History) PickResolution(now time.Time, from time.Time) entities.Resolution {
+	switch {
+	case !from.Before(now.Add(-h.retention.Raw)):
+		return entities.ResolutionRaw
+	case !from.Before(now.Add(-h.retention.Minute)):
+		return entities.ResolutionMinute
+	default:
+		return entities.ResolutionHour
+	}
+}
+
+// Retention возвращает периоды хранения истории.
+func (h *History) Retention() Retention {
+	return h.retention
+}
+
+// aggregate сворачивает значения из полуинтервала [from, to) в агрегаты
+// с указанным разрешением.
+func aggregate(points []entities.Point, from time.Time, to time.Time,
+	res entities.Resolution, mType string) []entities.Point {
+	period := res.Duration()
+	result := make([]entities.Point, 0)
+
+	var cur *entities.Point
+	var total float64
+	for _, p := range points {
+		if p.Time.Before(from) || !p.Time.Before(to) {
+			continue
+		}
+
+		start := p.Time.Truncate(period)
+		if cur == nil || !cur.Time.Equal(start) {
+			if cur != nil {
+				result = append(result, finish(*cur, total, period, mType))
+			}
+			cur = &entities.Point{Time: start, Min: p.Min, Max: p.Max}
+			total = 0
+		}
+
+		cur.Count += p.Count
+		cur.Min = min(cur.Min, p.Min)
+		cur.Max = max(cur.Max, p.Max)
+		cur.Last = p.Last
+		cur.Sum += p.Sum
+		total += p.Avg * float64(p.Count)
+	}
+	if cur != nil {
+		result = append(result, finish(*cur, total, period, mType))
+	}
+
+	return result
+}
+
+// finish вычисляет среднее значение и скорость изменения агрегата.
+func finish(p entities.Point, total float64, period time.Duration, mType string) entities.Point {
+	p.Avg = total / float64(p.Count)
+	if mType == "counter" {
+		p.Rate = p.Sum / period.Seconds()
+	} else {
+		p.Sum = 0
+	}
+	return p
+}
+
+// expire удаляет значения, предшествующие указанному времени.
+func expire(points []entities.Point, before time.Time) []entities.Point {
+	i := sort.Search(len(points), func(i int) bool {
+		return !points[i].Time.Before(before)
+	})
+	if i == 0 {
+		return points
+	}
+	return append(points[:0:0], points[i:]...)
+}
+
+// mergePoints объединяет значения, сохраняя порядок по времени и исключая повторы.
+func mergePoints(points []entities.Point, add []entities.Point) []entities.Point {
+	seen := make(map[int64]struct{}, len(points))
+	for _, p := range points {
+		seen[p.Time.UnixNano()] = struct{}{}
+	}
+	for _, p := range add {
+		if _, ok := seen[p.Time.UnixNano()]; !ok {
+			points = append(points, p)
+		}
+	}
+	sort.Slice(points, func(i, j int) bool {
+		return points[i].Time.Before(points[j].Time)
+	})
+	return points
+}
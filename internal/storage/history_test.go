@@ -0,0 +1,169 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pavlegich/metrics-alerting/internal/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistory_Rollup(t *testing.T) {
+	ctx := context.Background()
+	base := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		updates []entities.Update
+		now     time.Time
+		want    []entities.Series
+	}{
+		{
+			name: "gauge_minute",
+			updates: []entities.Update{
+				{ID: "Alloc", MType: "gauge", Value: 4, Time: base.Add(10 * time.Second)},
+				{ID: "Alloc", MType: "gauge", Value: 1, Time: base.Add(20 * time.Second)},
+				{ID: "Alloc", MType: "gauge", Value: 7, Time: base.Add(30 * time.Second)},
+				{ID: "Alloc", MType: "gauge", Value: 9, Time: base.Add(70 * time.Second)},
+			},
+			now: base.Add(65 * time.Second),
+			want: []entities.Series{
+				{ID: "Alloc", MType: "gauge", Resolution: entities.ResolutionMinute, Points: []entities.Point{
+					{Time: base, Count: 3, Min: 1, Max: 7, Avg: 4, Last: 7},
+				}},
+			},
+		},
+		{
+			name: "counter_minute",
+			updates: []entities.Update{
+				{ID: "PollCount", MType: "counter", Value: 5, Delta: 5, Time: base.Add(10 * time.Second)},
+				{ID: "PollCount", MType: "counter", Value: 35, Delta: 30, Time: base.Add(40 * time.Second)},
+			},
+			now: base.Add(time.Minute),
+			want: []entities.Series{
+				{ID: "PollCount", MType: "counter", Resolution: entities.ResolutionMinute, Points: []entities.Point{
+					{Time: base, Count: 2, Min: 5, Max: 35, Avg: 20, Last: 35, Sum: 35, Rate: 35.0 / 60},
+				}},
+			},
+		},
+		{
+			name: "gauge_out_of_order",
+			updates: []entities.Update{
+				{ID: "Alloc", MType: "gauge", Value: 7, Time: base.Add(30 * time.Second)},
+				{ID: "Alloc", MType: "gauge", Value: 9, Time: base.Add(70 * time.Second)},
+				{ID: "Alloc", MType: "gauge", Value: 4, Time: base.Add(10 * time.Second)},
+				{ID: "Alloc", MType: "gauge", Value: 1, Time: base.Add(20 * time.Second)},
+			},
+			now: base.Add(65 * time.Second),
+			want: []entities.Series{
+				{ID: "Alloc", MType: "gauge", Resolution: entities.ResolutionMinute, Points: []entities.Point{
+					{Time: base, Count: 3, Min: 1, Max: 7, Avg: 4, Last: 7},
+				}},
+			},
+		},
+		{
+			name: "gauge_hour",
+			updates: []entities.Update{
+				{ID: "Alloc", MType: "gauge", Value: 2, Time: base.Add(10 * time.Second)},
+				{ID: "Alloc", MType: "gauge", Value: 4, Time: base.Add(20 * time.Second)},
+				{ID: "Alloc", MType: "gauge", Value: 9, Time: base.Add(30 * time.Minute)},
+			},
+			now: base.Add(time.Hour),
+			want: []entities.Series{
+				{ID: "Alloc", MType: "gauge", Resolution: entities.ResolutionMinute, Points: []entities.Point{
+					{Time: base, Count: 2, Min: 2, Max: 4, Avg: 3, Last: 4},
+					{Time: base.Add(30 * time.Minute), Count: 1, Min: 9, Max: 9, Avg: 9, Last: 9},
+				}},
+				{ID: "Alloc", MType: "gauge", Resolution: entities.ResolutionHour, Points: []entities.Point{
+					{Time: base, Count: 3, Min: 2, Max: 9, Avg: 5, Last: 9},
+				}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHistory(ctx, Retention{Raw: time.Hour, Minute: 24 * time.Hour, Hour: 720 * time.Hour})
+			for _, u := range tt.updates {
+				h.Record(ctx, u)
+			}
+
+			got := h.Rollup(ctx, tt.now)
+			assert.Equal(t, tt.want, got)
+
+			// повторное сворачивание не создаёт агрегаты
+			assert.Empty(t, h.Rollup(ctx, tt.now))
+		})
+	}
+}
+
+func TestHistory_Retention(t *testing.T) {
+	ctx := context.Background()
+	base := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	h := NewHistory(ctx, Retention{Raw: 5 * time.Minute, Minute: time.Hour, Hour: 720 * time.Hour})
+	h.Record(ctx, entities.Update{ID: "Alloc", MType: "gauge", Value: 1, Time: base})
+	h.Record(ctx, entities.Update{ID: "Alloc", MType: "gauge", Value: 2, Time: base.Add(9 * time.Minute)})
+	h.Rollup(ctx, base.Add(10*time.Minute))
+
+	raw, ok := h.QueryResolution(ctx, "Alloc", base, base.Add(time.Hour), entities.ResolutionRaw)
+	require.True(t, ok)
+	assert.Len(t, raw.Points, 1)
+
+	minute, ok := h.QueryResolution(ctx, "Alloc", base, base.Add(time.Hour), entities.ResolutionMinute)
+	require.True(t, ok)
+	assert.Len(t, minute.Points, 2)
+
+	_, ok = h.Query(ctx, "Unknown", base, base.Add(time.Hour))
+	assert.False(t, ok)
+}
+
+func TestHistory_Expired(t *testing.T) {
+	ctx := context.Background()
+	base := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	h := NewHistory(ctx, Retention{Raw: 5 * time.Minute, Minute: time.Hour, Hour: 2 * time.Hour})
+	h.Record(ctx, entities.Update{ID: "Alloc", MType: "gauge", Value: 1, Time: base})
+	h.Rollup(ctx, base.Add(2*time.Hour))
+
+	_, ok := h.QueryResolution(ctx, "Alloc", base, base.Add(time.Hour), entities.ResolutionHour)
+	require.True(t, ok)
+
+	h.Rollup(ctx, base.Add(3*time.Hour))
+	_, ok = h.QueryResolution(ctx, "Alloc", base, base.Add(time.Hour), entities.ResolutionHour)
+	assert.False(t, ok)
+}
+
+func TestHistory_PickResolution(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	h := NewHistory(ctx, Retention{Raw: time.Hour, Minute: 24 * time.Hour, Hour: 720 * time.Hour})
+
+	tests := []struct {
+		name string
+		from time.Time
+		want entities.Resolution
+	}{
+		{
+			name: "recent",
+			from: now.Add(-30 * time.Minute),
+			want: entities.ResolutionRaw,
+		},
+		{
+			name: "day",
+			from: now.Add(-12 * time.Hour),
+			want: entities.ResolutionMinute,
+		},
+		{
+			name: "month",
+			from: now.Add(-10 * 24 * time.Hour),
+			want: entities.ResolutionHour,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, h.PickResolution(now, tt.from))
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pavlegich/metrics-alerting/internal/interfaces"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	// metricsBucket содержит название корзины метрик во встроенном хранилище.
+	metricsBucket = []byte("metrics")
+	// typesBucket содержит название корзины типов метрик во встроенном хранилище.
+	typesBucket = []byte("types")
+)
+
+// KV содержит информацию о встроенном транзакционном key-value хранилище.
+type KV struct {
+	db *bolt.DB
+}
+
+// NewKV открывает встроенное key-value хранилище по указанному пути.
+func NewKV(ctx context.Context, path string) (*KV, error) {
+	db, err := bolt.Open(path, 0666, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("NewKV: open storage failed %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{metricsBucket, typesBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("NewKV: create bucket failed %w", err)
+	}
+
+	return &KV{
+		db: db,
+	}, nil
+}
+
+// Save сохраняет в хранилище метрики, изменённые после последнего
+// успешного сохранения, и удаляет удалённые метрики в одной транзакции.
+func (kv *KV) Save(ctx context.Context, ms interfaces.MetricStorage) error {
+	metrics, version := ms.GetDirty(ctx)
+	deleted := ms.GetDeleted(ctx)
+	if len(metrics) == 0 && len(deleted) == 0 {
+		return nil
+	}
+	types := ms.GetAllTypes(ctx)
+
+	err := kv.db.Update(func(tx *bolt.Tx) error {
+		b, t := tx.Bucket(metricsBucket), tx.Bucket(typesBucket)
+		for _, id := range deleted {
+			if err := b.Delete([]byte(id)); err != nil {
+				return err
+			}
+			if err := t.Delete([]byte(id)); err != nil {
+				return err
+			}
+		}
+		for id, value := range metrics {
+			if err := b.Put([]byte(id), []byte(value)); err != nil {
+				return err
+			}
+			if err := t.Put([]byte(id), []byte(types[id])); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("SaveToKV: update transaction failed %w", err)
+	}
+	ms.MarkSaved(ctx, version)
+
+	return nil
+}
+
+// Load получает все метрики из хранилища
+// и сохраняет их в хранилище сервера.
+func (kv *KV) Load(ctx context.Context, ms interfaces.MetricStorage) error {
+	metrics := make([]DBMetric, 0)
+	err := kv.db.View(func(tx *bolt.Tx) error {
+		t := tx.Bucket(typesBucket)
+		return tx.Bucket(metricsBucket).ForEach(func(k, v []byte) error {
+			metrics = append(metrics, DBMetric{ID: string(k), MType: string(t.Get(k)), Value: string(v)})
+			return nil
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("LoadFromKV: view transaction failed %w", err)
+	}
+
+	for _, metric := range metrics {
+		if err := ms.Restore(ctx, metric.MType, metric.ID, metric.Value); err != nil {
+			return fmt.Errorf("LoadFromKV: put metric failed %w", err)
+		}
+	}
+
+	// Загруженные метрики уже есть в хранилище
+	_, version := ms.GetDirty(ctx)
+	ms.MarkSaved(ctx, version)
+
+	return nil
+}
+
+// Ping проверяет доступность хранилища.
+func (kv *KV) Ping(ctx context.Context) error {
+	return kv.db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket(metricsBucket) == nil {
+			return fmt.Errorf("Ping: bucket %s not found", metricsBucket)
+		}
+		return nil
+	})
+}
+
+// Close закрывает хранилище.
+func (kv *KV) Close() error {
+	return kv.db.Close()
+}
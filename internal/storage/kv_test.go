@@ -0,0 +1,40 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKV_SaveLoad(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "metrics.kv")
+
+	kv, err := NewKV(ctx, path)
+	require.NoError(t, err)
+	require.NoError(t, kv.Ping(ctx))
+
+	ms := NewMemStorage(ctx)
+	require.NoError(t, ms.Put(ctx, "gauge", "Alloc", "1.5"))
+	require.NoError(t, ms.Put(ctx, "counter", "PollCount", "3"))
+	require.NoError(t, kv.Save(ctx, ms))
+
+	require.NoError(t, ms.Put(ctx, "counter", "PollCount", "4"))
+	require.NoError(t, ms.Put(ctx, "gauge", "HeapAlloc", "1024"))
+	require.NoError(t, kv.Save(ctx, ms))
+	require.NoError(t, ms.Delete(ctx, "HeapAlloc"))
+	require.NoError(t, kv.Save(ctx, ms))
+	require.NoError(t, kv.Close())
+
+	// повторное открытие хранилища
+	kv, err = NewKV(ctx, path)
+	require.NoError(t, err)
+	defer kv.Close()
+
+	got := NewMemStorage(ctx)
+	require.NoError(t, kv.Load(ctx, got))
+	assert.Equal(t, map[string]string{"Alloc": "1.5", "PollCount": "7"}, got.Metrics)
+}
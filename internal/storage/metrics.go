@@ -3,82 +3,554 @@ package storage
 import (
 	"context"
 	"fmt"
-	"net/http"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
+
+	"github.com/pavlegich/metrics-alerting/internal/entities"
+	"github.com/pavlegich/metrics-alerting/internal/infra/logger"
+	"go.uber.org/zap"
 )
 
 // MemStorage хранит данные метрик сервера.
+// Для каждой изменённой метрики хранится номер версии изменения,
+// что позволяет сохранять только метрики, изменённые после последнего сохранения,
+// и время последнего обновления, по которому определяются устаревшие метрики.
+// Удалённые метрики хранятся до сохранения, чтобы удалить их из базы данных.
 type MemStorage struct {
-	Metrics map[string]string
-	mu      *sync.Mutex
+	Metrics   map[string]string
+	mu        *sync.Mutex
+	wal       *WAL
+	dirty     map[string]uint64
+	deleted   map[string]uint64
+	updated   map[string]time.Time
+	types     map[string]string
+	version   uint64
+	listeners []Listener
 }
 
+// Listener вызывается после каждого успешного обновления метрики.
+// Обработчик не должен блокироваться, так как вызывается до ответа клиенту.
+type Listener func(ctx context.Context, update entities.Update)
+
 // NewMemStorage создаёт новое хранилище метрик сервера.
 func NewMemStorage(ctx context.Context) *MemStorage {
 	return &MemStorage{
 		Metrics: make(map[string]string),
 		mu:      &sync.Mutex{},
+		dirty:   make(map[string]uint64),
+		deleted: make(map[string]uint64),
+		updated: make(map[string]time.Time),
+		types:   make(map[string]string),
 	}
 }
 
+// SetWAL подключает к хранилищу журнал предзаписи. После подключения
+// каждое принятое обновление метрики записывается в журнал до ответа клиенту.
+func (ms *MemStorage) SetWAL(wal *WAL) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.wal = wal
+}
+
+// AddListener подключает обработчик обновлений метрик.
+func (ms *MemStorage) AddListener(l Listener) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.listeners = append(ms.listeners, l)
+}
+
 // Put обрабатывает данные метрики, в случае успеха сохраняет
-// в хранилище сервера.
-func (ms *MemStorage) Put(ctx context.Context, metricType string, metricName string, metricValue string) int {
+// в хранилище сервера. При подключённом журнале предзаписи
+// метод возвращает управление только после синхронизации записи с диском
+// (см. waitSync).
+// Ошибки проверки метрики оборачивают ErrNotFound, ErrUnknownType или ErrInvalidValue
+// и возвращаются без префикса, так как их описание передаётся клиенту.
+func (ms *MemStorage) Put(ctx context.Context, metricType string, metricName string, metricValue string) error {
+	update, seq, listeners, err := ms.put(ctx, metricType, metricName, metricValue, nil)
+	if err != nil {
+		return err
+	}
+
+	ms.waitSync(ctx, seq)
+
+	for _, l := range listeners {
+		l(ctx, update)
+	}
+
+	return nil
+}
+
+// Restore сохраняет итоговое значение метрики указанного типа, загруженное
+// из файла, базы данных или журнала предзаписи. В отличие от Put, значение
+// счётчика заменяет текущее, а не складывается с ним. Метрики без типа,
+// сохранённые предыдущими версиями сервера, восстанавливаются с типом gauge.
+func (ms *MemStorage) Restore(ctx context.Context, metricType string, metricName string, metricValue string) error {
+	if metricType == "" {
+		metricType = "gauge"
+	}
+	update, seq, listeners, err := ms.put(ctx, metricType, metricName, metricValue,
+		map[string]string{metricName: "0"})
+	if err != nil {
+		return err
+	}
+
+	ms.waitSync(ctx, seq)
+
+	for _, l := range listeners {
+		l(ctx, update)
+	}
+
+	return nil
+}
+
+// PutBatch сохраняет пачку метрик. Сначала проверяются все метрики пачки,
+// затем корректные метрики применяются к хранилищу под одной блокировкой
+// и одной записью в журнал предзаписи. По умолчанию пачка применяется
+// атомарно: при ошибке в любой метрике хранилище не изменяется. В режиме
+// bestEffort применяются корректные метрики, а отклонённые перечисляются
+// в результате. Если пачка не применена, метод возвращает ошибку
+// первой отклонённой метрики.
+func (ms *MemStorage) PutBatch(ctx context.Context, metrics []entities.Metrics,
+	bestEffort bool) (entities.BatchResult, error) {
+	updates, seq, listeners, result, err := ms.putBatch(ctx, metrics, bestEffort)
+	if err != nil {
+		return result, err
+	}
+
+	ms.waitSync(ctx, seq)
+
+	for _, update := range updates {
+		for _, l := range listeners {
+			l(ctx, update)
+		}
+	}
+
+	return result, nil
+}
+
+// Delete удаляет метрику из хранилища. Удаление передаётся в базу данных
+// или встроенное хранилище при следующем сохранении.
+func (ms *MemStorage) Delete(ctx context.Context, metricName string) error {
+	seq, err := ms.delete(ctx, metricName)
+	if err != nil {
+		return err
+	}
+
+	ms.waitSync(ctx, seq)
+
+	return nil
+}
+
+// waitSync ожидает синхронизации с диском записи журнала предзаписи
+// с указанным порядковым номером. К этому моменту изменение уже применено
+// к хранилищу и видно другим клиентам, поэтому ошибка синхронизации
+// не возвращается клиенту: ответ с ошибкой привёл бы к повторной отправке
+// и двойному учёту значения счётчика. Ошибка журнала сохраняется в нём,
+// и последующие изменения отклоняются до применения к хранилищу, а изменение
+// остаётся в памяти и попадает в файл или базу данных при следующем сохранении.
+func (ms *MemStorage) waitSync(ctx context.Context, seq uint64) {
+	if seq == 0 {
+		return
+	}
+	if err := ms.wal.WaitSync(ctx, seq); err != nil {
+		logger.Log.Error("waitSync: wal sync failed, change is kept in memory only",
+			zap.Uint64("seq", seq), zap.Error(err))
+	}
+}
+
+// put проверяет и применяет обновление метрики под блокировкой хранилища,
+// возвращает данные обновления, порядковый номер записи в журнале предзаписи
+// и обработчики обновлений. Текущие значения счётчиков берутся из pending,
+// если они там есть.
+func (ms *MemStorage) put(ctx context.Context, metricType string, metricName string,
+	metricValue string, pending map[string]string) (entities.Update, uint64, []Listener, error) {
 	ms.mu.Lock()
 	defer ms.mu.Unlock()
 
+	update, value, err := ms.prepare(metricType, metricName, metricValue, pending)
+	if err != nil {
+		return update, 0, nil, err
+	}
+
+	// записываем итоговое значение в журнал до изменения хранилища
+	seq, err := ms.writeWAL(ctx, WALRecord{ID: metricName, MType: metricType, Value: value})
+	if err != nil {
+		return update, 0, nil, err
+	}
+
+	ms.apply(update, value)
+
+	return update, seq, ms.listeners, nil
+}
+
+// putBatch проверяет все метрики пачки и применяет корректные метрики
+// под блокировкой хранилища, возвращает данные обновлений, порядковый номер
+// записи в журнале предзаписи, обработчики обновлений и результат применения.
+func (ms *MemStorage) putBatch(ctx context.Context, metrics []entities.Metrics,
+	bestEffort bool) ([]entities.Update, uint64, []Listener, entities.BatchResult, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	result := entities.BatchResult{Errors: make([]entities.BatchError, 0)}
+	updates := make([]entities.Update, 0, len(metrics))
+	values := make([]string, 0, len(metrics))
+	records := make([]WALRecord, 0, len(metrics))
+	// значения счётчиков с учётом предыдущих метрик пачки
+	pending := make(map[string]string)
+	for i, metric := range metrics {
+		metricValue, err := metricValue(metric)
+		if err != nil {
+			result.Errors = append(result.Errors, batchError(i, metric.ID, err))
+			continue
+		}
+		update, value, err := ms.prepare(metric.MType, metric.ID, metricValue, pending)
+		if err != nil {
+			result.Errors = append(result.Errors, batchError(i, metric.ID, err))
+			continue
+		}
+		pending[metric.ID] = value
+
+		updates = append(updates, update)
+		values = append(values, value)
+		records = append(records, WALRecord{ID: metric.ID, MType: metric.MType, Value: value})
+	}
+	if len(result.Errors) > 0 && (!bestEffort || len(updates) == 0) {
+		return nil, 0, nil, result, result.Errors[0].Err
+	}
+
+	seq, err := ms.writeWAL(ctx, records...)
+	if err != nil {
+		return nil, 0, nil, entities.BatchResult{}, err
+	}
+
+	for i, update := range updates {
+		ms.apply(update, values[i])
+	}
+	result.Applied = len(updates)
+
+	return updates, seq, ms.listeners, result, nil
+}
+
+// delete удаляет метрику под блокировкой хранилища и возвращает
+// порядковый номер записи в журнале предзаписи.
+func (ms *MemStorage) delete(ctx context.Context, metricName string) (uint64, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if _, ok := ms.Metrics[metricName]; !ok {
+		return 0, fmt.Errorf("%w: %s", ErrNotFound, metricName)
+	}
+
+	seq, err := ms.writeWAL(ctx, WALRecord{ID: metricName, Deleted: true})
+	if err != nil {
+		return 0, err
+	}
+
+	delete(ms.Metrics, metricName)
+	delete(ms.dirty, metricName)
+	delete(ms.updated, metricName)
+	delete(ms.types, metricName)
+	ms.version++
+	if ms.deleted == nil {
+		ms.deleted = make(map[string]uint64)
+	}
+	ms.deleted[metricName] = ms.version
+
+	return seq, nil
+}
+
+// prepare проверяет обновление метрики и вычисляет её итоговое значение,
+// не изменяя хранилище. Текущие значения счётчиков берутся из pending,
+// если они там есть. Вызывается под блокировкой.
+func (ms *MemStorage) prepare(metricType string, metricName string, metricValue string,
+	pending map[string]string) (entities.Update, string, error) {
+	update := entities.Update{
+		ID:    metricName,
+		MType: metricType,
+		Time:  time.Now(),
+	}
+
 	if metricName == "" {
-		return http.StatusNotFound
+		return update, "", fmt.Errorf("%w: metric name is empty", ErrNotFound)
 	}
 	switch metricType {
 	case "gauge":
-		if _, err := strconv.ParseFloat(metricValue, 64); err != nil {
-			return http.StatusBadRequest
+		value, err := strconv.ParseFloat(metricValue, 64)
+		if err != nil {
+			return update, "", fmt.Errorf("%w: %s is not a gauge value", ErrInvalidValue, metricValue)
 		}
-		ms.Metrics[metricName] = metricValue
+		update.Value = value
 	case "counter":
 		// проверяем наличие метрики
-		if _, ok := ms.Metrics[metricName]; !ok {
-			ms.Metrics[metricName] = "0"
+		storedValue, ok := pending[metricName]
+		if !ok {
+			storedValue, ok = ms.Metrics[metricName]
+		}
+		if !ok {
+			storedValue = "0"
 		}
 
-		// конвертируем строку в значение float64, проверяем на ошибку
-		storageValue, errMetric := strconv.ParseInt(ms.Metrics[metricName], 10, 64)
+		// конвертируем строку в значение int64, проверяем на ошибку
+		storageValue, errMetric := strconv.ParseInt(storedValue, 10, 64)
 		if errMetric != nil {
-			return http.StatusInternalServerError
+			return update, "", fmt.Errorf("prepare: stored value of %s is not a counter %w", metricName, errMetric)
 		}
 		gotValue, errCounter := strconv.ParseInt(metricValue, 10, 64)
 		if errCounter != nil {
-			return http.StatusBadRequest
+			return update, "", fmt.Errorf("%w: %s is not a counter value", ErrInvalidValue, metricValue)
 		}
 
-		// складываем значения и добавляем в хранилище метрик
-		newMetricValue := storageValue + gotValue
-		ms.Metrics[metricName] = fmt.Sprintf("%v", newMetricValue)
+		// складываем значения
+		metricValue = fmt.Sprintf("%v", storageValue+gotValue)
+		update.Value = float64(storageValue + gotValue)
+		update.Delta = float64(gotValue)
 	default:
-		return http.StatusNotImplemented
+		return update, "", fmt.Errorf("%w: %s", ErrUnknownType, metricType)
+	}
+
+	return update, metricValue, nil
+}
+
+// writeWAL записывает записи в журнал предзаписи, если он подключён,
+// и возвращает порядковый номер последней записи. Вызывается под блокировкой.
+func (ms *MemStorage) writeWAL(ctx context.Context, records ...WALRecord) (uint64, error) {
+	if ms.wal == nil || len(records) == 0 {
+		return 0, nil
 	}
 
-	return http.StatusOK
+	seq, err := ms.wal.Write(ctx, records...)
+	if err != nil {
+		return 0, fmt.Errorf("writeWAL: wal write failed %w", err)
+	}
+	return seq, nil
+}
+
+// apply сохраняет проверенное значение метрики. Вызывается под блокировкой.
+func (ms *MemStorage) apply(update entities.Update, value string) {
+	ms.Metrics[update.ID] = value
+	ms.version++
+	if ms.dirty == nil {
+		ms.dirty = make(map[string]uint64)
+	}
+	ms.dirty[update.ID] = ms.version
+	if ms.updated == nil {
+		ms.updated = make(map[string]time.Time)
+	}
+	ms.updated[update.ID] = update.Time
+	if ms.types == nil {
+		ms.types = make(map[string]string)
+	}
+	ms.types[update.ID] = update.MType
+	delete(ms.deleted, update.ID)
+}
+
+// batchError формирует ошибку метрики пачки.
+func batchError(index int, id string, err error) entities.BatchError {
+	return entities.BatchError{
+		Index:   index,
+		ID:      id,
+		Code:    ErrorCode(err),
+		Message: err.Error(),
+		Err:     err,
+	}
+}
+
+// metricValue возвращает значение метрики в строковом виде
+// в зависимости от типа метрики.
+func metricValue(metric entities.Metrics) (string, error) {
+	switch metric.MType {
+	case "gauge":
+		if metric.Value == nil {
+			return "", fmt.Errorf("%w: gauge value is missing", ErrInvalidValue)
+		}
+		return fmt.Sprintf("%v", *metric.Value), nil
+	case "counter":
+		if metric.Delta == nil {
+			return "", fmt.Errorf("%w: counter delta is missing", ErrInvalidValue)
+		}
+		return fmt.Sprintf("%v", *metric.Delta), nil
+	default:
+		return "", fmt.Errorf("%w: %s", ErrUnknownType, metric.MType)
+	}
 }
 
 // Get получает из хранилища значение указанной метрики и возвращает это значение.
-func (ms *MemStorage) Get(ctx context.Context, metricType string, metricName string) (string, int) {
+func (ms *MemStorage) Get(ctx context.Context, metricType string, metricName string) (string, error) {
 	ms.mu.Lock()
 	defer ms.mu.Unlock()
 
 	if (metricType != "gauge") && (metricType != "counter") {
-		return "", http.StatusNotImplemented
+		return "", fmt.Errorf("%w: %s", ErrUnknownType, metricType)
 	}
 	value, ok := ms.Metrics[metricName]
 	if !ok {
-		return "", http.StatusNotFound
+		return "", fmt.Errorf("%w: %s", ErrNotFound, metricName)
 	}
-	return value, http.StatusOK
+	return value, nil
 }
 
-// GetAll возвращает все метрики из хранилища.
+// GetAll возвращает копию всех метрик из хранилища.
 func (ms *MemStorage) GetAll(ctx context.Context) map[string]string {
-	return ms.Metrics
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	metrics := make(map[string]string, len(ms.Metrics))
+	for m, v := range ms.Metrics {
+		metrics[m] = v
+	}
+	return metrics
+}
+
+// List возвращает отсортированные по имени метрики, подходящие под условия
+// фильтра, и имя последней метрики страницы, если за ней есть ещё метрики.
+func (ms *MemStorage) List(ctx context.Context, filter entities.MetricFilter) ([]entities.Metrics, string) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	names := make([]string, 0, len(ms.Metrics))
+	for name := range ms.Metrics {
+		if !strings.HasPrefix(name, filter.Prefix) || name <= filter.After && filter.After != "" {
+			continue
+		}
+		if filter.Type != "" && ms.metricType(name) != filter.Type {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	next := ""
+	if filter.Limit > 0 && len(names) > filter.Limit {
+		names = names[:filter.Limit]
+		next = names[len(names)-1]
+	}
+
+	metrics := make([]entities.Metrics, 0, len(names))
+	for _, name := range names {
+		metric := entities.Metrics{ID: name, MType: ms.metricType(name)}
+		value := ms.Metrics[name]
+		switch metric.MType {
+		case "counter":
+			delta, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				logger.Log.Error("List: parse counter failed", zap.String("metric", name), zap.Error(err))
+				continue
+			}
+			metric.Delta = &delta
+		default:
+			v, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				logger.Log.Error("List: parse gauge failed", zap.String("metric", name), zap.Error(err))
+				continue
+			}
+			metric.Value = &v
+		}
+		if t, ok := ms.updated[name]; ok {
+			metric.UpdatedAt = &t
+		}
+		metrics = append(metrics, metric)
+	}
+	return metrics, next
+}
+
+// GetAllTypes возвращает типы всех метрик хранилища.
+func (ms *MemStorage) GetAllTypes(ctx context.Context) map[string]string {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	types := make(map[string]string, len(ms.Metrics))
+	for m := range ms.Metrics {
+		types[m] = ms.metricType(m)
+	}
+	return types
+}
+
+// GetType возвращает тип указанной метрики.
+func (ms *MemStorage) GetType(ctx context.Context, metricName string) (string, bool) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if _, ok := ms.Metrics[metricName]; !ok {
+		return "", false
+	}
+	return ms.metricType(metricName), true
+}
+
+// GetUpdated возвращает время последнего обновления указанной метрики.
+func (ms *MemStorage) GetUpdated(ctx context.Context, metricName string) (time.Time, bool) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	t, ok := ms.updated[metricName]
+	return t, ok
+}
+
+// GetAllUpdated возвращает копию времени последнего обновления всех метрик.
+func (ms *MemStorage) GetAllUpdated(ctx context.Context) map[string]time.Time {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	updated := make(map[string]time.Time, len(ms.updated))
+	for m, t := range ms.updated {
+		updated[m] = t
+	}
+	return updated
+}
+
+// GetDirty возвращает метрики, изменённые после последнего успешного сохранения,
+// и номер версии хранилища, который передаётся в MarkSaved после сохранения.
+func (ms *MemStorage) GetDirty(ctx context.Context) (map[string]string, uint64) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	metrics := make(map[string]string, len(ms.dirty))
+	for m := range ms.dirty {
+		metrics[m] = ms.Metrics[m]
+	}
+	return metrics, ms.version
+}
+
+// GetDeleted возвращает имена метрик, удалённых после последнего успешного сохранения.
+func (ms *MemStorage) GetDeleted(ctx context.Context) []string {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	deleted := make([]string, 0, len(ms.deleted))
+	for m := range ms.deleted {
+		deleted = append(deleted, m)
+	}
+	sort.Strings(deleted)
+	return deleted
+}
+
+// MarkSaved отмечает сохранёнными метрики, изменённые или удалённые не позднее
+// указанной версии. Метрики, изменённые после получения версии, остаются несохранёнными.
+func (ms *MemStorage) MarkSaved(ctx context.Context, version uint64) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	for m, v := range ms.dirty {
+		if v <= version {
+			delete(ms.dirty, m)
+		}
+	}
+	for m, v := range ms.deleted {
+		if v <= version {
+			delete(ms.deleted, m)
+		}
+	}
+}
+
+// metricType возвращает тип метрики по последнему обновлению.
+// Вызывается под блокировкой.
+func (ms *MemStorage) metricType(name string) string {
+	if t, ok := ms.types[name]; ok {
+		return t
+	}
+	return "gauge"
 }
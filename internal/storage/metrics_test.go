@@ -3,12 +3,17 @@ package storage
 import (
 	"context"
 	"net/http"
+	"path/filepath"
 	"reflect"
 	"sync"
 	"testing"
+	"time"
 
+	"github.com/pavlegich/metrics-alerting/internal/entities"
+	"github.com/pavlegich/metrics-alerting/internal/infra/database"
 	"github.com/pavlegich/metrics-alerting/internal/interfaces"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestMemStorage_Put(t *testing.T) {
@@ -27,7 +32,7 @@ func TestMemStorage_Put(t *testing.T) {
 		name   string
 		fields fields
 		args   args
-		want   int
+		want   string
 	}{
 		{
 			name: "put_new_gauge",
@@ -39,7 +44,7 @@ func TestMemStorage_Put(t *testing.T) {
 				metricName:  "SomeMetric",
 				metricValue: "844082.1",
 			},
-			want: http.StatusOK,
+			want: "",
 		},
 		{
 			name: "put_wrong_gauge",
@@ -51,7 +56,7 @@ func TestMemStorage_Put(t *testing.T) {
 				metricName:  "SomeMetric",
 				metricValue: "none",
 			},
-			want: http.StatusBadRequest,
+			want: CodeInvalidValue,
 		},
 		{
 			name: "put_new_counter",
@@ -63,7 +68,7 @@ func TestMemStorage_Put(t *testing.T) {
 				metricName:  "SomeMetric",
 				metricValue: "84",
 			},
-			want: http.StatusOK,
+			want: "",
 		},
 		{
 			name: "put_existed_counter",
@@ -77,7 +82,7 @@ func TestMemStorage_Put(t *testing.T) {
 				metricName:  "SomeMetric",
 				metricValue: "4",
 			},
-			want: http.StatusOK,
+			want: "",
 		},
 		{
 			name: "put_wrong_counter",
@@ -89,7 +94,7 @@ func TestMemStorage_Put(t *testing.T) {
 				metricName:  "SomeMetric",
 				metricValue: "84.1",
 			},
-			want: http.StatusBadRequest,
+			want: CodeInvalidValue,
 		},
 		{
 			name: "wrong_value_in_storage",
@@ -103,7 +108,7 @@ func TestMemStorage_Put(t *testing.T) {
 				metricName:  "SomeMetric",
 				metricValue: "8",
 			},
-			want: http.StatusInternalServerError,
+			want: CodeInternal,
 		},
 		{
 			name: "put_wrong_type",
@@ -115,7 +120,7 @@ func TestMemStorage_Put(t *testing.T) {
 				metricName:  "SomeMetric",
 				metricValue: "84",
 			},
-			want: http.StatusNotImplemented,
+			want: CodeUnknownType,
 		},
 		{
 			name: "put_empty_name",
@@ -127,14 +132,18 @@ func TestMemStorage_Put(t *testing.T) {
 				metricName:  "",
 				metricValue: "84.4",
 			},
-			want: http.StatusNotFound,
+			want: CodeNotFound,
 		},
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			ms.Metrics = tc.fields.Metrics
-			get := ms.Put(ctx, tc.args.metricType, tc.args.metricName, tc.args.metricValue)
-			assert.Equal(t, tc.want, get)
+			err := ms.Put(ctx, tc.args.metricType, tc.args.metricName, tc.args.metricValue)
+			if tc.want == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.Equal(t, tc.want, ErrorCode(err))
 		})
 	}
 }
@@ -148,7 +157,7 @@ func TestMemStorage_Get(t *testing.T) {
 	}
 	type want struct {
 		value string
-		code  int
+		err   error
 	}
 	type args struct {
 		metricType string
@@ -171,7 +180,7 @@ func TestMemStorage_Get(t *testing.T) {
 			},
 			want: want{
 				value: "",
-				code:  http.StatusNotImplemented,
+				err:   ErrUnknownType,
 			},
 		},
 		{
@@ -185,7 +194,7 @@ func TestMemStorage_Get(t *testing.T) {
 			},
 			want: want{
 				value: "",
-				code:  http.StatusNotFound,
+				err:   ErrNotFound,
 			},
 		},
 		{
@@ -199,7 +208,7 @@ func TestMemStorage_Get(t *testing.T) {
 			},
 			want: want{
 				value: "",
-				code:  http.StatusNotFound,
+				err:   ErrNotFound,
 			},
 		},
 		{
@@ -215,7 +224,7 @@ func TestMemStorage_Get(t *testing.T) {
 			},
 			want: want{
 				value: "4.1",
-				code:  http.StatusOK,
+				err:   nil,
 			},
 		},
 		{
@@ -231,16 +240,16 @@ func TestMemStorage_Get(t *testing.T) {
 			},
 			want: want{
 				value: "4",
-				code:  http.StatusOK,
+				err:   nil,
 			},
 		},
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			ms.Metrics = tc.fields.Metrics
-			getValue, getCode := ms.Get(ctx, tc.args.metricType, tc.args.metricName)
+			getValue, err := ms.Get(ctx, tc.args.metricType, tc.args.metricName)
 			assert.Equal(t, tc.want.value, getValue)
-			assert.Equal(t, tc.want.code, getCode)
+			assert.ErrorIs(t, err, tc.want.err)
 		})
 	}
 }
@@ -304,7 +313,8 @@ func TestNewMemStorage(t *testing.T) {
 	}{
 		{
 			name: "storage_created",
-			want: &MemStorage{map[string]string{}, &sync.Mutex{}},
+			want: &MemStorage{Metrics: map[string]string{}, mu: &sync.Mutex{}, dirty: map[string]uint64{},
+				deleted: map[string]uint64{}, updated: map[string]time.Time{}, types: map[string]string{}},
 		},
 	}
 	for _, tc := range tests {
@@ -315,3 +325,329 @@ func TestNewMemStorage(t *testing.T) {
 		})
 	}
 }
+
+func TestMemStorage_Dirty(t *testing.T) {
+	ctx := context.Background()
+	ms := NewMemStorage(ctx)
+
+	require.NoError(t, ms.Put(ctx, "gauge", "Alloc", "1.5"))
+	require.NoError(t, ms.Put(ctx, "counter", "PollCount", "2"))
+
+	dirty, version := ms.GetDirty(ctx)
+	assert.Equal(t, map[string]string{"Alloc": "1.5", "PollCount": "2"}, dirty)
+
+	// метрика изменена во время сохранения
+	require.NoError(t, ms.Put(ctx, "counter", "PollCount", "3"))
+	ms.MarkSaved(ctx, version)
+
+	dirty, version = ms.GetDirty(ctx)
+	assert.Equal(t, map[string]string{"PollCount": "5"}, dirty)
+
+	ms.MarkSaved(ctx, version)
+	dirty, _ = ms.GetDirty(ctx)
+	assert.Empty(t, dirty)
+}
+
+func TestMemStorage_Updated(t *testing.T) {
+	ctx := context.Background()
+	ms := NewMemStorage(ctx)
+
+	_, ok := ms.GetUpdated(ctx, "Alloc")
+	assert.False(t, ok)
+
+	before := time.Now()
+	require.NoError(t, ms.Put(ctx, "gauge", "Alloc", "1.5"))
+	require.ErrorIs(t, ms.Put(ctx, "gauge", "HeapAlloc", "bad"), ErrInvalidValue)
+
+	updated, ok := ms.GetUpdated(ctx, "Alloc")
+	require.True(t, ok)
+	assert.False(t, updated.Before(before))
+
+	all := ms.GetAllUpdated(ctx)
+	assert.Equal(t, map[string]time.Time{"Alloc": updated}, all)
+}
+
+func TestMemStorage_PutBatch(t *testing.T) {
+	ctx := context.Background()
+	gauge := func(v float64) *float64 { return &v }
+	counter := func(v int64) *int64 { return &v }
+
+	tests := []struct {
+		name       string
+		metrics    []entities.Metrics
+		bestEffort bool
+		err        error
+		applied    int
+		errors     []int
+		want       map[string]string
+	}{
+		{
+			name: "applied",
+			metrics: []entities.Metrics{
+				{ID: "PollCount", MType: "counter", Delta: counter(2)},
+				{ID: "Alloc", MType: "gauge", Value: gauge(1.5)},
+				{ID: "PollCount", MType: "counter", Delta: counter(3)},
+			},
+			err:     nil,
+			applied: 3,
+			want:    map[string]string{"PollCount": "6", "Alloc": "1.5"},
+		},
+		{
+			name: "unknown_type",
+			metrics: []entities.Metrics{
+				{ID: "Alloc", MType: "gauge", Value: gauge(1.5)},
+				{ID: "Histogram", MType: "histogram"},
+			},
+			err:    ErrUnknownType,
+			errors: []int{1},
+			want:   map[string]string{"PollCount": "1"},
+		},
+		{
+			name: "missing_value",
+			metrics: []entities.Metrics{
+				{ID: "PollCount", MType: "counter", Delta: counter(2)},
+				{ID: "Alloc", MType: "gauge"},
+			},
+			err:    ErrInvalidValue,
+			errors: []int{1},
+			want:   map[string]string{"PollCount": "1"},
+		},
+		{
+			name: "empty_name",
+			metrics: []entities.Metrics{
+				{ID: "", MType: "gauge", Value: gauge(1)},
+			},
+			err:    ErrNotFound,
+			errors: []int{0},
+			want:   map[string]string{"PollCount": "1"},
+		},
+		{
+			name: "best_effort_partial",
+			metrics: []entities.Metrics{
+				{ID: "Alloc", MType: "gauge"},
+				{ID: "PollCount", MType: "counter", Delta: counter(2)},
+				{ID: "Histogram", MType: "histogram"},
+			},
+			bestEffort: true,
+			err:        nil,
+			applied:    1,
+			errors:     []int{0, 2},
+			want:       map[string]string{"PollCount": "3"},
+		},
+		{
+			name: "best_effort_all_invalid",
+			metrics: []entities.Metrics{
+				{ID: "Alloc", MType: "gauge"},
+			},
+			bestEffort: true,
+			err:        ErrInvalidValue,
+			errors:     []int{0},
+			want:       map[string]string{"PollCount": "1"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ms := NewMemStorage(ctx)
+			require.NoError(t, ms.Put(ctx, "counter", "PollCount", "1"))
+
+			updates := 0
+			ms.AddListener(func(ctx context.Context, update entities.Update) { updates++ })
+
+			result, err := ms.PutBatch(ctx, tc.metrics, tc.bestEffort)
+			assert.ErrorIs(t, err, tc.err)
+			assert.Equal(t, tc.applied, result.Applied)
+			assert.Equal(t, tc.applied, updates)
+			assert.Equal(t, tc.want, ms.GetAll(ctx))
+
+			indexes := make([]int, 0, len(result.Errors))
+			for _, e := range result.Errors {
+				assert.Equal(t, tc.metrics[e.Index].ID, e.ID)
+				assert.Equal(t, ErrorCode(e.Err), e.Code)
+				assert.NotEmpty(t, e.Message)
+				indexes = append(indexes, e.Index)
+			}
+			if tc.errors == nil {
+				tc.errors = []int{}
+			}
+			assert.Equal(t, tc.errors, indexes)
+		})
+	}
+}
+
+func TestMemStorage_Delete(t *testing.T) {
+	ctx := context.Background()
+	ms := NewMemStorage(ctx)
+
+	require.NoError(t, ms.Put(ctx, "gauge", "Alloc", "1.5"))
+	require.NoError(t, ms.Put(ctx, "counter", "PollCount", "2"))
+	_, version := ms.GetDirty(ctx)
+	ms.MarkSaved(ctx, version)
+
+	assert.ErrorIs(t, ms.Delete(ctx, "HeapAlloc"), ErrNotFound)
+	require.NoError(t, ms.Delete(ctx, "Alloc"))
+
+	_, err := ms.Get(ctx, "gauge", "Alloc")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, ok := ms.GetUpdated(ctx, "Alloc")
+	assert.False(t, ok)
+	assert.Equal(t, []string{"Alloc"}, ms.GetDeleted(ctx))
+
+	// повторно добавленная метрика не удаляется при сохранении
+	require.NoError(t, ms.Put(ctx, "counter", "PollCount", "1"))
+	require.NoError(t, ms.Delete(ctx, "PollCount"))
+	require.NoError(t, ms.Put(ctx, "counter", "PollCount", "1"))
+	assert.Equal(t, []string{"Alloc"}, ms.GetDeleted(ctx))
+
+	dirty, version := ms.GetDirty(ctx)
+	assert.Equal(t, map[string]string{"PollCount": "1"}, dirty)
+	ms.MarkSaved(ctx, version)
+	assert.Empty(t, ms.GetDeleted(ctx))
+}
+
+func TestMemStorage_List(t *testing.T) {
+	ctx := context.Background()
+	ms := NewMemStorage(ctx)
+
+	require.NoError(t, ms.Put(ctx, "gauge", "HeapAlloc", "1024"))
+	require.NoError(t, ms.Put(ctx, "gauge", "CPUutilization1", "95"))
+	require.NoError(t, ms.Put(ctx, "gauge", "CPUutilization2", "10"))
+	require.NoError(t, ms.Put(ctx, "counter", "PollCount", "3"))
+
+	names := func(metrics []entities.Metrics) []string {
+		res := make([]string, 0, len(metrics))
+		for _, m := range metrics {
+			res = append(res, m.ID)
+		}
+		return res
+	}
+
+	tests := []struct {
+		name   string
+		filter entities.MetricFilter
+		want   []string
+		next   string
+	}{
+		{name: "all", filter: entities.MetricFilter{},
+			want: []string{"CPUutilization1", "CPUutilization2", "HeapAlloc", "PollCount"}},
+		{name: "by_prefix", filter: entities.MetricFilter{Prefix: "CPU"},
+			want: []string{"CPUutilization1", "CPUutilization2"}},
+		{name: "by_type", filter: entities.MetricFilter{Type: "counter"}, want: []string{"PollCount"}},
+		{name: "first_page", filter: entities.MetricFilter{Limit: 2},
+			want: []string{"CPUutilization1", "CPUutilization2"}, next: "CPUutilization2"},
+		{name: "last_page", filter: entities.MetricFilter{Limit: 2, After: "CPUutilization2"},
+			want: []string{"HeapAlloc", "PollCount"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, next := ms.List(ctx, tc.filter)
+			assert.Equal(t, tc.want, names(got))
+			assert.Equal(t, tc.next, next)
+		})
+	}
+
+	metrics, _ := ms.List(ctx, entities.MetricFilter{Prefix: "Poll"})
+	require.Len(t, metrics, 1)
+	require.NotNil(t, metrics[0].Delta)
+	assert.Equal(t, int64(3), *metrics[0].Delta)
+	assert.NotNil(t, metrics[0].UpdatedAt)
+}
+
+func TestMemStorage_RestoreTypes(t *testing.T) {
+	ctx := context.Background()
+
+	fill := func(t *testing.T, ms *MemStorage) {
+		require.NoError(t, ms.Put(ctx, "gauge", "Alloc", "1.5"))
+		require.NoError(t, ms.Put(ctx, "counter", "PollCount", "3"))
+		require.NoError(t, ms.Put(ctx, "counter", "PollCount", "4"))
+	}
+	tests := []struct {
+		name    string
+		restart func(t *testing.T) *MemStorage
+	}{
+		{
+			name: "file",
+			restart: func(t *testing.T) *MemStorage {
+				file := NewFile(filepath.Join(t.TempDir(), "metrics.json"), 0)
+				ms := NewMemStorage(ctx)
+				fill(t, ms)
+				require.NoError(t, file.Save(ctx, ms))
+
+				got := NewMemStorage(ctx)
+				require.NoError(t, file.Load(ctx, got))
+				return got
+			},
+		},
+		{
+			name: "kv",
+			restart: func(t *testing.T) *MemStorage {
+				kv, err := NewKV(ctx, filepath.Join(t.TempDir(), "metrics.kv"))
+				require.NoError(t, err)
+				defer kv.Close()
+				ms := NewMemStorage(ctx)
+				fill(t, ms)
+				require.NoError(t, kv.Save(ctx, ms))
+
+				got := NewMemStorage(ctx)
+				require.NoError(t, kv.Load(ctx, got))
+				return got
+			},
+		},
+		{
+			name: "sqlite",
+			restart: func(t *testing.T) *MemStorage {
+				db, err := database.Init(ctx, "sqlite://"+filepath.Join(t.TempDir(), "metrics.db"))
+				require.NoError(t, err)
+				defer db.Close()
+				s := NewSQLite(db)
+				ms := NewMemStorage(ctx)
+				fill(t, ms)
+				require.NoError(t, s.Save(ctx, ms))
+
+				got := NewMemStorage(ctx)
+				require.NoError(t, s.Load(ctx, got))
+				return got
+			},
+		},
+		{
+			name: "wal",
+			restart: func(t *testing.T) *MemStorage {
+				path := filepath.Join(t.TempDir(), "metrics.wal")
+				wal, err := NewWAL(ctx, path, 0)
+				require.NoError(t, err)
+				ms := NewMemStorage(ctx)
+				ms.SetWAL(wal)
+				fill(t, ms)
+				require.NoError(t, wal.Close())
+
+				wal, err = NewWAL(ctx, path, 0)
+				require.NoError(t, err)
+				defer wal.Close()
+				got := NewMemStorage(ctx)
+				require.NoError(t, wal.Replay(ctx, got))
+				return got
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tc.restart(t)
+
+			// после перезапуска счётчики не превращаются в gauge
+			counters, _ := got.List(ctx, entities.MetricFilter{Type: "counter"})
+			require.Len(t, counters, 1)
+			assert.Equal(t, "PollCount", counters[0].ID)
+			require.NotNil(t, counters[0].Delta)
+			assert.Equal(t, int64(7), *counters[0].Delta)
+
+			gauges, _ := got.List(ctx, entities.MetricFilter{Type: "gauge"})
+			require.Len(t, gauges, 1)
+			assert.Equal(t, "Alloc", gauges[0].ID)
+
+			// восстановленный счётчик продолжает накапливать значение
+			require.NoError(t, got.Put(ctx, "counter", "PollCount", "1"))
+			value, err := got.Get(ctx, "counter", "PollCount")
+			require.NoError(t, err)
+			assert.Equal(t, "8", value)
+		})
+	}
+}
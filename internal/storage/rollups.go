@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pavlegich/metrics-alerting/internal/entities"
+)
+
+// rollupTables содержит названия таблиц агрегатов для каждого разрешения.
+var rollupTables = map[entities.Resolution]string{
+	entities.ResolutionMinute: "rollups_1m",
+	entities.ResolutionHour:   "rollups_1h",
+}
+
+// rollupColumns содержит количество столбцов таблицы агрегатов.
+const rollupColumns = 10
+
+// SaveRollups сохраняет агрегаты истории метрик в базу данных.
+func (d *Database) SaveRollups(ctx context.Context, rollups []entities.Series) error {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("SaveRollups: begin transaction failed %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, r := range rollups {
+		table, ok := rollupTables[r.Resolution]
+		if !ok {
+			return fmt.Errorf("SaveRollups: unsupported resolution %s", r.Resolution)
+		}
+
+		for start := 0; start < len(r.Points); start += upsertBatchSize {
+			end := min(start+upsertBatchSize, len(r.Points))
+			args := make([]any, 0, rollupColumns*(end-start))
+			for _, p := range r.Points[start:end] {
+				args = append(args, r.ID, r.MType, p.Time, p.Count, p.Min, p.Max, p.Avg, p.Last, p.Sum, p.Rate)
+			}
+			if _, err := tx.ExecContext(ctx, rollupUpsertQuery(table, end-start), args...); err != nil {
+				return fmt.Errorf("SaveRollups: batch insert failed %w", err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("SaveRollups: commit transaction failed %w", err)
+	}
+	return nil
+}
+
+// LoadRollups получает из базы данных агрегаты с указанным разрешением,
+// начинающиеся не раньше указанного времени.
+func (d *Database) LoadRollups(ctx context.Context, res entities.Resolution, since time.Time) ([]entities.Series, error) {
+	table, ok := rollupTables[res]
+	if !ok {
+		return nil, fmt.Errorf("LoadRollups: unsupported resolution %s", res)
+	}
+
+	rows, err := d.db.QueryContext(ctx, "SELECT id, type, start, count, min, max, avg, last, sum, rate FROM "+
+		table+" WHERE start >= $1 ORDER BY id, start", since)
+	if err != nil {
+		return nil, fmt.Errorf("LoadRollups: read rows from table failed %w", err)
+	}
+	defer rows.Close()
+
+	rollups := make([]entities.Series, 0)
+	for rows.Next() {
+		var id, mType string
+		var p entities.Point
+		if err := rows.Scan(&id, &mType, &p.Time, &p.Count, &p.Min, &p.Max, &p.Avg, &p.Last, &p.Sum, &p.Rate); err != nil {
+			return nil, fmt.Errorf("LoadRollups: scan row failed %w", err)
+		}
+		if n := len(rollups); n == 0 || rollups[n-1].ID != id {
+			rollups = append(rollups, entities.Series{ID: id, MType: mType, Resolution: res})
+		}
+		rollups[len(rollups)-1].Points = append(rollups[len(rollups)-1].Points, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("LoadRollups: rows.Err %w", err)
+	}
+
+	return rollups, nil
+}
+
+// DeleteRollups удаляет из базы данных агрегаты с указанным разрешением,
+// начинающиеся раньше указанного времени.
+func (d *Database) DeleteRollups(ctx context.Context, res entities.Resolution, before time.Time) error {
+	table, ok := rollupTables[res]
+	if !ok {
+		return fmt.Errorf("DeleteRollups: unsupported resolution %s", res)
+	}
+
+	if _, err := d.db.ExecContext(ctx, "DELETE FROM "+table+" WHERE start < $1", before); err != nil {
+		return fmt.Errorf("DeleteRollups: delete rows failed %w", err)
+	}
+	return nil
+}
+
+// rollupUpsertQuery формирует запрос вставки или обновления указанного количества агрегатов.
+func rollupUpsertQuery(table string, rows int) string {
+	var b strings.Builder
+	b.WriteString("INSERT INTO " + table + " (id, type, start, count, min, max, avg, last, sum, rate) VALUES ")
+	for i := 0; i < rows; i++ {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString("(")
+		for j := 0; j < rollupColumns; j++ {
+			if j > 0 {
+				b.WriteString(", ")
+			}
+			fmt.Fprintf(&b, "$%d", rollupColumns*i+j+1)
+		}
+		b.WriteString(")")
+	}
+	b.WriteString(" ON CONFLICT (id, start) DO UPDATE SET type = EXCLUDED.type, count = EXCLUDED.count, " +
+		"min = EXCLUDED.min, max = EXCLUDED.max, avg = EXCLUDED.avg, last = EXCLUDED.last, " +
+		"sum = EXCLUDED.sum, rate = EXCLUDED.rate")
+	return b.String()
+}
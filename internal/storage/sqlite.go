@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/pavlegich/metrics-alerting/internal/interfaces"
+)
+
+// SQLite содержит информацию о встроенной базе данных SQLite.
+type SQLite struct {
+	db *sql.DB
+}
+
+// NewSQLite создаёт новый объект SQLite для хранения метрик сервера.
+func NewSQLite(db *sql.DB) *SQLite {
+	return &SQLite{
+		db: db,
+	}
+}
+
+// Save сохраняет в базу данных метрики, изменённые после последнего
+// успешного сохранения, и удаляет удалённые метрики.
+func (s *SQLite) Save(ctx context.Context, ms interfaces.MetricStorage) error {
+	metrics, version := ms.GetDirty(ctx)
+	deleted := ms.GetDeleted(ctx)
+	if len(metrics) == 0 && len(deleted) == 0 {
+		return nil
+	}
+	types := ms.GetAllTypes(ctx)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("SaveToSQLite: begin transaction failed %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, id := range deleted {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM storage WHERE id = ?", id); err != nil {
+			return fmt.Errorf("SaveToSQLite: delete metric failed %w", err)
+		}
+	}
+
+	// Сохранение метрик в хранилище пачками
+	args := make([]any, 0, 3*upsertBatchSize)
+	for id, value := range metrics {
+		args = append(args, id, types[id], value)
+		if len(args) == cap(args) {
+			if _, err := tx.ExecContext(ctx, sqliteUpsertQuery(len(args)/3), args...); err != nil {
+				return fmt.Errorf("SaveToSQLite: batch insert failed %w", err)
+			}
+			args = args[:0]
+		}
+	}
+	if len(args) > 0 {
+		if _, err := tx.ExecContext(ctx, sqliteUpsertQuery(len(args)/3), args...); err != nil {
+			return fmt.Errorf("SaveToSQLite: batch insert failed %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("SaveToSQLite: commit transaction failed %w", err)
+	}
+	ms.MarkSaved(ctx, version)
+
+	return nil
+}
+
+// Load получает все метрики из базы данных
+// и сохраняет их в хранилище сервера.
+func (s *SQLite) Load(ctx context.Context, ms interfaces.MetricStorage) error {
+	rows, err := s.db.QueryContext(ctx, "SELECT id, type, value FROM storage")
+	if err != nil {
+		return fmt.Errorf("LoadFromSQLite: read rows from table failed %w", err)
+	}
+	defer rows.Close()
+
+	metrics := make([]DBMetric, 0)
+	for rows.Next() {
+		var metric DBMetric
+		if err := rows.Scan(&metric.ID, &metric.MType, &metric.Value); err != nil {
+			return fmt.Errorf("LoadFromSQLite: scan row failed %w", err)
+		}
+		metrics = append(metrics, metric)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("LoadFromSQLite: rows.Err %w", err)
+	}
+
+	for _, metric := range metrics {
+		if err := ms.Restore(ctx, metric.MType, metric.ID, metric.Value); err != nil {
+			return fmt.Errorf("LoadFromSQLite: put metric failed %w", err)
+		}
+	}
+
+	// Загруженные метрики уже есть в базе данных
+	_, version := ms.GetDirty(ctx)
+	ms.MarkSaved(ctx, version)
+
+	return nil
+}
+
+// Ping проверяет доступность базы данных.
+func (s *SQLite) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+// sqliteUpsertQuery формирует запрос вставки или обновления указанного количества метрик.
+func sqliteUpsertQuery(rows int) string {
+	return "INSERT INTO storage (id, type, value) VALUES " +
+		strings.TrimSuffix(strings.Repeat("(?, ?, ?), ", rows), ", ") +
+		" ON CONFLICT (id) DO UPDATE SET type = excluded.type, value = excluded.value"
+}
@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/pavlegich/metrics-alerting/internal/infra/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLite_SaveLoad(t *testing.T) {
+	ctx := context.Background()
+	dsn := "sqlite://" + filepath.Join(t.TempDir(), "metrics.db")
+
+	db, err := database.Init(ctx, dsn)
+	require.NoError(t, err)
+	defer db.Close()
+
+	s := NewSQLite(db)
+	require.NoError(t, s.Ping(ctx))
+
+	ms := NewMemStorage(ctx)
+	require.NoError(t, ms.Put(ctx, "gauge", "Alloc", "1.5"))
+	require.NoError(t, ms.Put(ctx, "counter", "PollCount", "3"))
+	require.NoError(t, s.Save(ctx, ms))
+
+	// повторное сохранение записывает только изменённые метрики
+	require.NoError(t, ms.Put(ctx, "counter", "PollCount", "4"))
+	dirty, _ := ms.GetDirty(ctx)
+	assert.Equal(t, map[string]string{"PollCount": "7"}, dirty)
+	require.NoError(t, s.Save(ctx, ms))
+
+	got := NewMemStorage(ctx)
+	require.NoError(t, s.Load(ctx, got))
+	assert.Equal(t, map[string]string{"Alloc": "1.5", "PollCount": "7"}, got.Metrics)
+
+	dirty, _ = got.GetDirty(ctx)
+	assert.Empty(t, dirty)
+
+	// удалённая метрика удаляется из базы данных при сохранении
+	require.NoError(t, ms.Delete(ctx, "Alloc"))
+	require.NoError(t, s.Save(ctx, ms))
+	assert.Empty(t, ms.GetDeleted(ctx))
+
+	got = NewMemStorage(ctx)
+	require.NoError(t, s.Load(ctx, got))
+	assert.Equal(t, map[string]string{"PollCount": "7"}, got.Metrics)
+}
+
+func TestSQLite_sqliteUpsertQuery(t *testing.T) {
+	tests := []struct {
+		name string
+		rows int
+		want string
+	}{
+		{
+			name: "multiple_rows",
+			rows: 2,
+			want: "INSERT INTO storage (id, type, value) VALUES (?, ?, ?), (?, ?, ?) " +
+				"ON CONFLICT (id) DO UPDATE SET type = excluded.type, value = excluded.value",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sqliteUpsertQuery(tt.rows); got != tt.want {
+				t.Errorf("sqliteUpsertQuery() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// stateBucket содержит название корзины состояния во встроенном хранилище.
+var stateBucket = []byte("state")
+
+// SaveState атомарно сохраняет состояние указанного вида в отдельный файл
+// рядом с файлом метрик.
+func (f *File) SaveState(ctx context.Context, kind string, data []byte) error {
+	if err := writeFileAtomic(f.statePath(kind), data, nil); err != nil {
+		return fmt.Errorf("SaveState: %w", err)
+	}
+	return nil
+}
+
+// LoadState получает состояние указанного вида из файла.
+// При отсутствии файла возвращается пустое состояние.
+func (f *File) LoadState(ctx context.Context, kind string) ([]byte, error) {
+	data, err := os.ReadFile(f.statePath(kind))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("LoadState: read file error %w", err)
+	}
+	return data, nil
+}
+
+// statePath возвращает путь к файлу состояния указанного вида,
+// например /tmp/metrics-db.rules.json для файла /tmp/metrics-db.json.
+func (f *File) statePath(kind string) string {
+	ext := filepath.Ext(f.path)
+	return strings.TrimSuffix(f.path, ext) + "." + kind + ext
+}
+
+// SaveState сохраняет состояние указанного вида в базу данных.
+func (d *Database) SaveState(ctx context.Context, kind string, data []byte) error {
+	_, err := d.db.ExecContext(ctx, "INSERT INTO state (kind, data) VALUES ($1, $2) "+
+		"ON CONFLICT (kind) DO UPDATE SET data = EXCLUDED.data", kind, string(data))
+	if err != nil {
+		return fmt.Errorf("SaveState: insert into table failed %w", err)
+	}
+	return nil
+}
+
+// LoadState получает состояние указанного вида из базы данных.
+// При отсутствии состояния возвращается пустое состояние.
+func (d *Database) LoadState(ctx context.Context, kind string) ([]byte, error) {
+	var data string
+	err := d.db.QueryRowContext(ctx, "SELECT data FROM state WHERE kind = $1", kind).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("LoadState: read row failed %w", err)
+	}
+	return []byte(data), nil
+}
+
+// SaveState сохраняет состояние указанного вида в базу данных.
+func (s *SQLite) SaveState(ctx context.Context, kind string, data []byte) error {
+	_, err := s.db.ExecContext(ctx, "INSERT INTO state (kind, data) VALUES (?, ?) "+
+		"ON CONFLICT (kind) DO UPDATE SET data = excluded.data", kind, string(data))
+	if err != nil {
+		return fmt.Errorf("SaveState: insert into table failed %w", err)
+	}
+	return nil
+}
+
+// LoadState получает состояние указанного вида из базы данных.
+// При отсутствии состояния возвращается пустое состояние.
+func (s *SQLite) LoadState(ctx context.Context, kind string) ([]byte, error) {
+	var data string
+	err := s.db.QueryRowContext(ctx, "SELECT data FROM state WHERE kind = ?", kind).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("LoadState: read row failed %w", err)
+	}
+	return []byte(data), nil
+}
+
+// SaveState сохраняет состояние указанного вида во встроенное хранилище.
+func (kv *KV) SaveState(ctx context.Context, kind string, data []byte) error {
+	err := kv.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(stateBucket)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(kind), data)
+	})
+	if err != nil {
+		return fmt.Errorf("SaveState: update transaction failed %w", err)
+	}
+	return nil
+}
+
+// LoadState получает состояние указанного вида из встроенного хранилища.
+// При отсутствии состояния возвращается пустое состояние.
+func (kv *KV) LoadState(ctx context.Context, kind string) ([]byte, error) {
+	var data []byte
+	err := kv.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(stateBucket)
+		if b == nil {
+			return nil
+		}
+		if v := b.Get([]byte(kind)); v != nil {
+			data = append([]byte(nil), v...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("LoadState: view transaction failed %w", err)
+	}
+	return data, nil
+}
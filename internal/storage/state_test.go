@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/pavlegich/metrics-alerting/internal/infra/database"
+	"github.com/pavlegich/metrics-alerting/internal/interfaces"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateStorage_SaveLoad(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	kv, err := NewKV(ctx, filepath.Join(dir, "metrics.kv"))
+	require.NoError(t, err)
+	defer kv.Close()
+
+	db, err := database.Init(ctx, "sqlite://"+filepath.Join(dir, "metrics.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	tests := []struct {
+		name  string
+		state interfaces.StateStorage
+	}{
+		{
+			name:  "file",
+			state: NewFile(filepath.Join(dir, "metrics.json"), 0),
+		},
+		{
+			name:  "kv",
+			state: kv,
+		},
+		{
+			name:  "sqlite",
+			state: NewSQLite(db),
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := tc.state.LoadState(ctx, "rules")
+			require.NoError(t, err)
+			assert.Nil(t, data)
+
+			require.NoError(t, tc.state.SaveState(ctx, "rules", []byte(`[{"id":"1"}]`)))
+			require.NoError(t, tc.state.SaveState(ctx, "rules", []byte(`[{"id":"2"}]`)))
+
+			data, err = tc.state.LoadState(ctx, "rules")
+			require.NoError(t, err)
+			assert.Equal(t, `[{"id":"2"}]`, string(data))
+		})
+	}
+}
+
+func TestFile_statePath(t *testing.T) {
+	f := NewFile("/tmp/metrics-db.json", 0)
+	assert.Equal(t, "/tmp/metrics-db.rules.json", f.statePath("rules"))
+}
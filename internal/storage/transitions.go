@@ -0,0 +1,214 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pavlegich/metrics-alerting/internal/entities"
+)
+
+// defaultTransitionsLimit содержит количество записей истории оповещений,
+// возвращаемых по умолчанию.
+const defaultTransitionsLimit = 1000
+
+// AlertLog содержит ограниченную историю изменений состояния оповещений в памяти.
+// Используется при отсутствии базы данных, при переполнении удаляются старые записи.
+type AlertLog struct {
+	mu      *sync.RWMutex
+	entries []entities.AlertTransition
+	next    int
+	full    bool
+}
+
+// NewAlertLog создаёт новую историю оповещений указанной ёмкости.
+func NewAlertLog(ctx context.Context, capacity int) *AlertLog {
+	return &AlertLog{
+		mu:      &sync.RWMutex{},
+		entries: make([]entities.AlertTransition, capacity),
+	}
+}
+
+// SaveTransition добавляет изменение состояния оповещения в историю.
+func (l *AlertLog) SaveTransition(ctx context.Context, t entities.AlertTransition) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.entries) == 0 {
+		return nil
+	}
+	l.entries[l.next] = t
+	l.next = (l.next + 1) % len(l.entries)
+	if l.next == 0 {
+		l.full = true
+	}
+	return nil
+}
+
+// QueryTransitions возвращает подходящие изменения состояния, начиная с последних.
+func (l *AlertLog) QueryTransitions(ctx context.Context, filter entities.AlertFilter) ([]entities.AlertTransition, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultTransitionsLimit
+	}
+
+	size := l.next
+	if l.full {
+		size = len(l.entries)
+	}
+
+	res := make([]entities.AlertTransition, 0)
+	for i := 1; i <= size && len(res) < limit; i++ {
+		t := l.entries[(l.next-i+len(l.entries))%len(l.entries)]
+		if filter.Match(t) {
+			res = append(res, t)
+		}
+	}
+	return res, nil
+}
+
+// SaveTransition сохраняет изменение состояния оповещения в базу данных.
+func (d *Database) SaveTransition(ctx context.Context, t entities.AlertTransition) error {
+	if err := saveTransition(ctx, d.db, "$", t.Time, t); err != nil {
+		return fmt.Errorf("SaveTransition: %w", err)
+	}
+	return nil
+}
+
+// QueryTransitions получает из базы данных подходящие изменения состояния оповещений,
+// начиная с последних.
+func (d *Database) QueryTransitions(ctx context.Context, filter entities.AlertFilter) ([]entities.AlertTransition, error) {
+	res, err := queryTransitions(ctx, d.db, "$", filter, func(t time.Time) any { return t })
+	if err != nil {
+		return nil, fmt.Errorf("QueryTransitions: %w", err)
+	}
+	return res, nil
+}
+
+// SaveTransition сохраняет изменение состояния оповещения в базу данных.
+// Время хранится в наносекундах Unix для корректного сравнения.
+func (s *SQLite) SaveTransition(ctx context.Context, t entities.AlertTransition) error {
+	if err := saveTransition(ctx, s.db, "?", t.Time.UnixNano(), t); err != nil {
+		return fmt.Errorf("SaveTransition: %w", err)
+	}
+	return nil
+}
+
+// QueryTransitions получает из базы данных подходящие изменения состояния оповещений,
+// начиная с последних.
+func (s *SQLite) QueryTransitions(ctx context.Context, filter entities.AlertFilter) ([]entities.AlertTransition, error) {
+	res, err := queryTransitions(ctx, s.db, "?", filter, func(t time.Time) any { return t.UnixNano() })
+	if err != nil {
+		return nil, fmt.Errorf("QueryTransitions: %w", err)
+	}
+	return res, nil
+}
+
+// saveTransition добавляет запись истории оповещений в таблицу.
+// Оповещение целиком хранится в столбце data в формате JSON.
+func saveTransition(ctx context.Context, db *sql.DB, placeholder string, ts any, t entities.AlertTransition) error {
+	data, err := json.Marshal(t.Alert)
+	if err != nil {
+		return fmt.Errorf("saveTransition: alert marshal %w", err)
+	}
+
+	query := "INSERT INTO alert_history (time, fingerprint, rule_id, rule_name, metric, state, value, data) VALUES (" +
+		placeholders(placeholder, 1, 8) + ")"
+	_, err = db.ExecContext(ctx, query, ts, t.Fingerprint, t.RuleID, t.RuleName, t.Labels["metric"],
+		string(t.State), t.Value, string(data))
+	if err != nil {
+		return fmt.Errorf("saveTransition: insert into table failed %w", err)
+	}
+	return nil
+}
+
+// queryTransitions получает записи истории оповещений по условиям отбора.
+func queryTransitions(ctx context.Context, db *sql.DB, placeholder string, filter entities.AlertFilter,
+	timeArg func(time.Time) any) ([]entities.AlertTransition, error) {
+	conds := make([]string, 0)
+	args := make([]any, 0)
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		conds = append(conds, cond+" "+placeholders(placeholder, len(args), 1))
+	}
+
+	if filter.RuleID != "" {
+		add("rule_id =", filter.RuleID)
+	}
+	if filter.Metric != "" {
+		add("metric =", filter.Metric)
+	}
+	if filter.State != "" {
+		add("state =", string(filter.State))
+	}
+	if !filter.From.IsZero() {
+		add("time >=", timeArg(filter.From))
+	}
+	if !filter.To.IsZero() {
+		add("time <=", timeArg(filter.To))
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultTransitionsLimit
+	}
+
+	query := "SELECT data, time FROM alert_history"
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	query += fmt.Sprintf(" ORDER BY time DESC, id DESC LIMIT %d", limit)
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("queryTransitions: read rows from table failed %w", err)
+	}
+	defer rows.Close()
+
+	res := make([]entities.AlertTransition, 0)
+	for rows.Next() {
+		var data string
+		var ts any
+		if err := rows.Scan(&data, &ts); err != nil {
+			return nil, fmt.Errorf("queryTransitions: scan row failed %w", err)
+		}
+
+		var t entities.AlertTransition
+		if err := json.Unmarshal([]byte(data), &t.Alert); err != nil {
+			return nil, fmt.Errorf("queryTransitions: alert unmarshal %w", err)
+		}
+		switch v := ts.(type) {
+		case time.Time:
+			t.Time = v
+		case int64:
+			t.Time = time.Unix(0, v)
+		}
+		res = append(res, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("queryTransitions: rows error %w", err)
+	}
+
+	return res, nil
+}
+
+// placeholders формирует список параметров запроса, начиная с указанного номера:
+// $1, $2 для PostgreSQL или ?, ? для SQLite.
+func placeholders(placeholder string, start int, n int) string {
+	ps := make([]string, n)
+	for i := range ps {
+		if placeholder == "?" {
+			ps[i] = "?"
+		} else {
+			ps[i] = fmt.Sprintf("$%d", start+i)
+		}
+	}
+	return strings.Join(ps, ", ")
+}
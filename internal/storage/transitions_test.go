@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pavlegich/metrics-alerting/internal/entities"
+	"github.com/pavlegich/metrics-alerting/internal/infra/database"
+	"github.com/pavlegich/metrics-alerting/internal/interfaces"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransitionStorage(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := database.Init(ctx, "sqlite://"+filepath.Join(t.TempDir(), "metrics.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	transition := func(rule, metric string, state entities.AlertState, offset time.Duration) entities.AlertTransition {
+		return entities.AlertTransition{
+			Time: start.Add(offset),
+			Alert: entities.Alert{
+				Fingerprint: rule + metric,
+				RuleID:      rule,
+				RuleName:    rule,
+				State:       state,
+				Labels:      map[string]string{"alertname": rule, "metric": metric},
+				Value:       float64(offset / time.Minute),
+			},
+		}
+	}
+
+	tests := []struct {
+		name string
+		ts   interfaces.TransitionStorage
+	}{
+		{name: "memory", ts: NewAlertLog(ctx, 10)},
+		{name: "sqlite", ts: NewSQLite(db)},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			require.NoError(t, tc.ts.SaveTransition(ctx, transition("cpu", "CPUutilization1", entities.StatePending, 0)))
+			require.NoError(t, tc.ts.SaveTransition(ctx, transition("cpu", "CPUutilization1", entities.StateFiring, time.Minute)))
+			require.NoError(t, tc.ts.SaveTransition(ctx, transition("heap", "HeapAlloc", entities.StateFiring, 2*time.Minute)))
+			require.NoError(t, tc.ts.SaveTransition(ctx, transition("cpu", "CPUutilization1", entities.StateResolved, 3*time.Minute)))
+
+			got, err := tc.ts.QueryTransitions(ctx, entities.AlertFilter{})
+			require.NoError(t, err)
+			require.Len(t, got, 4)
+			assert.Equal(t, entities.StateResolved, got[0].State)
+			assert.True(t, got[0].Time.Equal(start.Add(3*time.Minute)))
+			assert.Equal(t, "CPUutilization1", got[0].Labels["metric"])
+
+			got, err = tc.ts.QueryTransitions(ctx, entities.AlertFilter{RuleID: "cpu", State: entities.StateFiring})
+			require.NoError(t, err)
+			require.Len(t, got, 1)
+			assert.Equal(t, float64(1), got[0].Value)
+
+			got, err = tc.ts.QueryTransitions(ctx, entities.AlertFilter{
+				Metric: "CPUutilization1",
+				From:   start.Add(30 * time.Second),
+				To:     start.Add(2 * time.Minute),
+			})
+			require.NoError(t, err)
+			require.Len(t, got, 1)
+			assert.Equal(t, entities.StateFiring, got[0].State)
+
+			got, err = tc.ts.QueryTransitions(ctx, entities.AlertFilter{Limit: 2})
+			require.NoError(t, err)
+			assert.Len(t, got, 2)
+		})
+	}
+}
+
+func TestAlertLog_Bounded(t *testing.T) {
+	ctx := context.Background()
+	l := NewAlertLog(ctx, 3)
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, l.SaveTransition(ctx, entities.AlertTransition{
+			Alert: entities.Alert{Value: float64(i)},
+		}))
+	}
+
+	got, err := l.QueryTransitions(ctx, entities.AlertFilter{})
+	require.NoError(t, err)
+	require.Len(t, got, 3)
+	assert.Equal(t, []float64{4, 3, 2}, []float64{got[0].Value, got[1].Value, got[2].Value})
+}
+
+func TestPlaceholders(t *testing.T) {
+	assert.Equal(t, "$3, $4", placeholders("$", 3, 2))
+	assert.Equal(t, "?, ?, ?", placeholders("?", 1, 3))
+}
@@ -0,0 +1,345 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pavlegich/metrics-alerting/internal/infra/logger"
+	"github.com/pavlegich/metrics-alerting/internal/interfaces"
+	"go.uber.org/zap"
+)
+
+// WALRecord содержит запись журнала предзаписи.
+// В записи хранится итоговое значение метрики после обновления,
+// поэтому повторное применение журнала поверх снимка не искажает значения счётчиков.
+type WALRecord struct {
+	ID      string `json:"id"`
+	MType   string `json:"type,omitempty"`
+	Value   string `json:"value,omitempty"`
+	Deleted bool   `json:"deleted,omitempty"`
+}
+
+// WAL содержит данные журнала предзаписи хранилища метрик.
+type WAL struct {
+	path         string
+	syncInterval time.Duration
+
+	mu      *sync.Mutex
+	synced  *sync.Cond
+	file    *os.File
+	writer  *bufio.Writer
+	written uint64
+	flushed uint64
+	err     error
+	closed  bool
+	done    chan struct{}
+}
+
+// NewWAL открывает журнал предзаписи по указанному пути.
+// При нулевом syncInterval каждая запись синхронизируется с диском отдельно,
+// иначе записи синхронизируются группами с указанным интервалом.
+func NewWAL(ctx context.Context, path string, syncInterval time.Duration) (*WAL, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		return nil, fmt.Errorf("NewWAL: open file failed %w", err)
+	}
+
+	mu := &sync.Mutex{}
+	w := &WAL{
+		path:         path,
+		syncInterval: syncInterval,
+		mu:           mu,
+		synced:       sync.NewCond(mu),
+		file:         file,
+		writer:       bufio.NewWriter(file),
+		done:         make(chan struct{}),
+	}
+
+	if syncInterval > 0 {
+		go w.groupCommit()
+	}
+
+	return w, nil
+}
+
+// Write добавляет записи в буфер журнала одной операцией и возвращает её порядковый
+// номер, который используется для ожидания синхронизации с диском.
+func (w *WAL) Write(ctx context.Context, recs ...WALRecord) (uint64, error) {
+	var data []byte
+	for _, rec := range recs {
+		line, err := json.Marshal(rec)
+		if err != nil {
+			return 0, fmt.Errorf("Write: record marshal %w", err)
+		}
+		data = append(data, line...)
+		data = append(data, '\n')
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, fmt.Errorf("Write: wal is closed")
+	}
+	if w.err != nil {
+		return 0, fmt.Errorf("Write: wal is broken %w", w.err)
+	}
+
+	if _, err := w.writer.Write(data); err != nil {
+		w.err = err
+		return 0, fmt.Errorf("Write: write record failed %w", err)
+	}
+	w.written++
+
+	if w.syncInterval == 0 {
+		if err := w.flush(); err != nil {
+			return 0, fmt.Errorf("Write: %w", err)
+		}
+	}
+
+	return w.written, nil
+}
+
+// WaitSync ожидает синхронизации с диском записи с указанным порядковым номером.
+func (w *WAL) WaitSync(ctx context.Context, seq uint64) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for w.flushed < seq && w.err == nil && !w.closed {
+		w.synced.Wait()
+	}
+
+	if w.flushed >= seq {
+		return nil
+	}
+	if w.err != nil {
+		return fmt.Errorf("WaitSync: wal is broken %w", w.err)
+	}
+	return fmt.Errorf("WaitSync: wal is closed")
+}
+
+// Replay применяет записи журнала к хранилищу метрик сервера.
+// Недописанная при сбое последняя запись отбрасывается.
+func (w *WAL) Replay(ctx context.Context, ms interfaces.MetricStorage) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	count := 0
+	for _, path := range []string{w.checkpointPath(), w.path} {
+		n, valid, err := replayFile(ctx, path, ms)
+		if err != nil {
+			return fmt.Errorf("Replay: %w", err)
+		}
+		count += n
+
+		// отрезаем повреждённый хвост, чтобы новые записи не оказались после него
+		if path == w.path {
+			if err := w.file.Truncate(valid); err != nil {
+				return fmt.Errorf("Replay: truncate torn tail failed %w", err)
+			}
+		}
+	}
+
+	logger.Log.Info("Replay: wal records applied", zap.Int("records", count))
+
+	return nil
+}
+
+// Checkpoint начинает новый сегмент журнала, сохраняет снимок хранилища
+// с помощью save и в случае успеха удаляет предыдущий сегмент.
+// Все записи предыдущего сегмента попадают в снимок, так как снимок
+// делается после переключения сегментов.
+func (w *WAL) Checkpoint(ctx context.Context, save func(ctx context.Context) error) error {
+	if err := w.rotate(); err != nil {
+		return fmt.Errorf("Checkpoint: %w", err)
+	}
+
+	if err := save(ctx); err != nil {
+		return fmt.Errorf("Checkpoint: snapshot save failed %w", err)
+	}
+
+	if err := os.Remove(w.checkpointPath()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("Checkpoint: remove old segment failed %w", err)
+	}
+
+	return nil
+}
+
+// Close синхронизирует оставшиеся записи с диском и закрывает журнал.
+func (w *WAL) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return nil
+	}
+
+	err := w.flush()
+	w.closed = true
+	close(w.done)
+	w.synced.Broadcast()
+
+	if errClose := w.file.Close(); errClose != nil && err == nil {
+		err = errClose
+	}
+	if err != nil {
+		return fmt.Errorf("Close: %w", err)
+	}
+	return nil
+}
+
+// groupCommit периодически синхронизирует накопленные записи с диском.
+func (w *WAL) groupCommit() {
+	ticker := time.NewTicker(w.syncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+			w.mu.Lock()
+			if w.written > w.flushed && w.err == nil {
+				if err := w.flush(); err != nil {
+					logger.Log.Error("groupCommit: wal sync failed", zap.Error(err))
+				}
+			}
+			w.mu.Unlock()
+		}
+	}
+}
+
+// flush сбрасывает буфер в файл, синхронизирует файл с диском
+// и пробуждает ожидающих синхронизации. Вызывается под блокировкой.
+func (w *WAL) flush() error {
+	if err := w.writer.Flush(); err != nil {
+		w.err = err
+		w.synced.Broadcast()
+		return fmt.Errorf("flush: write buffer failed %w", err)
+	}
+	if err := w.file.Sync(); err != nil {
+		w.err = err
+		w.synced.Broadcast()
+		return fmt.Errorf("flush: sync file failed %w", err)
+	}
+	w.flushed = w.written
+	w.synced.Broadcast()
+	return nil
+}
+
+// rotate переносит текущий сегмент журнала в сегмент контрольной точки
+// и открывает новый пустой сегмент.
+func (w *WAL) rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return fmt.Errorf("rotate: wal is closed")
+	}
+	if err := w.flush(); err != nil {
+		return fmt.Errorf("rotate: %w", err)
+	}
+	if err := w.file.Close(); err != nil {
+		return fmt.Errorf("rotate: close segment failed %w", err)
+	}
+
+	// предыдущий снимок не сохранился, поэтому дописываем сегмент к старому
+	if _, err := os.Stat(w.checkpointPath()); err == nil {
+		if err := appendFile(w.checkpointPath(), w.path); err != nil {
+			return fmt.Errorf("rotate: %w", err)
+		}
+		if err := os.Remove(w.path); err != nil {
+			return fmt.Errorf("rotate: remove segment failed %w", err)
+		}
+	} else if err := os.Rename(w.path, w.checkpointPath()); err != nil {
+		return fmt.Errorf("rotate: rename segment failed %w", err)
+	}
+
+	file, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		w.err = err
+		return fmt.Errorf("rotate: open segment failed %w", err)
+	}
+	w.file = file
+	w.writer.Reset(file)
+
+	return nil
+}
+
+// checkpointPath возвращает путь к сегменту журнала, ожидающему сохранения снимка.
+func (w *WAL) checkpointPath() string {
+	return w.path + ".checkpoint"
+}
+
+// replayFile применяет записи указанного файла журнала к хранилищу
+// и возвращает количество записей и длину корректной части файла.
+func replayFile(ctx context.Context, path string, ms interfaces.MetricStorage) (int, int64, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("replayFile: read file failed %w", err)
+	}
+
+	count := 0
+	var valid int64
+	for len(data) > 0 {
+		end := bytes.IndexByte(data, '\n')
+		if end < 0 {
+			break
+		}
+
+		var rec WALRecord
+		if err := json.Unmarshal(data[:end], &rec); err != nil {
+			break
+		}
+		if rec.Deleted {
+			// метрика могла быть уже удалена до сохранения снимка
+			if err := ms.Delete(ctx, rec.ID); err != nil && !errors.Is(err, ErrNotFound) {
+				return count, valid, fmt.Errorf("replayFile: delete metric failed %w", err)
+			}
+		} else if err := ms.Restore(ctx, rec.MType, rec.ID, rec.Value); err != nil {
+			return count, valid, fmt.Errorf("replayFile: put metric failed %w", err)
+		}
+
+		count++
+		valid += int64(end + 1)
+		data = data[end+1:]
+	}
+
+	if len(data) > 0 {
+		logger.Log.Info("replayFile: torn wal tail discarded",
+			zap.String("path", path), zap.Int("bytes", len(data)))
+	}
+
+	return count, valid, nil
+}
+
+// appendFile дописывает содержимое файла src в конец файла dst.
+func appendFile(dst string, src string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("appendFile: open source failed %w", err)
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		return fmt.Errorf("appendFile: open destination failed %w", err)
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, in); err != nil {
+		return fmt.Errorf("appendFile: copy failed %w", err)
+	}
+	return out.Sync()
+}
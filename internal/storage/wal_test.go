@@ -0,0 +1,221 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pavlegich/metrics-alerting/internal/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWAL_Replay(t *testing.T) {
+	ctx := context.Background()
+
+	type put struct {
+		metricType  string
+		metricName  string
+		metricValue string
+	}
+	tests := []struct {
+		name         string
+		syncInterval time.Duration
+		puts         []put
+		tail         string
+		want         map[string]string
+	}{
+		{
+			name:         "exact_counter_values",
+			syncInterval: 0,
+			puts: []put{
+				{"counter", "PollCount", "3"},
+				{"counter", "PollCount", "4"},
+				{"gauge", "Alloc", "1.5"},
+				{"counter", "PollCount", "5"},
+			},
+			want: map[string]string{
+				"PollCount": "12",
+				"Alloc":     "1.5",
+			},
+		},
+		{
+			name:         "group_commit",
+			syncInterval: 5 * time.Millisecond,
+			puts: []put{
+				{"counter", "PollCount", "1"},
+				{"counter", "PollCount", "1"},
+			},
+			want: map[string]string{
+				"PollCount": "2",
+			},
+		},
+		{
+			name:         "torn_tail_discarded",
+			syncInterval: 0,
+			puts: []put{
+				{"counter", "PollCount", "7"},
+			},
+			tail: `{"id":"PollCount","ty`,
+			want: map[string]string{
+				"PollCount": "7",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "metrics.wal")
+
+			wal, err := NewWAL(ctx, path, tt.syncInterval)
+			require.NoError(t, err)
+			ms := NewMemStorage(ctx)
+			ms.SetWAL(wal)
+			for _, p := range tt.puts {
+				require.NoError(t, ms.Put(ctx, p.metricType, p.metricName, p.metricValue))
+			}
+			require.NoError(t, wal.Close())
+
+			if tt.tail != "" {
+				f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0666)
+				require.NoError(t, err)
+				_, err = f.WriteString(tt.tail)
+				require.NoError(t, err)
+				require.NoError(t, f.Close())
+			}
+
+			restored, err := NewWAL(ctx, path, tt.syncInterval)
+			require.NoError(t, err)
+			defer restored.Close()
+
+			got := NewMemStorage(ctx)
+			require.NoError(t, restored.Replay(ctx, got))
+			assert.Equal(t, tt.want, got.Metrics)
+		})
+	}
+}
+
+func TestWAL_ReplayBatchDelete(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "metrics.wal")
+	delta := int64(2)
+	value := 1.5
+
+	wal, err := NewWAL(ctx, path, 0)
+	require.NoError(t, err)
+	ms := NewMemStorage(ctx)
+	ms.SetWAL(wal)
+	_, err = ms.PutBatch(ctx, []entities.Metrics{
+		{ID: "PollCount", MType: "counter", Delta: &delta},
+		{ID: "Alloc", MType: "gauge", Value: &value},
+		{ID: "PollCount", MType: "counter", Delta: &delta},
+	}, false)
+	require.NoError(t, err)
+	require.NoError(t, ms.Delete(ctx, "Alloc"))
+	require.NoError(t, wal.Close())
+
+	restored, err := NewWAL(ctx, path, 0)
+	require.NoError(t, err)
+	defer restored.Close()
+
+	got := NewMemStorage(ctx)
+	require.NoError(t, restored.Replay(ctx, got))
+	assert.Equal(t, map[string]string{"PollCount": "4"}, got.Metrics)
+}
+
+func TestWAL_Checkpoint(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	walPath := filepath.Join(dir, "metrics.wal")
+
+	file := NewFile(filepath.Join(dir, "metrics-db.json"), 0)
+	wal, err := NewWAL(ctx, walPath, 0)
+	require.NoError(t, err)
+
+	ms := NewMemStorage(ctx)
+	ms.SetWAL(wal)
+	require.NoError(t, ms.Put(ctx, "counter", "PollCount", "10"))
+
+	// снимок не сохранился, записи журнала должны остаться
+	err = wal.Checkpoint(ctx, func(ctx context.Context) error {
+		return os.ErrPermission
+	})
+	require.Error(t, err)
+	require.NoError(t, ms.Put(ctx, "counter", "PollCount", "1"))
+
+	require.NoError(t, wal.Checkpoint(ctx, func(ctx context.Context) error {
+		return file.Save(ctx, ms)
+	}))
+	require.NoError(t, ms.Put(ctx, "counter", "PollCount", "5"))
+	require.NoError(t, wal.Close())
+
+	_, err = os.Stat(walPath + ".checkpoint")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	// восстановление из снимка и усечённого журнала
+	restored, err := NewWAL(ctx, walPath, 0)
+	require.NoError(t, err)
+	defer restored.Close()
+
+	got := NewMemStorage(ctx)
+	require.NoError(t, file.Load(ctx, got))
+	require.NoError(t, restored.Replay(ctx, got))
+	assert.Equal(t, map[string]string{"PollCount": "16"}, got.Metrics)
+}
+
+func TestWAL_ConcurrentPut(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "metrics.wal")
+
+	wal, err := NewWAL(ctx, path, 2*time.Millisecond)
+	require.NoError(t, err)
+	ms := NewMemStorage(ctx)
+	ms.SetWAL(wal)
+
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, ms.Put(ctx, "counter", "PollCount", "2"))
+		}()
+	}
+	wg.Wait()
+	require.NoError(t, wal.Close())
+
+	restored, err := NewWAL(ctx, path, 0)
+	require.NoError(t, err)
+	defer restored.Close()
+
+	got := NewMemStorage(ctx)
+	require.NoError(t, restored.Replay(ctx, got))
+	assert.Equal(t, map[string]string{"PollCount": "100"}, got.Metrics)
+}
+
+func TestWAL_SyncFailure(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "metrics.wal")
+
+	wal, err := NewWAL(ctx, path, 2*time.Millisecond)
+	require.NoError(t, err)
+	defer wal.Close()
+	ms := NewMemStorage(ctx)
+	ms.SetWAL(wal)
+
+	require.NoError(t, ms.Put(ctx, "counter", "PollCount", "2"))
+
+	// сбой синхронизации после применения изменения к хранилищу
+	require.NoError(t, wal.file.Close())
+	require.NoError(t, ms.Put(ctx, "counter", "PollCount", "3"))
+	assert.Equal(t, map[string]string{"PollCount": "5"}, ms.Metrics)
+
+	// после сбоя изменения отклоняются до применения к хранилищу,
+	// поэтому повторная отправка не учитывает значение дважды
+	assert.Error(t, ms.Put(ctx, "counter", "PollCount", "3"))
+	_, err = ms.PutBatch(ctx, []entities.Metrics{{ID: "PollCount", MType: "counter", Delta: new(int64)}}, false)
+	assert.Error(t, err)
+	assert.Error(t, ms.Delete(ctx, "PollCount"))
+	assert.Equal(t, map[string]string{"PollCount": "5"}, ms.Metrics)
+}
@@ -1,14 +1,23 @@
 package grpc
 
 import (
+	"errors"
 	"fmt"
-	"net/http"
+	"regexp"
+	"time"
 
 	"github.com/pavlegich/metrics-alerting/internal/entities"
 	pb "github.com/pavlegich/metrics-alerting/internal/proto"
+	"github.com/pavlegich/metrics-alerting/internal/storage"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+// errorDomain содержит домен ошибок сервера в деталях статуса gRPC.
+const errorDomain = "metrics-alerting"
+
 func ConvertFromMetricsToGRPC(metric entities.Metrics) (*pb.Metric, error) {
 	pbMetric := &pb.Metric{
 		Id:   metric.ID,
@@ -26,19 +35,318 @@ func ConvertFromMetricsToGRPC(metric entities.Metrics) (*pb.Metric, error) {
 	return pbMetric, nil
 }
 
-func ConvertCodeHTTPtoGRPC(code int) codes.Code {
-	switch code {
-	case http.StatusNotFound:
+// ConvertFromGRPCToMetrics преобразует метрику из proto-формата. Значение
+// метрики неизвестного типа не заполняется, такая метрика отклоняется хранилищем.
+func ConvertFromGRPCToMetrics(pbMetric *pb.Metric) entities.Metrics {
+	if pbMetric == nil {
+		return entities.Metrics{}
+	}
+
+	metric := entities.Metrics{
+		ID:    pbMetric.Id,
+		MType: pbMetric.Type,
+	}
+	switch pbMetric.Type {
+	case "gauge":
+		value := pbMetric.Value
+		metric.Value = &value
+	case "counter":
+		delta := pbMetric.Delta
+		metric.Delta = &delta
+	}
+
+	return metric
+}
+
+// ConvertFromBatchResultToGRPC преобразует результат применения пачки метрик
+// в proto-формат.
+func ConvertFromBatchResultToGRPC(result entities.BatchResult) *pb.UpdateBatchResponse {
+	resp := &pb.UpdateBatchResponse{
+		Applied: int32(result.Applied),
+		Errors:  make([]*pb.BatchError, 0, len(result.Errors)),
+	}
+	for _, e := range result.Errors {
+		resp.Errors = append(resp.Errors, &pb.BatchError{
+			Index:   int32(e.Index),
+			Id:      e.ID,
+			Code:    e.Code,
+			Message: e.Message,
+		})
+	}
+	return resp
+}
+
+// ConvertFromRuleToGRPC преобразует правило оповещения в proto-формат.
+func ConvertFromRuleToGRPC(rule entities.Rule) *pb.Rule {
+	pbRule := &pb.Rule{
+		Id:         rule.ID,
+		Name:       rule.Name,
+		MetricType: rule.MetricType,
+		MetricName: rule.MetricName,
+		Operator:   rule.Operator,
+		Threshold:  rule.Threshold,
+		For:        time.Duration(rule.For).String(),
+		Labels:     rule.Labels,
+		Kind:       rule.Kind,
+		Alpha:      rule.Alpha,
+		Expr:       rule.Expr,
+		Message:    rule.Message,
+	}
+	if rule.AbsentFor > 0 {
+		pbRule.AbsentFor = time.Duration(rule.AbsentFor).String()
+	}
+	if rule.Window > 0 {
+		pbRule.Window = time.Duration(rule.Window).String()
+	}
+	return pbRule
+}
+
+// ConvertFromGRPCToRule преобразует правило оповещения из proto-формата.
+func ConvertFromGRPCToRule(pbRule *pb.Rule) (entities.Rule, error) {
+	if pbRule == nil {
+		return entities.Rule{}, fmt.Errorf("ConvertFromGRPCToRule: rule is empty")
+	}
+
+	rule := entities.Rule{
+		ID:         pbRule.Id,
+		Name:       pbRule.Name,
+		Kind:       pbRule.Kind,
+		MetricType: pbRule.MetricType,
+		MetricName: pbRule.MetricName,
+		Operator:   pbRule.Operator,
+		Threshold:  pbRule.Threshold,
+		Labels:     pbRule.Labels,
+		Alpha:      pbRule.Alpha,
+		Expr:       pbRule.Expr,
+		Message:    pbRule.Message,
+	}
+	if pbRule.For != "" {
+		d, err := time.ParseDuration(pbRule.For)
+		if err != nil {
+			return entities.Rule{}, fmt.Errorf("ConvertFromGRPCToRule: parse for duration failed %w", err)
+		}
+		rule.For = entities.Duration(d)
+	}
+	if pbRule.AbsentFor != "" {
+		d, err := time.ParseDuration(pbRule.AbsentFor)
+		if err != nil {
+			return entities.Rule{}, fmt.Errorf("ConvertFromGRPCToRule: parse absent_for duration failed %w", err)
+		}
+		rule.AbsentFor = entities.Duration(d)
+	}
+	if pbRule.Window != "" {
+		d, err := time.ParseDuration(pbRule.Window)
+		if err != nil {
+			return entities.Rule{}, fmt.Errorf("ConvertFromGRPCToRule: parse window duration failed %w", err)
+		}
+		rule.Window = entities.Duration(d)
+	}
+
+	return rule, nil
+}
+
+// ConvertFromSilenceToGRPC преобразует тишину в proto-формат.
+func ConvertFromSilenceToGRPC(silence entities.Silence) *pb.Silence {
+	pbSilence := &pb.Silence{
+		Id:        silence.ID,
+		Matchers:  make([]*pb.Matcher, 0, len(silence.Matchers)),
+		StartsAt:  timestamppb.New(silence.StartsAt),
+		Schedule:  silence.Schedule,
+		CreatedBy: silence.CreatedBy,
+		Comment:   silence.Comment,
+	}
+	for _, m := range silence.Matchers {
+		pbSilence.Matchers = append(pbSilence.Matchers, &pb.Matcher{
+			Name:    m.Name,
+			Value:   m.Value,
+			IsRegex: m.IsRegex,
+		})
+	}
+	if !silence.EndsAt.IsZero() {
+		pbSilence.EndsAt = timestamppb.New(silence.EndsAt)
+	}
+	if silence.Duration != 0 {
+		pbSilence.Duration = time.Duration(silence.Duration).String()
+	}
+
+	return pbSilence
+}
+
+// ConvertFromAlertToGRPC преобразует оповещение в proto-формат.
+func ConvertFromAlertToGRPC(alert entities.Alert) *pb.Alert {
+	pbAlert := &pb.Alert{
+		Fingerprint: alert.Fingerprint,
+		RuleId:      alert.RuleID,
+		RuleName:    alert.RuleName,
+		State:       string(alert.State),
+		Labels:      alert.Labels,
+		Value:       alert.Value,
+		Message:     alert.Message,
+		ActiveAt:    timestamppb.New(alert.ActiveAt),
+		SilencedBy:  alert.SilencedBy,
+		InhibitedBy: alert.InhibitedBy,
+		AckedBy:     alert.AckedBy,
+	}
+	if !alert.FiredAt.IsZero() {
+		pbAlert.FiredAt = timestamppb.New(alert.FiredAt)
+	}
+	if !alert.AckedAt.IsZero() {
+		pbAlert.AckedAt = timestamppb.New(alert.AckedAt)
+	}
+
+	return pbAlert
+}
+
+// ConvertFromGRPCToSilence преобразует тишину из proto-формата.
+func ConvertFromGRPCToSilence(pbSilence *pb.Silence) (entities.Silence, error) {
+	if pbSilence == nil {
+		return entities.Silence{}, fmt.Errorf("ConvertFromGRPCToSilence: silence is empty")
+	}
+
+	silence := entities.Silence{
+		ID:        pbSilence.Id,
+		Matchers:  make([]entities.Matcher, 0, len(pbSilence.Matchers)),
+		Schedule:  pbSilence.Schedule,
+		CreatedBy: pbSilence.CreatedBy,
+		Comment:   pbSilence.Comment,
+	}
+	for _, m := range pbSilence.Matchers {
+		silence.Matchers = append(silence.Matchers, entities.Matcher{
+			Name:    m.Name,
+			Value:   m.Value,
+			IsRegex: m.IsRegex,
+		})
+	}
+	if pbSilence.StartsAt != nil {
+		silence.StartsAt = pbSilence.StartsAt.AsTime()
+	}
+	if pbSilence.EndsAt != nil {
+		silence.EndsAt = pbSilence.EndsAt.AsTime()
+	}
+	if pbSilence.Duration != "" {
+		d, err := time.ParseDuration(pbSilence.Duration)
+		if err != nil {
+			return entities.Silence{}, fmt.Errorf("ConvertFromGRPCToSilence: parse duration failed %w", err)
+		}
+		silence.Duration = entities.Duration(d)
+	}
+
+	return silence, nil
+}
+
+// ConvertFromGRPCToEventFilter преобразует условия отбора событий из proto-формата.
+func ConvertFromGRPCToEventFilter(in *pb.WatchRequest) (entities.EventFilter, error) {
+	filter := entities.EventFilter{
+		Events: in.Events,
+		Types:  in.Types,
+		Prefix: in.Prefix,
+	}
+	for _, e := range in.Events {
+		if e != entities.EventMetric && e != entities.EventAlert {
+			return entities.EventFilter{}, fmt.Errorf("ConvertFromGRPCToEventFilter: unknown event type %q", e)
+		}
+	}
+	for _, t := range in.Types {
+		if t != "gauge" && t != "counter" {
+			return entities.EventFilter{}, fmt.Errorf("ConvertFromGRPCToEventFilter: unknown metric type %q", t)
+		}
+	}
+	for _, m := range in.Matchers {
+		if m.IsRegex {
+			if _, err := regexp.Compile(m.Value); err != nil {
+				return entities.EventFilter{}, fmt.Errorf("ConvertFromGRPCToEventFilter: invalid matcher regex %q", m.Value)
+			}
+		}
+		filter.Matchers = append(filter.Matchers, entities.Matcher{
+			Name:    m.Name,
+			Value:   m.Value,
+			IsRegex: m.IsRegex,
+		})
+	}
+
+	return filter, nil
+}
+
+// ConvertFromEventToGRPC преобразует событие потока обновлений в proto-формат.
+func ConvertFromEventToGRPC(event entities.Event) (*pb.WatchResponse, error) {
+	resp := &pb.WatchResponse{
+		Type: event.Type,
+		Time: timestamppb.New(event.Time),
+	}
+	if event.Metric != nil {
+		metric, err := ConvertFromMetricsToGRPC(*event.Metric)
+		if err != nil {
+			return nil, fmt.Errorf("ConvertFromEventToGRPC: %w", err)
+		}
+		resp.Metric = metric
+	}
+	if event.Alert != nil {
+		resp.Alert = ConvertFromAlertToGRPC(*event.Alert)
+	}
+
+	return resp, nil
+}
+
+// ConvertErrorToGRPC преобразует ошибку хранилища метрик в ошибку со статусом
+// gRPC. Детали статуса содержат ErrorInfo с кодом ошибки и именем метрики.
+func ConvertErrorToGRPC(err error, metric string) error {
+	st := status.New(ConvertErrorCodeToGRPC(err), err.Error())
+	withDetails, detailsErr := st.WithDetails(errorInfo(storage.ErrorCode(err), metric))
+	if detailsErr != nil {
+		return st.Err()
+	}
+	return withDetails.Err()
+}
+
+// ConvertBatchErrorToGRPC преобразует ошибку применения пачки метрик в ошибку
+// со статусом gRPC. Детали статуса содержат ErrorInfo первой отклонённой метрики
+// и BadRequest с ошибками всех отклонённых метрик.
+func ConvertBatchErrorToGRPC(err error, result entities.BatchResult) error {
+	st := status.New(ConvertErrorCodeToGRPC(err), err.Error())
+	if len(result.Errors) == 0 {
+		return st.Err()
+	}
+
+	violations := make([]*errdetails.BadRequest_FieldViolation, 0, len(result.Errors))
+	for _, e := range result.Errors {
+		violations = append(violations, &errdetails.BadRequest_FieldViolation{
+			Field:       fmt.Sprintf("metrics[%d]", e.Index),
+			Description: e.Message,
+		})
+	}
+	first := result.Errors[0]
+	withDetails, detailsErr := st.WithDetails(
+		errorInfo(first.Code, first.ID),
+		&errdetails.BadRequest{FieldViolations: violations},
+	)
+	if detailsErr != nil {
+		return st.Err()
+	}
+	return withDetails.Err()
+}
+
+// ConvertErrorCodeToGRPC возвращает код статуса gRPC для ошибки хранилища метрик.
+func ConvertErrorCodeToGRPC(err error) codes.Code {
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
 		return codes.NotFound
-	case http.StatusBadRequest:
+	case errors.Is(err, storage.ErrUnknownType), errors.Is(err, storage.ErrInvalidValue):
 		return codes.InvalidArgument
-	case http.StatusInternalServerError:
-		return codes.Internal
-	case http.StatusNotImplemented:
-		return codes.Unimplemented
 	default:
-		return codes.Unknown
+		return codes.Internal
+	}
+}
+
+// errorInfo формирует детали ошибки хранилища метрик.
+func errorInfo(code string, metric string) *errdetails.ErrorInfo {
+	info := &errdetails.ErrorInfo{
+		Reason: code,
+		Domain: errorDomain,
+	}
+	if metric != "" {
+		info.Metadata = map[string]string{"metric": metric}
 	}
+	return info
 }
 
 // func ConvertFromGRPCToMetrics(pbMetric *pb.Metric) (entities.Metrics, error) {